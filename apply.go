@@ -0,0 +1,587 @@
+package azkaban
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectSpec is the desired state of a project, brought about by Apply.
+//
+//	name: etl
+//	description: nightly loads
+//	source: ./flows
+//	exclude: ["*.md"]
+//	permissions:
+//	  - user: deploy
+//	    permissions: [admin]
+//	  - group: analysts
+//	    permissions: [read, execute]
+//	proxy_users: [etl]
+//	schedules:
+//	  - flow: load
+//	    cron: 0 0 2 ? * *
+//	    sla:
+//	      emails: [oncall@example.com]
+//	      settings:
+//	        - rule: SUCCESS
+//	          duration: 2h
+//	          email: true
+type ProjectSpec struct {
+	Name string `yaml:"name"`
+
+	// Description is set when the project is created, azkaban does not report the description
+	// of existing projects
+	Description string `yaml:"description"`
+
+	// Source is the directory packaged into the archive, or an archive
+	Source  string   `yaml:"source"`
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+
+	Permissions []PermissionSpec `yaml:"permissions"`
+	ProxyUsers  []string         `yaml:"proxy_users"`
+	Schedules   []ScheduleSpec   `yaml:"schedules"`
+}
+
+// PermissionSpec grants permissions to either a user or a group.
+type PermissionSpec struct {
+	User        string   `yaml:"user"`
+	Group       string   `yaml:"group"`
+	Permissions []string `yaml:"permissions"`
+}
+
+// ScheduleSpec is the cron schedule of a flow and its SLA.
+type ScheduleSpec struct {
+	Flow string `yaml:"flow"`
+	Cron string `yaml:"cron"`
+	SLA  *SLA   `yaml:"sla"`
+}
+
+// LoadProjectSpec reads a spec written in yaml, its source is relative to the spec file.
+func LoadProjectSpec(path string) (*ProjectSpec, error) {
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec ProjectSpec
+	if err = yaml.Unmarshal(content, &spec); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if spec.Source != "" && !filepath.IsAbs(spec.Source) {
+		spec.Source = filepath.Join(filepath.Dir(path), spec.Source)
+	}
+
+	if err = spec.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return &spec, nil
+
+}
+
+// Validate checks the spec is complete and consistent.
+func (this *ProjectSpec) Validate() error {
+
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if this.Name == "" {
+		problem("the project has no name")
+	}
+	if this.Source == "" {
+		problem("the project has no source")
+	}
+
+	types := map[string]bool{}
+	for _, t := range []string{PermissionType.Admin, PermissionType.Read, PermissionType.Write, PermissionType.Execute, PermissionType.Schedule} {
+		types[t] = true
+	}
+
+	granted := map[string]bool{}
+	for _, p := range this.Permissions {
+		if (p.User == "") == (p.Group == "") {
+			problem("a permission must have either a user or a group")
+			continue
+		}
+		key := permissionKey(p.User, false)
+		if p.Group != "" {
+			key = permissionKey(p.Group, true)
+		}
+		if granted[key] {
+			problem("permissions of %s are given twice", key)
+		}
+		granted[key] = true
+		for _, t := range p.Permissions {
+			if !types[strings.ToUpper(t)] {
+				problem("unknown permission %s", t)
+			}
+		}
+	}
+
+	scheduled := map[string]bool{}
+	for _, s := range this.Schedules {
+		if s.Flow == "" || s.Cron == "" {
+			problem("a schedule must have a flow and a cron expression")
+			continue
+		}
+		if scheduled[s.Flow] {
+			problem("flow %s is scheduled twice, azkaban keeps a single schedule per flow", s.Flow)
+		}
+		scheduled[s.Flow] = true
+		if s.SLA == nil {
+			continue
+		}
+		for _, setting := range s.SLA.Settings {
+			if rule := strings.ToUpper(setting.Rule); rule != "" && rule != SLARule.Success && rule != SLARule.Finish {
+				problem("unknown SLA rule %s of flow %s", setting.Rule, s.Flow)
+			}
+			if setting.Duration < time.Minute {
+				problem("SLA durations of flow %s must be at least a minute", s.Flow)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "\n"))
+	}
+
+	return nil
+
+}
+
+// the archive of the spec, packaged when the source is a directory
+func (this *ProjectSpec) archive() ([]byte, error) {
+
+	info, err := os.Stat(this.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return ioutil.ReadFile(this.Source)
+	}
+
+	var buffer bytes.Buffer
+	err = PackageDir(&buffer, this.Source, PackageOptions{Include: this.Include, Exclude: this.Exclude})
+
+	return buffer.Bytes(), err
+
+}
+
+// actions of the changes of a plan
+var ChangeAction = struct{ Create, Update, Remove string }{"create", "update", "remove"}
+
+// Change is a step of a plan, such as adding a proxy user.
+type Change struct {
+	Action string `json:"action"`
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Detail string `json:"detail,omitempty"`
+
+	apply func() error
+}
+
+func (this Change) String() string {
+
+	sign := map[string]string{ChangeAction.Create: "+", ChangeAction.Update: "~", ChangeAction.Remove: "-"}[this.Action]
+
+	if this.Detail == "" {
+		return fmt.Sprintf("%s %s %s", sign, this.Kind, this.Name)
+	}
+
+	return fmt.Sprintf("%s %s %s: %s", sign, this.Kind, this.Name, this.Detail)
+
+}
+
+// Plan lists the changes bringing a project to its spec, in the order they are applied.
+type Plan struct {
+	Project string   `json:"project"`
+	Changes []Change `json:"changes"`
+}
+
+func (this *Plan) String() string {
+
+	if len(this.Changes) == 0 {
+		return fmt.Sprintf("project %s is up to date\n", this.Project)
+	}
+
+	var b strings.Builder
+	for _, c := range this.Changes {
+		b.WriteString(c.String())
+		b.WriteString("\n")
+	}
+
+	return b.String()
+
+}
+
+func (this *Plan) add(action, kind, name, detail string, apply func() error) {
+	this.Changes = append(this.Changes, Change{Action: action, Kind: kind, Name: name, Detail: detail, apply: apply})
+}
+
+// Apply brings a project to its spec: the project is created when missing, the archive is
+// uploaded when its hash differs from the one of the latest upload, and permissions, proxy users,
+// schedules and SLAs not in the spec are removed. The permissions of the user applying the spec
+// are never removed, so it cannot lock itself out.
+//
+// The plan of the changes is returned, with dryRun nothing is changed. Applying a spec again
+// gives an empty plan.
+func (this *Client) Apply(spec *ProjectSpec, dryRun bool) (*Plan, error) {
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	plan, err := this.plan(spec)
+	if err != nil || dryRun {
+		return plan, err
+	}
+
+	for _, change := range plan.Changes {
+		if err = change.apply(); err != nil {
+			return plan, fmt.Errorf("%s: %v", strings.TrimLeft(change.String(), "+~- "), err)
+		}
+	}
+
+	return plan, nil
+
+}
+
+func (this *Client) plan(spec *ProjectSpec) (*Plan, error) {
+
+	// init return
+	plan := &Plan{Project: spec.Name}
+	name := spec.Name
+
+	_, err := this.GetProject(name)
+	exists := err == nil
+	if err != nil && err != ProjectNotFound {
+		return nil, err
+	}
+
+	if !exists {
+		plan.add(ChangeAction.Create, "project", name, spec.Description, func() error {
+			_, err := this.CreateProject(name, spec.Description)
+			return err
+		})
+	}
+
+	if err = this.planArchive(plan, spec, exists); err != nil {
+		return nil, err
+	}
+
+	if err = this.planPermissions(plan, spec, exists); err != nil {
+		return nil, err
+	}
+
+	if err = this.planProxyUsers(plan, spec, exists); err != nil {
+		return nil, err
+	}
+
+	if err = this.planSchedules(plan, spec, exists); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+
+}
+
+// archives are compared by hash, archives packaged by PackageDir are identical for identical trees
+func (this *Client) planArchive(plan *Plan, spec *ProjectSpec, exists bool) error {
+
+	archive, err := spec.archive()
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(archive)
+	digest := hex.EncodeToString(sum[:])
+
+	remote := ""
+	if exists {
+		hash := sha256.New()
+		err = this.DownloadProject(context.Background(), spec.Name, 0, hash)
+		if err == nil {
+			remote = hex.EncodeToString(hash.Sum(nil))
+		} else if err != ArchiveNotFound {
+			return err
+		}
+	}
+
+	if remote == digest {
+		return nil
+	}
+
+	action, detail := ChangeAction.Create, "sha256 "+digest[:12]
+	if remote != "" {
+		action, detail = ChangeAction.Update, fmt.Sprintf("sha256 %s -> %s", remote[:12], digest[:12])
+	}
+
+	plan.add(action, "archive", spec.Name+".zip", detail, func() error {
+		_, err := this.UploadProject(context.Background(), spec.Name, bytes.NewReader(archive), spec.Name+".zip", nil)
+		return err
+	})
+
+	return nil
+
+}
+
+// the key of the permission of a user or a group
+func permissionKey(name string, group bool) string {
+	if group {
+		return "group " + name
+	}
+	return "user " + name
+}
+
+func (this *Client) planPermissions(plan *Plan, spec *ProjectSpec, exists bool) error {
+
+	// a new project gives all permissions to its creator
+	var current []Permission
+	if exists {
+		var err error
+		if current, err = this.FetchPermissions(spec.Name); err != nil {
+			return err
+		}
+	} else if this.Username != "" {
+		current = []Permission{{Name: this.Username, Permissions: []string{PermissionType.Admin}}}
+	}
+
+	granted := map[string]Permission{}
+	for _, p := range current {
+		granted[permissionKey(p.Name, p.Group)] = p
+	}
+
+	wanted := map[string]bool{}
+
+	for _, s := range spec.Permissions {
+
+		p := Permission{Name: s.User}
+		if s.Group != "" {
+			p = Permission{Name: s.Group, Group: true}
+		}
+		for _, t := range s.Permissions {
+			p.Permissions = append(p.Permissions, strings.ToUpper(t))
+		}
+		sort.Strings(p.Permissions)
+
+		key := permissionKey(p.Name, p.Group)
+		wanted[key] = true
+
+		was, ok := granted[key]
+		switch {
+		case !ok:
+			plan.add(ChangeAction.Create, "permission", key, strings.Join(p.Permissions, ","), func() error {
+				return this.AddPermission(spec.Name, p)
+			})
+		case strings.Join(was.Permissions, ",") != strings.Join(p.Permissions, ","):
+			plan.add(ChangeAction.Update, "permission", key, strings.Join(was.Permissions, ",")+" -> "+strings.Join(p.Permissions, ","), func() error {
+				return this.ChangePermission(spec.Name, p)
+			})
+		}
+
+	}
+
+	for _, p := range current {
+		key := permissionKey(p.Name, p.Group)
+		if wanted[key] || (!p.Group && p.Name == this.Username) {
+			continue
+		}
+		p := p
+		plan.add(ChangeAction.Remove, "permission", key, strings.Join(p.Permissions, ","), func() error {
+			return this.RemovePermission(spec.Name, p.Name, p.Group)
+		})
+	}
+
+	return nil
+
+}
+
+func (this *Client) planProxyUsers(plan *Plan, spec *ProjectSpec, exists bool) error {
+
+	var current []string
+	if exists {
+		var err error
+		if current, err = this.FetchProxyUsers(spec.Name); err != nil {
+			return err
+		}
+	}
+
+	added, wanted := map[string]bool{}, map[string]bool{}
+	for _, user := range current {
+		added[user] = true
+	}
+
+	for _, user := range spec.ProxyUsers {
+		wanted[user] = true
+		if added[user] {
+			continue
+		}
+		user := user
+		plan.add(ChangeAction.Create, "proxy user", user, "", func() error {
+			return this.AddProxyUser(spec.Name, user)
+		})
+	}
+
+	for _, user := range current {
+		if wanted[user] {
+			continue
+		}
+		user := user
+		plan.add(ChangeAction.Remove, "proxy user", user, "", func() error {
+			return this.RemoveProxyUser(spec.Name, user)
+		})
+	}
+
+	return nil
+
+}
+
+func (this *Client) planSchedules(plan *Plan, spec *ProjectSpec, exists bool) error {
+
+	current := map[string]Schedule{}
+	if exists {
+		schedules, err := this.FetchSchedules(spec.Name)
+		if err != nil {
+			return err
+		}
+		for _, s := range schedules {
+			current[s.Flow] = s
+		}
+	}
+
+	wanted := map[string]bool{}
+
+	for _, s := range spec.Schedules {
+
+		s := s
+		wanted[s.Flow] = true
+
+		// the id of the schedule, known once it is created
+		was, ok := current[s.Flow]
+		id := new(int)
+		*id = was.ID
+
+		if !ok || was.Cron != s.Cron {
+
+			action, detail := ChangeAction.Create, s.Cron
+			if ok {
+				action, detail = ChangeAction.Update, fmt.Sprintf("%s -> %s", scheduleString(was), s.Cron)
+			}
+
+			plan.add(action, "schedule", s.Flow, detail, func() error {
+				var err error
+				*id, err = this.ScheduleCronFlow(spec.Name, s.Flow, s.Cron)
+				return err
+			})
+
+		}
+
+		// a new schedule has no SLA
+		var sla *SLA
+		if ok && was.Cron == s.Cron {
+			var err error
+			if sla, err = this.FetchSLA(was.ID); err != nil {
+				return err
+			}
+		}
+
+		planSLA(plan, s.Flow, sla, s.SLA, func(sla SLA) error {
+			return this.SetSLA(*id, sla)
+		})
+
+	}
+
+	var removed []string
+	for flow := range current {
+		if !wanted[flow] {
+			removed = append(removed, flow)
+		}
+	}
+	sort.Strings(removed)
+
+	for _, flow := range removed {
+		was := current[flow]
+		plan.add(ChangeAction.Remove, "schedule", flow, scheduleString(was), func() error {
+			return this.RemoveSchedule(was.ID)
+		})
+	}
+
+	return nil
+
+}
+
+// a schedule as shown by plans, its cron expression or its period
+func scheduleString(s Schedule) string {
+	if s.Cron != "" {
+		return s.Cron
+	}
+	return fmt.Sprintf("every %s from %s", s.Period, s.FirstSchedAt)
+}
+
+// an SLA comparable with another, rules in upper case and sorted settings
+func normalizeSLA(sla *SLA) SLA {
+
+	if sla == nil {
+		return SLA{}
+	}
+
+	normal := SLA{Emails: append([]string{}, sla.Emails...)}
+	sort.Strings(normal.Emails)
+
+	for _, s := range sla.Settings {
+		s.Rule = strings.ToUpper(s.Rule)
+		if s.Rule == "" {
+			s.Rule = SLARule.Success
+		}
+		s.Duration = s.Duration.Truncate(time.Minute)
+		normal.Settings = append(normal.Settings, s)
+	}
+	sort.Slice(normal.Settings, func(i, j int) bool { return normal.Settings[i].String() < normal.Settings[j].String() })
+
+	return normal
+
+}
+
+func slaString(sla SLA) string {
+	var settings []string
+	for _, s := range sla.Settings {
+		settings = append(settings, s.String())
+	}
+	return fmt.Sprintf("%s to %s", strings.Join(settings, "; "), strings.Join(sla.Emails, ","))
+}
+
+func planSLA(plan *Plan, flow string, current, wanted *SLA, set func(SLA) error) {
+
+	was, sla := normalizeSLA(current), normalizeSLA(wanted)
+	if slaString(was) == slaString(sla) {
+		return
+	}
+
+	switch {
+	case len(sla.Settings) == 0:
+		plan.add(ChangeAction.Remove, "sla", flow, slaString(was), func() error { return set(SLA{}) })
+	case len(was.Settings) == 0:
+		plan.add(ChangeAction.Create, "sla", flow, slaString(sla), func() error { return set(sla) })
+	default:
+		plan.add(ChangeAction.Update, "sla", flow, slaString(was)+" -> "+slaString(sla), func() error { return set(sla) })
+	}
+
+}
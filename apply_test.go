@@ -0,0 +1,184 @@
+package azkaban_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wanglun/azkaban"
+	"github.com/wanglun/azkaban/azkabantest"
+)
+
+// the changes of a plan as strings
+func changes(plan *azkaban.Plan) []string {
+	var list []string
+	for _, c := range plan.Changes {
+		list = append(list, c.String())
+	}
+	return list
+}
+
+func TestApply(t *testing.T) {
+
+	server := azkabantest.NewServer()
+	defer server.Close()
+
+	client, err := server.Client()
+	assert.Nil(t, err)
+
+	// a copy of the test flow, changed below
+	source := t.TempDir()
+	for _, name := range []string{"foo.job", "bar.job", "flow.properties"} {
+		content, err := ioutil.ReadFile(filepath.Join("testdata", "testflow", name))
+		assert.Nil(t, err)
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(source, name), content, 0644))
+	}
+
+	spec := &azkaban.ProjectSpec{
+		Name:        "applied",
+		Description: "applied project",
+		Source:      source,
+		Permissions: []azkaban.PermissionSpec{
+			{User: "deploy", Permissions: []string{"read", "execute"}},
+			{Group: "analysts", Permissions: []string{"read"}},
+		},
+		ProxyUsers: []string{"etl"},
+		Schedules: []azkaban.ScheduleSpec{{
+			Flow: "bar",
+			Cron: "0 0 2 ? * *",
+			SLA: &azkaban.SLA{
+				Emails:   []string{"oncall@example.com"},
+				Settings: []azkaban.SLASetting{{Rule: azkaban.SLARule.Success, Duration: 90 * time.Minute, Email: true}},
+			},
+		}},
+	}
+
+	// nothing is changed in dry run
+	plan, err := client.Apply(spec, true)
+	assert.Nil(t, err)
+	assert.Len(t, plan.Changes, 7)
+	assert.Equal(t, "+ project applied: applied project", plan.Changes[0].String())
+	_, err = client.GetProject("applied")
+	assert.Equal(t, azkaban.ProjectNotFound, err)
+
+	plan, err = client.Apply(spec, false)
+	assert.Nil(t, err)
+	assert.Len(t, plan.Changes, 7)
+
+	permissions, err := client.FetchPermissions("applied")
+	assert.Nil(t, err)
+	assert.Equal(t, []azkaban.Permission{
+		{Name: "azkaban", Permissions: []string{"ADMIN"}},
+		{Name: "deploy", Permissions: []string{"EXECUTE", "READ"}},
+		{Name: "analysts", Group: true, Permissions: []string{"READ"}},
+	}, permissions)
+
+	schedule, err := client.FetchSchedule("applied", "bar")
+	assert.Nil(t, err)
+	sla, err := client.FetchSLA(schedule.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, spec.Schedules[0].SLA, sla)
+
+	// applying again changes nothing
+	plan, err = client.Apply(spec, false)
+	assert.Nil(t, err)
+	assert.Empty(t, plan.Changes)
+	assert.Equal(t, "project applied is up to date\n", plan.String())
+
+	// changes made outside of the spec are undone, along with the spec changes
+	assert.Nil(t, client.AddProxyUser("applied", "intruder"))
+	assert.Nil(t, client.AddPermission("applied", azkaban.Permission{Name: "intruder", Permissions: []string{"ADMIN"}}))
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(source, "foo.job"), []byte("type=command\ncommand=echo changed\n"), 0644))
+	spec.Permissions[0].Permissions = []string{"admin"}
+	spec.Schedules[0].SLA.Settings[0].Kill = true
+
+	plan, err = client.Apply(spec, false)
+	assert.Nil(t, err)
+	assert.Len(t, plan.Changes, 5)
+	assert.Equal(t, []string{"archive", "permission", "permission", "proxy user", "sla"}, []string{
+		plan.Changes[0].Kind, plan.Changes[1].Kind, plan.Changes[2].Kind, plan.Changes[3].Kind, plan.Changes[4].Kind,
+	})
+	assert.Equal(t, "~ permission user deploy: EXECUTE,READ -> ADMIN", plan.Changes[1].String())
+	assert.Equal(t, "- permission user intruder: ADMIN", plan.Changes[2].String())
+	assert.Equal(t, "- proxy user intruder", plan.Changes[3].String())
+
+	// a new cron expression replaces the schedule, and its SLA is set again
+	spec.Schedules[0].Cron = "0 0 3 ? * *"
+	plan, err = client.Apply(spec, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"~ schedule bar: 0 0 2 ? * * -> 0 0 3 ? * *",
+		"+ sla bar: flow success within 1h30m0s, email+kill to oncall@example.com",
+	}, changes(plan))
+
+	schedule, err = client.FetchSchedule("applied", "bar")
+	assert.Nil(t, err)
+	sla, err = client.FetchSLA(schedule.ID)
+	assert.Nil(t, err)
+	assert.True(t, sla.Settings[0].Kill)
+
+	// schedules left out of the spec are removed
+	spec.Schedules = nil
+	plan, err = client.Apply(spec, false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"- schedule bar: 0 0 3 ? * *"}, changes(plan))
+
+	_, err = client.FetchSchedule("applied", "bar")
+	assert.Equal(t, azkaban.ScheduleNotFound, err)
+
+}
+
+func TestLoadProjectSpec(t *testing.T) {
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "project.yaml")
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`
+name: etl
+source: flows
+permissions:
+  - user: deploy
+    permissions: [admin]
+schedules:
+  - flow: load
+    cron: 0 0 2 ? * *
+    sla:
+      emails: [oncall@example.com]
+      settings:
+        - rule: finish
+          duration: 2h
+          kill: true
+`), 0600))
+
+	spec, err := azkaban.LoadProjectSpec(path)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "flows"), spec.Source)
+	assert.Equal(t, 2*time.Hour, spec.Schedules[0].SLA.Settings[0].Duration)
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`
+name: etl
+permissions:
+  - user: deploy
+    group: deployers
+    permissions: [own]
+schedules:
+  - flow: load
+    cron: 0 0 2 ? * *
+  - flow: load
+    cron: 0 0 3 ? * *
+`), 0600))
+
+	_, err = azkaban.LoadProjectSpec(path)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "no source")
+	assert.Contains(t, err.Error(), "either a user or a group")
+	assert.Contains(t, err.Error(), "scheduled twice")
+
+	_, err = azkaban.LoadProjectSpec(filepath.Join(dir, "missing.yaml"))
+	assert.True(t, os.IsNotExist(err))
+
+}
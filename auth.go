@@ -15,6 +15,12 @@ func (this *Client) Authenticate(username, password string) error {
 	values.Add("password", password)
 
 	// init session
-	return this.action(http.MethodPost, "/", values, this)
+	if err := this.action(http.MethodPost, "/", values, this); err != nil {
+		return err
+	}
+
+	this.Username = username
+
+	return nil
 
 }
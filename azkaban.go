@@ -10,15 +10,34 @@ import (
 	"net/url"
 	"reflect"
 	"strings"
+	"time"
 )
 
 var EmptyResponse = errors.New("Empty response")
 
+// SessionExpired is returned when the session id is not known by the server, because it expired
+// or the server restarted.
+var SessionExpired = errors.New("session")
+
 // Client is the base struct for requests
 type Client struct {
 	Endpoint string
 	Session  string `json:"session.id"`
 	Status   string `json:"status"`
+
+	// Username is the user logged in with Authenticate or Login, when known
+	Username string `json:"-"`
+
+	// Location is the time zone of the server, the times of executions, execution flows and their
+	// nodes, projects and project log events are returned in it when set, in the local time zone
+	// otherwise
+	Location *time.Location `json:"-"`
+
+	// HTTPClient sends the requests, a client skipping the TLS verification is used when nil
+	HTTPClient *http.Client `json:"-"`
+
+	// Defaults are applied to the flows executed with ExecuteFlow
+	Defaults ExecutionDefaults `json:"-"`
 }
 
 type Detail struct {
@@ -36,6 +55,14 @@ func New(endpoint string) *Client {
 
 }
 
+// init http.Client without ssl verification, unless one is set
+func (this *Client) httpClient() *http.Client {
+	if this.HTTPClient != nil {
+		return this.HTTPClient
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+}
+
 func (this *Client) action(method, route string, values url.Values, data interface{}) error {
 
 	// init vars
@@ -62,11 +89,8 @@ func (this *Client) action(method, route string, values url.Values, data interfa
 			request.URL.RawQuery = values.Encode()
 		}
 
-		// init http.Client without ssl verification
-		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
-
 		// do request
-		if response, err = client.Do(request); err == nil {
+		if response, err = this.httpClient().Do(request); err == nil {
 
 			defer response.Body.Close()
 
@@ -83,7 +107,9 @@ func (this *Client) action(method, route string, values url.Values, data interfa
 					if err = json.Unmarshal(content, &detail); err == nil {
 
 						// return azkaban error as a go error
-						if detail.Error != "" {
+						if detail.Error == SessionExpired.Error() {
+							err = SessionExpired
+						} else if detail.Error != "" {
 							err = errors.New(detail.Error)
 						} else if detail.Status == "error" {
 							err = errors.New(detail.Message)
@@ -120,3 +146,20 @@ func (this *Client) action(method, route string, values url.Values, data interfa
 	return err
 
 }
+
+// convert a timestamp in milliseconds, as sent by azkaban, into a time.Time,
+// azkaban's -1 for times that are not known yet gives the zero time
+func timeFromMillis(ms int64) time.Time {
+	if ms <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond))
+}
+
+// set a time in the server time zone, zero times are kept zero
+func (this *Client) localize(t time.Time) time.Time {
+	if t.IsZero() || this.Location == nil {
+		return t
+	}
+	return t.In(this.Location)
+}
@@ -1,4 +1,4 @@
-package azkaban
+package azkaban_test
 
 import (
 	"os"
@@ -6,28 +6,34 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/wanglun/azkaban"
+	"github.com/wanglun/azkaban/azkabantest"
 )
 
-const AZKABAN_ENDPOINT = "AZKABAN_ENDPOINT"
-const AZKABAN_USER = "AZKABAN_USER"
-const AZKABAN_PASS = "AZKABAN_PASS"
-
 const PROJECT_NAME = "test_client"
 const PROJECT_DESC = "project for client testing"
 const FLOW_ZIP_PATH = "./testdata/testflow.zip"
 
+// set to run TestClient against the server of the azkaban profile, which it writes to
+const AZKABAN_LIVE_TEST = "AZKABAN_LIVE_TEST"
+
 func TestClient(t *testing.T) {
-	endpoint := os.Getenv(AZKABAN_ENDPOINT)
-	user := os.Getenv(AZKABAN_USER)
-	pass := os.Getenv(AZKABAN_PASS)
-	if endpoint == "" || user == "" || pass == "" {
-		t.Fatal("endpoint/user/pass is empty")
+	// a fake server, unless the profile given by the config file or AZKABAN_ENDPOINT, AZKABAN_USER
+	// and AZKABAN_PASS is asked for, its test_client project is deleted and created again
+	var client *azkaban.Client
+	var err error
+	if os.Getenv(AZKABAN_LIVE_TEST) != "" {
+		client, err = azkaban.NewFromProfile("")
+		if err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		server := azkabantest.NewServer()
+		defer server.Close()
+		client, err = server.Client()
+		assert.Nil(t, err)
 	}
 
-	client := New(endpoint)
-	err := client.Authenticate(user, pass)
-	assert.Nil(t, err)
-
 	// create project
 	_, err = client.GetProject(PROJECT_NAME)
 	if err == nil {
@@ -54,12 +60,12 @@ func TestClient(t *testing.T) {
 	t.Logf("%#v", jobs)
 
 	// execute flow
-	execute, err := client.ExecuteFlow(PROJECT_NAME, flow_id, ConcurrentOptionDefault, nil)
+	execute, err := client.ExecuteFlow(PROJECT_NAME, flow_id, azkaban.ConcurrentOptionDefault, nil)
 	assert.Nil(t, err)
 	t.Logf("%#v", execute)
 
 	// execute flow with override properties
-	execute, err = client.ExecuteFlow(PROJECT_NAME, flow_id, ConcurrentOptionIgnore, map[string]string{"test.p1": "100", "test.p2": "p2_overrided"})
+	execute, err = client.ExecuteFlow(PROJECT_NAME, flow_id, azkaban.ConcurrentOptionIgnore, map[string]string{"test.p1": "100", "test.p2": "p2_overrided"})
 	assert.Nil(t, err)
 	t.Logf("%#v", execute)
 
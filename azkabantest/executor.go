@@ -0,0 +1,665 @@
+package azkabantest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wanglun/azkaban"
+)
+
+// an execution of a flow, its nodes are planned when it is submitted and their state is derived
+// from the clock of the server, so executions progress without any goroutine
+type execution struct {
+	id        int64
+	project   *project
+	flow      string
+	user      string
+	params    map[string]string
+	option    string
+	executor  int
+	emails    map[string][]string
+	submitted time.Time
+	killed    time.Time
+	nodes     []*run
+}
+
+// a planned node of an execution
+type run struct {
+	id, nested, kind, flow string
+	in                     []string
+
+	// when the job starts and ends, or when it is cancelled because a dependency failed
+	start, end time.Time
+	fails      bool
+	cancelled  bool
+
+	// nodes of an embedded flow
+	nodes []*run
+}
+
+// plan the nodes of a graph starting at a time, each job runs JobDuration once its dependencies finished
+func (s *Server) plan(project string, g *azkaban.Graph, start time.Time) []*run {
+
+	// uploads with cycles are refused
+	order, _ := g.TopologicalSort()
+
+	runs := map[string]*run{}
+
+	for _, id := range order {
+
+		node := g.Node(id)
+		r := &run{id: id, nested: g.NestedID(id), kind: node.Type, flow: node.EmbeddedFlow(), in: g.Parents(id)}
+
+		begin := start
+		for _, parent := range r.in {
+			if runs[parent].end.After(begin) {
+				begin = runs[parent].end
+			}
+			r.cancelled = r.cancelled || runs[parent].failed()
+		}
+
+		switch sub := g.SubGraph(id); {
+		case r.cancelled:
+			r.end = begin
+		case sub != nil:
+			r.start, r.end = begin, begin
+			r.nodes = s.plan(project, sub, begin)
+			for _, n := range r.nodes {
+				if n.end.After(r.end) {
+					r.end = n.end
+				}
+			}
+		default:
+			r.start, r.end = begin, begin.Add(s.JobDuration)
+			r.fails = s.failing[[2]string{project, id}]
+		}
+
+		runs[id] = r
+
+	}
+
+	// in the order of the graph
+	var nodes []*run
+	for _, id := range g.Nodes() {
+		if r, ok := runs[id]; ok {
+			nodes = append(nodes, r)
+		}
+	}
+
+	return nodes
+
+}
+
+func (r *run) failed() bool {
+	if r.fails || r.cancelled {
+		return true
+	}
+	for _, n := range r.nodes {
+		if n.failed() {
+			return true
+		}
+	}
+	return false
+}
+
+// state of a node at a time, the execution was killed at that time when killed is set,
+// zero times are not known yet
+func (r *run) state(at time.Time, killed bool) (string, time.Time, time.Time) {
+
+	switch {
+
+	case r.cancelled:
+		if killed && at.Before(r.end) {
+			return azkaban.ExecutionStatus.Cancelled, at, at
+		}
+		if !at.Before(r.end) {
+			return azkaban.ExecutionStatus.Cancelled, r.end, r.end
+		}
+		return azkaban.ExecutionStatus.Ready, time.Time{}, time.Time{}
+
+	case r.nodes != nil:
+		return aggregate(r.nodes, at, killed)
+
+	case at.Before(r.start):
+		if killed {
+			return azkaban.ExecutionStatus.Cancelled, at, at
+		}
+		return azkaban.ExecutionStatus.Ready, time.Time{}, time.Time{}
+
+	case at.Before(r.end):
+		if killed {
+			return azkaban.ExecutionStatus.Killed, r.start, at
+		}
+		return azkaban.ExecutionStatus.Running, r.start, time.Time{}
+
+	case r.fails:
+		return azkaban.ExecutionStatus.Failed, r.start, r.end
+
+	}
+
+	return azkaban.ExecutionStatus.Succeeded, r.start, r.end
+
+}
+
+// state of a flow from the state of its nodes
+func aggregate(nodes []*run, at time.Time, killed bool) (string, time.Time, time.Time) {
+
+	var (
+		start, end                     time.Time
+		finished                       = true
+		failed, wasKilled, wasCanceled bool
+	)
+
+	for _, n := range nodes {
+
+		status, s, e := n.state(at, killed)
+
+		if !s.IsZero() && (start.IsZero() || s.Before(start)) {
+			start = s
+		}
+		if e.After(end) {
+			end = e
+		}
+
+		switch status {
+		case azkaban.ExecutionStatus.Ready, azkaban.ExecutionStatus.Running, azkaban.ExecutionStatus.FailedFinishing:
+			finished = false
+		}
+
+		failed = failed || status == azkaban.ExecutionStatus.Failed || status == azkaban.ExecutionStatus.FailedFinishing
+		wasKilled = wasKilled || status == azkaban.ExecutionStatus.Killed
+		wasCanceled = wasCanceled || status == azkaban.ExecutionStatus.Cancelled
+
+	}
+
+	switch {
+	case !finished && start.IsZero():
+		return azkaban.ExecutionStatus.Ready, time.Time{}, time.Time{}
+	case !finished && failed:
+		return azkaban.ExecutionStatus.FailedFinishing, start, time.Time{}
+	case !finished:
+		return azkaban.ExecutionStatus.Running, start, time.Time{}
+	case wasKilled:
+		return azkaban.ExecutionStatus.Killed, start, end
+	case failed:
+		return azkaban.ExecutionStatus.Failed, start, end
+	case wasCanceled:
+		return azkaban.ExecutionStatus.Cancelled, start, end
+	}
+
+	return azkaban.ExecutionStatus.Succeeded, start, end
+
+}
+
+// the time the state of an execution is computed at, and whether it was killed by then
+func (s *Server) clock(e *execution) (time.Time, bool) {
+	now := s.now()
+	if !e.killed.IsZero() && !now.Before(e.killed) {
+		return e.killed, true
+	}
+	return now, false
+}
+
+func (s *Server) state(e *execution) (string, time.Time, time.Time) {
+
+	at, killed := s.clock(e)
+
+	status, _, end := aggregate(e.nodes, at, killed)
+
+	if killed {
+		return azkaban.ExecutionStatus.Killed, e.submitted, at
+	}
+
+	// the flow starts when it is submitted, and ends then when it has no job
+	switch status {
+	case azkaban.ExecutionStatus.Running, azkaban.ExecutionStatus.FailedFinishing:
+	case azkaban.ExecutionStatus.Ready:
+		status = azkaban.ExecutionStatus.Running
+	default:
+		if end.IsZero() {
+			end = e.submitted
+		}
+	}
+
+	return status, e.submitted, end
+
+}
+
+func (s *Server) running(e *execution) bool {
+	_, _, end := s.state(e)
+	return end.IsZero()
+}
+
+// executions of a flow, most recent first
+func (s *Server) flowExecutions(p *project, flow string) []*execution {
+
+	var executions []*execution
+	for _, e := range s.executions {
+		if e.project == p && e.flow == flow {
+			executions = append(executions, e)
+		}
+	}
+
+	sort.Slice(executions, func(i, j int) bool { return executions[i].id > executions[j].id })
+
+	return executions
+
+}
+
+func (s *Server) fetchFlowExecutions(w http.ResponseWriter, r *http.Request, p *project) {
+
+	flow := r.FormValue("flow")
+	executions := s.flowExecutions(p, flow)
+	from, to := page(len(executions), formInt(r, "start", 0), formInt(r, "length", len(executions)))
+
+	list := []map[string]interface{}{}
+	for _, e := range executions[from:to] {
+		list = append(list, s.executionSummary(e))
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"executions": list,
+		"project":    p.name,
+		"projectId":  p.id,
+		"flow":       flow,
+		"from":       from,
+		"length":     to - from,
+		"total":      len(executions),
+	})
+
+}
+
+// an execution as listed by fetchFlowExecutions
+func (s *Server) executionSummary(e *execution) map[string]interface{} {
+
+	status, start, end := s.state(e)
+
+	return map[string]interface{}{
+		"execId":     e.id,
+		"projectId":  e.project.id,
+		"flowId":     e.flow,
+		"submitUser": e.user,
+		"submitTime": millis(e.submitted),
+		"startTime":  millis(start),
+		"endTime":    millis(end),
+		"status":     status,
+	}
+
+}
+
+func (s *Server) handleExecutor(w http.ResponseWriter, r *http.Request, user string) {
+
+	switch ajax := r.FormValue("ajax"); ajax {
+	case "executeFlow":
+		s.executeFlow(w, r, user)
+	case "getRunning":
+		s.getRunning(w, r)
+	case "fetchexecflow":
+		if e := s.execution(w, r); e != nil {
+			writeJSON(w, s.executionFlow(e))
+		}
+	case "fetchExecJobLogs":
+		if e := s.execution(w, r); e != nil {
+			s.fetchExecJobLogs(w, r, e)
+		}
+	case "cancelFlow":
+		s.cancelFlow(w, r)
+	case "flowInfo":
+		s.flowInfo(w, r)
+	case "fetchExecutors":
+		s.fetchExecutors(w)
+	case "reloadExecutors":
+		s.reloadExecutors(w)
+	default:
+		writeError(w, "Unknown ajax call %s.", ajax)
+	}
+
+}
+
+// the execution of the execid parameter, an error is written when it is not known
+func (s *Server) execution(w http.ResponseWriter, r *http.Request) *execution {
+
+	id, _ := strconv.ParseInt(r.FormValue("execid"), 10, 64)
+
+	e, ok := s.executions[id]
+	if !ok {
+		writeError(w, "Cannot find execution '%s'", r.FormValue("execid"))
+		return nil
+	}
+
+	return e
+
+}
+
+func (s *Server) executeFlow(w http.ResponseWriter, r *http.Request, user string) {
+
+	name, flow := r.FormValue("project"), r.FormValue("flow")
+
+	p, ok := s.projects[name]
+	if !ok {
+		writeError(w, "Project %s does not exist", name)
+		return
+	}
+
+	if !p.hasFlow(flow) {
+		writeError(w, "Flow %s cannot be found in project %s", flow, name)
+		return
+	}
+
+	option := r.FormValue("concurrentOption")
+
+	if option == "skip" {
+		for _, e := range s.flowExecutions(p, flow) {
+			if s.running(e) {
+				writeError(w, "Flow %s is already running. Skipping execution.", flow)
+				return
+			}
+		}
+	}
+
+	params := formMap(r, "flowOverride")
+
+	executor, ok := s.dispatch(params)
+	if !ok {
+		writeError(w, "Executor with id %s does not exist", params["useExecutor"])
+		return
+	}
+
+	e := &execution{
+		id:        int64(s.nextID()),
+		project:   p,
+		flow:      flow,
+		user:      user,
+		params:    params,
+		option:    option,
+		executor:  executor,
+		submitted: s.now(),
+	}
+	e.nodes = s.plan(name, azkaban.NewGraph(p.local.FlowGraph(flow)), e.submitted)
+
+	// notification emails of the flow, unless overridden
+	e.emails = s.emails(p, flow)
+	for _, kind := range []string{"failure", "success"} {
+		if r.FormValue(kind+"EmailsOverride") == "true" {
+			e.emails[kind+".emails"] = splitEmails(r.FormValue(kind + "Emails"))
+		}
+	}
+
+	s.executions[e.id] = e
+
+	writeJSON(w, map[string]interface{}{
+		"project": name,
+		"flow":    flow,
+		"execid":  e.id,
+		"message": fmt.Sprintf("Execution submitted successfully with exec id %d", e.id),
+	})
+
+}
+
+func (s *Server) getRunning(w http.ResponseWriter, r *http.Request) {
+
+	ids := []int64{}
+
+	if p, ok := s.projects[r.FormValue("project")]; ok {
+		for _, e := range s.flowExecutions(p, r.FormValue("flow")) {
+			if s.running(e) {
+				ids = append(ids, e.id)
+			}
+		}
+	}
+
+	writeJSON(w, map[string]interface{}{"execIds": ids})
+
+}
+
+func (s *Server) executionFlow(e *execution) map[string]interface{} {
+
+	at, killed := s.clock(e)
+	status, start, end := s.state(e)
+
+	var nodes func(runs []*run) []map[string]interface{}
+	nodes = func(runs []*run) []map[string]interface{} {
+
+		list := []map[string]interface{}{}
+
+		for _, r := range runs {
+
+			status, start, end := r.state(at, killed)
+
+			node := map[string]interface{}{
+				"id":         r.id,
+				"nestedId":   r.nested,
+				"type":       r.kind,
+				"status":     status,
+				"in":         r.in,
+				"attempt":    0,
+				"startTime":  millis(start),
+				"endTime":    millis(end),
+				"updateTime": millis(at),
+			}
+
+			if r.flow != "" {
+				node["flow"] = r.flow
+				node["nodes"] = nodes(r.nodes)
+			}
+
+			list = append(list, node)
+
+		}
+
+		return list
+
+	}
+
+	return map[string]interface{}{
+		"execid":     e.id,
+		"project":    e.project.name,
+		"projectId":  e.project.id,
+		"flowId":     e.flow,
+		"flow":       e.flow,
+		"submitUser": e.user,
+		"status":     status,
+		"attempt":    0,
+		"submitTime": millis(e.submitted),
+		"startTime":  millis(start),
+		"endTime":    millis(end),
+		"updateTime": millis(at),
+		"nodes":      nodes(e.nodes),
+	}
+
+}
+
+// find a node by nested id
+func findRun(runs []*run, nested string) *run {
+	for _, r := range runs {
+		if r.nested == nested {
+			return r
+		}
+		if found := findRun(r.nodes, nested); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// the log of a job, written as the job progresses
+func (s *Server) jobLog(e *execution, r *run) string {
+
+	at, killed := s.clock(e)
+	status, start, end := r.state(at, killed)
+
+	if start.IsZero() || r.cancelled {
+		return ""
+	}
+
+	var log strings.Builder
+
+	line := func(t time.Time, format string, args ...interface{}) {
+		fmt.Fprintf(&log, "%s INFO - %s\n", t.UTC().Format("02-01-2006 15:04:05 MST"), fmt.Sprintf(format, args...))
+	}
+
+	line(start, "Starting job %s at %d", r.nested, millis(start))
+
+	// the commands of command jobs, with the runtime properties substituted
+	overrides := map[string]string{}
+	for k, v := range e.params {
+		overrides[k] = v
+	}
+	overrides["azkaban.flow.execid"] = strconv.FormatInt(e.id, 10)
+	overrides["azkaban.flow.flowid"] = e.flow
+	overrides["azkaban.flow.projectname"] = e.project.name
+
+	props, err := e.project.local.ResolveJob(r.id, overrides)
+	if err != nil {
+		line(start, "Failed to resolve properties: %v", err)
+		props = map[string]string{}
+	}
+
+	for k, v := range e.project.overrides[r.id] {
+		props[k] = v
+	}
+
+	for _, key := range commandKeys(props) {
+		line(start, "Command: %s", props[key])
+	}
+
+	if !end.IsZero() {
+		line(end, "Finishing job %s at %d with status %s", r.nested, millis(end), status)
+	}
+
+	return log.String()
+
+}
+
+// command, command.1, command.2... in order
+func commandKeys(props map[string]string) []string {
+
+	var keys []string
+	if _, ok := props["command"]; ok {
+		keys = append(keys, "command")
+	}
+
+	for i := 1; ; i++ {
+		key := fmt.Sprintf("command.%d", i)
+		if _, ok := props[key]; !ok {
+			return keys
+		}
+		keys = append(keys, key)
+	}
+
+}
+
+func (s *Server) fetchExecJobLogs(w http.ResponseWriter, r *http.Request, e *execution) {
+
+	job := findRun(e.nodes, r.FormValue("jobId"))
+	if job == nil {
+		writeError(w, "Job %s doesn't exist in %d", r.FormValue("jobId"), e.id)
+		return
+	}
+
+	log := s.jobLog(e, job)
+	from, to := page(len(log), formInt(r, "offset", 0), formInt(r, "length", len(log)))
+
+	writeJSON(w, map[string]interface{}{"data": log[from:to], "offset": from, "length": to - from})
+
+}
+
+// by execid, or every running execution of a project flow as the client's CancelFlow asks
+func (s *Server) cancelFlow(w http.ResponseWriter, r *http.Request) {
+
+	var executions []*execution
+
+	if r.FormValue("execid") != "" {
+		e := s.execution(w, r)
+		if e == nil {
+			return
+		}
+		if !s.running(e) {
+			writeError(w, "Execution %d of flow %s isn't running.", e.id, e.flow)
+			return
+		}
+		executions = append(executions, e)
+	} else if p, ok := s.projects[r.FormValue("project")]; ok {
+		for _, e := range s.flowExecutions(p, r.FormValue("flow")) {
+			if s.running(e) {
+				executions = append(executions, e)
+			}
+		}
+	}
+
+	if len(executions) == 0 {
+		writeError(w, "Flow %s isn't running.", r.FormValue("flow"))
+		return
+	}
+
+	for _, e := range executions {
+		e.killed = s.now()
+	}
+
+	writeJSON(w, map[string]string{})
+
+}
+
+func (s *Server) flowInfo(w http.ResponseWriter, r *http.Request) {
+
+	// options of an execution
+	if r.FormValue("execid") != "" {
+		if e := s.execution(w, r); e != nil {
+			writeJSON(w, map[string]interface{}{
+				"flowParam":          e.params,
+				"successEmails":      e.emails["success.emails"],
+				"failureEmails":      e.emails["failure.emails"],
+				"notifyFailureFirst": false,
+				"notifyFailureLast":  false,
+				"failureAction":      "finishCurrent",
+				"concurrentOptions":  e.option,
+				"pipelineLevel":      0,
+			})
+		}
+		return
+	}
+
+	p, ok := s.projects[r.FormValue("project")]
+	if !ok || !p.hasFlow(r.FormValue("flow")) {
+		writeError(w, "Flow %s not found.", r.FormValue("flow"))
+		return
+	}
+
+	emails := s.emails(p, r.FormValue("flow"))
+
+	writeJSON(w, map[string]interface{}{
+		"successEmails": emails["success.emails"],
+		"failureEmails": emails["failure.emails"],
+	})
+
+}
+
+// notification emails set on the job of a flow
+func (s *Server) emails(p *project, flow string) map[string][]string {
+
+	emails := map[string][]string{}
+
+	props, _ := p.local.ResolveJob(flow, nil)
+
+	for _, key := range []string{"success.emails", "failure.emails"} {
+		emails[key] = splitEmails(props[key])
+	}
+
+	return emails
+
+}
+
+// split a comma separated list of emails
+func splitEmails(list string) []string {
+	emails := []string{}
+	for _, email := range strings.Split(list, ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
@@ -0,0 +1,142 @@
+package azkabantest
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	"github.com/wanglun/azkaban"
+)
+
+// an executor server of the cluster, active is its flag in the executors table, loaded is the
+// flag the web server read at the last reload
+type executor struct {
+	id     int
+	server *httptest.Server
+	active bool
+	loaded bool
+}
+
+func (ex *executor) value() azkaban.Executor {
+	host, port, _ := net.SplitHostPort(ex.server.Listener.Addr().String())
+	n, _ := strconv.Atoi(port)
+	return azkaban.Executor{ID: ex.id, Host: host, Port: n, Active: ex.loaded}
+}
+
+// AddExecutor starts an active executor server, executions are dispatched to the first active
+// executor unless they use another one.
+func (s *Server) AddExecutor() azkaban.Executor {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ex := &executor{id: s.nextID(), active: true, loaded: true}
+	ex.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.handleExecutorServer(w, r, ex)
+	}))
+	s.executors = append(s.executors, ex)
+
+	return ex.value()
+
+}
+
+// Close shuts down the executor servers, then the web server.
+func (s *Server) Close() {
+
+	for _, ex := range s.executors {
+		ex.server.Close()
+	}
+
+	s.Server.Close()
+
+}
+
+// the executor of a flow execution, the first active one unless the useExecutor parameter
+// says otherwise
+func (s *Server) dispatch(params map[string]string) (int, bool) {
+
+	if id, ok := params["useExecutor"]; ok {
+		for _, ex := range s.executors {
+			if strconv.Itoa(ex.id) == id {
+				return ex.id, true
+			}
+		}
+		return 0, false
+	}
+
+	for _, ex := range s.executors {
+		if ex.loaded {
+			return ex.id, true
+		}
+	}
+
+	return 0, true
+
+}
+
+func (s *Server) fetchExecutors(w http.ResponseWriter) {
+
+	executors := []azkaban.Executor{}
+	for _, ex := range s.executors {
+		executors = append(executors, ex.value())
+	}
+
+	writeJSON(w, map[string]interface{}{"executors": executors})
+
+}
+
+func (s *Server) reloadExecutors(w http.ResponseWriter) {
+
+	for _, ex := range s.executors {
+		ex.loaded = ex.active
+	}
+
+	writeJSON(w, map[string]string{"success": "Successfully reloaded executors"})
+
+}
+
+// the calls the web server and the admins make to an executor server itself
+func (s *Server) handleExecutorServer(w http.ResponseWriter, r *http.Request, ex *executor) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.URL.Path {
+
+	case "/serverStatistics":
+
+		assigned := 0
+		for _, e := range s.executions {
+			if e.executor == ex.id && s.running(e) {
+				assigned++
+			}
+		}
+
+		writeJSON(w, map[string]interface{}{
+			"remainingMemoryPercent": 50.0,
+			"remainingMemoryInMB":    4096,
+			"remainingFlowCapacity":  30 - assigned,
+			"numberOfAssignedFlows":  assigned,
+			"lastDispatchedTime":     -1,
+			"cpuUsage":               0.1,
+		})
+
+	case "/executor":
+
+		switch action := r.FormValue("action"); action {
+		case "activate", "deactivate":
+			ex.active = action == "activate"
+			writeJSON(w, map[string]string{"status": "success"})
+		case "getStatus":
+			writeJSON(w, map[string]string{"isActive": strconv.FormatBool(ex.active)})
+		default:
+			writeError(w, "Unsupported action type %s", action)
+		}
+
+	default:
+		http.NotFound(w, r)
+
+	}
+
+}
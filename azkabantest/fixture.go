@@ -0,0 +1,337 @@
+package azkabantest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Redacted replaces passwords and session ids in fixtures.
+const Redacted = "REDACTED"
+
+// form fields and response keys holding secrets or user names
+var secrets = []string{"username", "password", "session.id"}
+
+// Fixture is a list of requests to azkaban and their responses, stored as a json golden file.
+type Fixture struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a request and its response.
+type Interaction struct {
+	Request  FixtureRequest  `json:"request"`
+	Response FixtureResponse `json:"response"`
+}
+
+// FixtureRequest is a request, reduced to what identifies it. The form gathers the query string,
+// url encoded and multipart fields, the content of uploaded files is not kept.
+type FixtureRequest struct {
+	Method string     `json:"method"`
+	Path   string     `json:"path"`
+	Form   url.Values `json:"form,omitempty"`
+	File   string     `json:"file,omitempty"`
+}
+
+// FixtureResponse is a response, its body is kept as json, text or base64 for archives.
+type FixtureResponse struct {
+	Status      int             `json:"status"`
+	ContentType string          `json:"contentType,omitempty"`
+	JSON        json.RawMessage `json:"json,omitempty"`
+	Text        string          `json:"text,omitempty"`
+	Binary      []byte          `json:"binary,omitempty"`
+}
+
+// LoadFixture reads a fixture file.
+func LoadFixture(name string) (*Fixture, error) {
+
+	body, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture Fixture
+	if err = json.Unmarshal(body, &fixture); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	return &fixture, nil
+
+}
+
+// Save writes the fixture file, creating its directory.
+func (f *Fixture) Save(name string) error {
+
+	body, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(name, append(body, '\n'), 0644)
+
+}
+
+// read the request body and replace it, so it can still be sent
+func readRequest(req *http.Request) (FixtureRequest, error) {
+
+	recorded := FixtureRequest{Method: req.Method, Path: req.URL.Path, Form: url.Values{}}
+
+	for key, values := range req.URL.Query() {
+		recorded.Form[key] = values
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return recorded, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+
+	switch mediaType {
+
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return recorded, err
+		}
+		for key, value := range values {
+			recorded.Form[key] = value
+		}
+
+	case "multipart/form-data":
+		reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return recorded, err
+			}
+			if part.FileName() != "" {
+				recorded.File = part.FileName()
+				continue
+			}
+			value, err := ioutil.ReadAll(part)
+			if err != nil {
+				return recorded, err
+			}
+			recorded.Form.Add(part.FormName(), string(value))
+		}
+
+	}
+
+	for _, key := range secrets {
+		if _, ok := recorded.Form[key]; ok {
+			recorded.Form.Set(key, Redacted)
+		}
+	}
+
+	// as loaded from a fixture file
+	if len(recorded.Form) == 0 {
+		recorded.Form = nil
+	}
+
+	return recorded, nil
+
+}
+
+// Recorder is an http.RoundTripper recording the requests it sends through Transport and their responses.
+//
+//	recorder := azkabantest.NewRecorder(nil)
+//	client.HTTPClient = &http.Client{Transport: recorder}
+//	client.FetchFlows("project")
+//	err := recorder.Save("testdata/fixtures/synthetic/FetchFlows.json")
+type Recorder struct {
+	Transport http.RoundTripper
+
+	mu      sync.Mutex
+	fixture Fixture
+}
+
+// NewRecorder returns a recorder sending the requests through transport, http.DefaultTransport when nil.
+func NewRecorder(transport http.RoundTripper) *Recorder {
+
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &Recorder{Transport: transport}
+
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	request, err := readRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	response := FixtureResponse{Status: res.StatusCode, ContentType: res.Header.Get("Content-Type")}
+
+	// numbers are kept as they are, timestamps would lose precision as float64
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value interface{}
+	switch {
+	case len(bytes.TrimSpace(body)) > 0 && decoder.Decode(&value) == nil && !decoder.More():
+		if object, ok := value.(map[string]interface{}); ok {
+			for _, key := range secrets {
+				if _, ok := object[key]; ok {
+					object[key] = Redacted
+				}
+			}
+		}
+		response.JSON, _ = json.Marshal(value)
+	case utf8.Valid(body):
+		response.Text = string(body)
+	default:
+		response.Binary = body
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fixture.Interactions = append(r.fixture.Interactions, Interaction{Request: request, Response: response})
+
+	return res, nil
+
+}
+
+// Save writes the interactions recorded so far into a fixture file and starts a new fixture.
+func (r *Recorder) Save(name string) error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.fixture.Save(name); err != nil {
+		return err
+	}
+
+	r.fixture = Fixture{}
+
+	return nil
+
+}
+
+// Replayer is an http.RoundTripper answering requests with the responses of a fixture. A request
+// gets the response of the first unused interaction with the same method, path, form and file,
+// or of the last one when they were all used, so the same request can get different responses.
+type Replayer struct {
+	mu      sync.Mutex
+	fixture *Fixture
+	used    []bool
+}
+
+// NewReplayer returns a replayer of a fixture.
+func NewReplayer(fixture *Fixture) *Replayer {
+	return &Replayer{fixture: fixture, used: make([]bool, len(fixture.Interactions))}
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	request, err := readRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	found := -1
+	for i, interaction := range r.fixture.Interactions {
+		if reflect.DeepEqual(interaction.Request, request) {
+			found = i
+			if !r.used[i] {
+				break
+			}
+		}
+	}
+
+	if found < 0 {
+		return nil, fmt.Errorf("azkabantest: no recorded response for %s %s %s", request.Method, request.Path, request.Form.Encode())
+	}
+
+	r.used[found] = true
+
+	response := r.fixture.Interactions[found].Response
+
+	body := response.Binary
+	if len(response.JSON) > 0 {
+		body = response.JSON
+	} else if response.Text != "" {
+		body = []byte(response.Text)
+	}
+
+	header := http.Header{}
+	if response.ContentType != "" {
+		header.Set("Content-Type", response.ContentType)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.Status, http.StatusText(response.Status)),
+		StatusCode:    response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+
+}
+
+// Unused returns the requests of the fixture that were never replayed.
+func (r *Replayer) Unused() []FixtureRequest {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unused []FixtureRequest
+	for i, interaction := range r.fixture.Interactions {
+		if !r.used[i] {
+			unused = append(unused, interaction.Request)
+		}
+	}
+
+	return unused
+
+}
+
+// FixturePath returns the path of the fixture of a test case in a corpus, below dir.
+func FixturePath(dir, corpus, name string) string {
+	return filepath.Join(dir, corpus, strings.NewReplacer("/", "_", " ", "_").Replace(name)+".json")
+}
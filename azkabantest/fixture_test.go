@@ -0,0 +1,59 @@
+package azkabantest
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wanglun/azkaban"
+)
+
+func TestRecordReplay(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	recorder := NewRecorder(nil)
+	client := azkaban.New(server.URL)
+	client.HTTPClient = &http.Client{Transport: recorder}
+
+	assert.Nil(t, client.Authenticate(DefaultUser, DefaultPassword))
+	_, err := client.GetProject("project")
+	assert.Equal(t, azkaban.ProjectNotFound, err)
+	_, err = client.CreateProject("project", "")
+	assert.Nil(t, err)
+	_, err = client.GetProject("project")
+	assert.Nil(t, err)
+
+	name := filepath.Join(t.TempDir(), "fixture.json")
+	assert.Nil(t, recorder.Save(name))
+
+	fixture, err := LoadFixture(name)
+	assert.Nil(t, err)
+	assert.Len(t, fixture.Interactions, 4)
+
+	// secrets are redacted
+	login := fixture.Interactions[0]
+	assert.Equal(t, Redacted, login.Request.Form.Get("username"))
+	assert.Equal(t, Redacted, login.Request.Form.Get("password"))
+	assert.JSONEq(t, `{"session.id": "REDACTED", "status": "success"}`, string(login.Response.JSON))
+
+	// the same request gets the responses in the order they were recorded
+	replayer := NewReplayer(fixture)
+	client = azkaban.New("http://azkaban.test")
+	client.HTTPClient = &http.Client{Transport: replayer}
+
+	assert.Nil(t, client.Authenticate("someone", "secret"))
+	_, err = client.GetProject("project")
+	assert.Equal(t, azkaban.ProjectNotFound, err)
+	_, err = client.CreateProject("project", "")
+	assert.Nil(t, err)
+	_, err = client.GetProject("project")
+	assert.Nil(t, err)
+	_, err = client.GetProject("project")
+	assert.Nil(t, err)
+	assert.Empty(t, replayer.Unused())
+
+	_, err = client.FetchProjects()
+	assert.Error(t, err)
+}
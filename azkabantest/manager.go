@@ -0,0 +1,467 @@
+package azkabantest
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wanglun/azkaban"
+)
+
+// a project and its uploaded versions
+type project struct {
+	id          int
+	name        string
+	description string
+	createdBy   string
+	createdAt   time.Time
+
+	// archives by version, starting at 1
+	archives [][]byte
+	local    *azkaban.LocalProject
+
+	// overrides set with setJobOverrideProperty, by job
+	overrides map[string]map[string]string
+
+	// permission types of the users and of the groups, by name
+	users, groups map[string][]string
+	proxyUsers    map[string]bool
+
+	events []azkaban.ProjectEvent
+}
+
+func (p *project) version() int {
+	return len(p.archives)
+}
+
+func (p *project) log(at time.Time, user, kind, format string, args ...interface{}) {
+	p.events = append(p.events, azkaban.ProjectEvent{Time: at, User: user, Type: kind, Message: fmt.Sprintf(format, args...)})
+}
+
+func (p *project) hasFlow(flow string) bool {
+	if p.local == nil {
+		return false
+	}
+	for _, name := range p.local.Flows() {
+		if name == flow {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) sortedProjects() []*project {
+
+	var projects []*project
+	for _, p := range s.projects {
+		projects = append(projects, p)
+	}
+
+	sort.Slice(projects, func(i, j int) bool { return projects[i].id < projects[j].id })
+
+	return projects
+
+}
+
+func (s *Server) handleManager(w http.ResponseWriter, r *http.Request, user string) {
+
+	switch {
+	case r.FormValue("action") == "create":
+		s.createProject(w, r, user)
+	case r.FormValue("delete") == "true":
+		delete(s.projects, r.FormValue("project"))
+		writePage(w)
+	case r.FormValue("download") == "true":
+		s.downloadProject(w, r)
+	default:
+		s.handleManagerAjax(w, r, user)
+	}
+
+}
+
+func (s *Server) handleManagerAjax(w http.ResponseWriter, r *http.Request, user string) {
+
+	ajax := r.FormValue("ajax")
+
+	p, ok := s.projects[r.FormValue("project")]
+
+	// azkaban answers with an empty body for unknown projects
+	if !ok {
+		switch ajax {
+		case "upload":
+			writeError(w, "Installation Failed. Project '%s' doesn't exist.", r.FormValue("project"))
+		case "":
+			writePage(w)
+		}
+		return
+	}
+
+	switch ajax {
+
+	case "upload":
+		s.uploadProject(w, r, user, p)
+
+	case "fetchprojectflows":
+		flows := []map[string]string{}
+		if p.local != nil {
+			for _, flow := range p.local.Flows() {
+				flows = append(flows, map[string]string{"flowId": flow})
+			}
+		}
+		writeJSON(w, map[string]interface{}{"project": p.name, "projectId": p.id, "flows": flows})
+
+	case "fetchflowgraph":
+		flow := r.FormValue("flow")
+		if !p.hasFlow(flow) {
+			writeError(w, "Flow %s not found.", flow)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"project": p.name, "projectId": p.id, "flow": flow, "nodes": p.local.FlowGraph(flow).Nodes})
+
+	case "fetchFlowExecutions":
+		s.fetchFlowExecutions(w, r, p)
+
+	case "fetchProjectLogEvents":
+		fetchProjectLogEvents(w, r, p)
+
+	case "fetchJobInfo":
+		var job *azkaban.LocalJob
+		if p.local != nil {
+			job = p.local.Job(r.FormValue("jobName"))
+		}
+		if job == nil {
+			writeError(w, "Job %s not found.", r.FormValue("jobName"))
+			return
+		}
+		props := map[string]string{}
+		for _, prop := range job.Props {
+			props[prop.Key] = prop.Value
+		}
+		writeJSON(w, map[string]interface{}{
+			"jobName":        job.Name,
+			"jobType":        job.Type(),
+			"generalParams":  props,
+			"overrideParams": p.overrides[job.Name],
+		})
+
+	case "setJobOverrideProperty":
+		job := r.FormValue("jobName")
+		p.overrides[job] = formMap(r, "jobOverride")
+		p.log(s.now(), user, azkaban.EventType.PropertyOverride, "Modified Properties: %s", job)
+
+	case "getPermissions", "getGroupPermissions":
+		permissions := p.users
+		if ajax == "getGroupPermissions" {
+			permissions = p.groups
+		}
+		list := []map[string]interface{}{}
+		for _, name := range sortedKeys(permissions) {
+			list = append(list, map[string]interface{}{"username": name, "permission": permissions[name]})
+		}
+		writeJSON(w, map[string]interface{}{"project": p.name, "permissions": list})
+
+	case "addPermission", "changePermission":
+		s.setPermission(w, r, user, p)
+
+	case "getProxyUsers":
+		users := []string{}
+		for name := range p.proxyUsers {
+			users = append(users, name)
+		}
+		sort.Strings(users)
+		writeJSON(w, map[string]interface{}{"proxyUsers": users})
+
+	case "addProxyUser":
+		name := r.FormValue("name")
+		if p.proxyUsers[name] {
+			writeError(w, "User %s is already a proxy user.", name)
+			return
+		}
+		p.proxyUsers[name] = true
+		p.log(s.now(), user, azkaban.EventType.ProxyUser, "Proxy user %s is added to project.", name)
+		writeJSON(w, map[string]string{})
+
+	case "removeProxyUser":
+		name := r.FormValue("name")
+		delete(p.proxyUsers, name)
+		p.log(s.now(), user, azkaban.EventType.ProxyUser, "Proxy user %s has been removed form the project.", name)
+		writeJSON(w, map[string]string{})
+
+	default:
+		writeError(w, "Unknown ajax call %s.", ajax)
+
+	}
+
+}
+
+// grant the permissions of the form to a user or a group, which lose them all when none is set
+func (s *Server) setPermission(w http.ResponseWriter, r *http.Request, user string, p *project) {
+
+	name, group := r.FormValue("name"), r.FormValue("group") == "true"
+
+	permissions, kind, event := p.users, "user", azkaban.EventType.UserPermission
+	if group {
+		permissions, kind, event = p.groups, "group", azkaban.EventType.GroupPermission
+	}
+
+	_, exists := permissions[name]
+	if r.FormValue("ajax") == "addPermission" && exists {
+		writeError(w, "%s %s already has permissions.", kind, name)
+		return
+	}
+	if r.FormValue("ajax") == "changePermission" && !exists {
+		writeError(w, "%s %s has no permissions.", kind, name)
+		return
+	}
+
+	var granted []string
+	for _, t := range []string{azkaban.PermissionType.Admin, azkaban.PermissionType.Execute, azkaban.PermissionType.Read, azkaban.PermissionType.Schedule, azkaban.PermissionType.Write} {
+		if r.FormValue("permissions["+strings.ToLower(t)+"]") == "true" {
+			granted = append(granted, t)
+		}
+	}
+
+	if len(granted) == 0 {
+		delete(permissions, name)
+	} else {
+		permissions[name] = granted
+	}
+
+	p.log(s.now(), user, event, "Permission for %s %s set to %s", kind, name, strings.Join(granted, ","))
+
+	writeJSON(w, map[string]string{})
+
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// values of the form fields named prefix[key], by key
+func formMap(r *http.Request, prefix string) map[string]string {
+	values := map[string]string{}
+	for key := range r.Form {
+		if strings.HasPrefix(key, prefix+"[") && strings.HasSuffix(key, "]") {
+			values[key[len(prefix)+1:len(key)-1]] = r.Form.Get(key)
+		}
+	}
+	return values
+}
+
+func (s *Server) createProject(w http.ResponseWriter, r *http.Request, user string) {
+
+	name := r.FormValue("name")
+
+	if name == "" {
+		writeJSON(w, map[string]string{"status": "error", "message": "Project name cannot be empty."})
+		return
+	}
+
+	if _, ok := s.projects[name]; ok {
+		writeJSON(w, map[string]string{"status": "error", "message": "Project already exists."})
+		return
+	}
+
+	p := &project{
+		id:          s.nextID(),
+		name:        name,
+		description: r.FormValue("description"),
+		createdBy:   user,
+		createdAt:   s.now(),
+		overrides:   map[string]map[string]string{},
+		users:       map[string][]string{user: {azkaban.PermissionType.Admin}},
+		groups:      map[string][]string{},
+		proxyUsers:  map[string]bool{},
+	}
+	p.log(s.now(), user, azkaban.EventType.Created, "Created project %s", name)
+	s.projects[name] = p
+
+	writeJSON(w, map[string]string{"status": "success", "path": "manager?project=" + name, "action": "redirect"})
+
+}
+
+func (s *Server) uploadProject(w http.ResponseWriter, r *http.Request, user string, p *project) {
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, "Installation Failed. No file uploaded.")
+		return
+	}
+	defer file.Close()
+
+	archive, err := ioutil.ReadAll(file)
+	if err != nil {
+		writeError(w, "Installation Failed. %v", err)
+		return
+	}
+
+	local, err := readArchive(archive)
+	if err != nil {
+		writeError(w, "Installation Failed.\n%v", err)
+		return
+	}
+
+	p.archives = append(p.archives, archive)
+	p.local = local
+	p.log(s.now(), user, azkaban.EventType.Uploaded, "Uploaded project files zip %s", header.Filename)
+
+	writeJSON(w, map[string]interface{}{"projectId": p.id, "version": strconv.Itoa(p.version())})
+
+}
+
+// read and validate an uploaded archive, Flow 2.0 projects are converted to the legacy format
+func readArchive(archive []byte) (*azkaban.LocalProject, error) {
+
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		files []azkaban.File
+		flow2 bool
+	)
+
+	for _, entry := range reader.File {
+
+		if entry.FileInfo().IsDir() || strings.HasPrefix(entry.Name, "__MACOSX/") {
+			continue
+		}
+
+		rc, err := entry.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		flow2 = flow2 || path.Ext(entry.Name) == ".project"
+		files = append(files, azkaban.File{Name: entry.Name, Body: body})
+
+	}
+
+	if flow2 {
+
+		converted, err := azkaban.ReadFlow2Project(files)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = converted.Validate(); err != nil {
+			return nil, err
+		}
+
+		if files, err = azkaban.ConvertFromFlow2(converted); err != nil {
+			return nil, err
+		}
+
+	}
+
+	local, err := azkaban.ReadProject(files)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(local.Jobs) == 0 {
+		return nil, fmt.Errorf("No flows found in the project.")
+	}
+
+	// only the problems azkaban refuses, unknown types and parameters fail at execution time
+	var problems []string
+	for _, finding := range local.Validate() {
+		switch finding.Type {
+		case azkaban.FindingType.MissingDependency, azkaban.FindingType.Cycle, azkaban.FindingType.DuplicateJob:
+			problems = append(problems, finding.String())
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+
+	return local, nil
+
+}
+
+func (s *Server) downloadProject(w http.ResponseWriter, r *http.Request) {
+
+	p, ok := s.projects[r.FormValue("project")]
+	if !ok || p.version() == 0 {
+		writePage(w)
+		return
+	}
+
+	version := p.version()
+	if v := r.FormValue("version"); v != "" {
+		version, _ = strconv.Atoi(v)
+	}
+
+	if version < 1 || version > p.version() {
+		writePage(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s-%d.zip", p.name, version))
+	w.Write(p.archives[version-1])
+
+}
+
+// form value as an int, or a default value
+func formInt(r *http.Request, key string, value int) int {
+	if n, err := strconv.Atoi(r.FormValue(key)); err == nil {
+		return n
+	}
+	return value
+}
+
+// slice bounds of a page
+func page(total, offset, length int) (int, int) {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > total {
+		offset = total
+	}
+	end := offset + length
+	if length < 0 || end > total {
+		end = total
+	}
+	return offset, end
+}
+
+func fetchProjectLogEvents(w http.ResponseWriter, r *http.Request, p *project) {
+
+	// most recent first
+	var rows [][]interface{}
+	for i := len(p.events) - 1; i >= 0; i-- {
+		event := p.events[i]
+		rows = append(rows, []interface{}{event.User, millis(event.Time), event.Type, event.Message})
+	}
+
+	from, to := page(len(rows), formInt(r, "skip", 0), formInt(r, "size", len(rows)))
+
+	writeJSON(w, map[string]interface{}{
+		"project":   p.name,
+		"projectId": p.id,
+		"columns":   []string{"user", "time", "type", "message"},
+		"logData":   append([][]interface{}{}, rows[from:to]...),
+	})
+
+}
@@ -0,0 +1,281 @@
+package azkabantest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wanglun/azkaban"
+)
+
+// a schedule of a flow, either a cron expression or a first time and a period
+type schedule struct {
+	id      int
+	project *project
+	flow    string
+	user    string
+	cron    string
+	first   time.Time
+	period  string
+
+	// the SLA set with setSla
+	slaEmails   []string
+	slaSettings []string
+}
+
+func (s *Server) handleSchedule(w http.ResponseWriter, r *http.Request, user string) {
+
+	if r.FormValue("action") == "removeSched" {
+		s.removeSchedule(w, r)
+		return
+	}
+
+	switch ajax := r.FormValue("ajax"); ajax {
+	case "scheduleFlow", "scheduleCronFlow":
+		s.scheduleFlow(w, r, user)
+	case "fetchSchedule":
+		s.fetchSchedule(w, r)
+	case "slaInfo":
+		s.slaInfo(w, r)
+	case "setSla":
+		s.setSla(w, r, user)
+	default:
+		writeError(w, "Unknown ajax call %s.", ajax)
+	}
+
+}
+
+func (s *Server) scheduleFlow(w http.ResponseWriter, r *http.Request, user string) {
+
+	name, flow := r.FormValue("projectName"), r.FormValue("flow")
+
+	p, ok := s.projects[name]
+	if !ok {
+		writeJSON(w, map[string]string{"status": "error", "message": fmt.Sprintf("Project %s does not exist", name)})
+		return
+	}
+
+	if !p.hasFlow(flow) {
+		writeJSON(w, map[string]string{"status": "error", "message": fmt.Sprintf("Flow %s cannot be found in project %s", flow, name)})
+		return
+	}
+
+	sched := &schedule{project: p, flow: flow, user: user}
+
+	if r.FormValue("ajax") == "scheduleCronFlow" {
+
+		sched.cron = r.FormValue("cronExpression")
+		if len(strings.Fields(sched.cron)) < 6 {
+			writeJSON(w, map[string]string{"status": "error", "message": fmt.Sprintf("This expression <%s> can not be parsed to quartz cron.", sched.cron)})
+			return
+		}
+
+	} else {
+
+		first, err := parseScheduleTime(r.FormValue("scheduleDate"), r.FormValue("scheduleTime"))
+		if err != nil {
+			writeJSON(w, map[string]string{"status": "error", "message": err.Error()})
+			return
+		}
+
+		sched.first = first
+		if r.FormValue("is_recurring") == "on" {
+			sched.period = r.FormValue("period")
+		}
+
+	}
+
+	// a flow has a single schedule, azkaban replaces it
+	for id, other := range s.schedules {
+		if other.project == p && other.flow == flow {
+			delete(s.schedules, id)
+		}
+	}
+
+	sched.id = s.nextID()
+	s.schedules[sched.id] = sched
+
+	writeJSON(w, map[string]interface{}{
+		"status":     "success",
+		"message":    fmt.Sprintf("%s.%s scheduled.", name, flow),
+		"scheduleId": sched.id,
+	})
+
+}
+
+// parse the MM/DD/YYYY date and the hh,mm,pm,ZZZ time sent by ScheduleFlow, the time zone is ignored.
+// The hour may be on 12 or 24 hours, as azkaban does 12 hours are added to PM hours before noon.
+func parseScheduleTime(date, clock string) (time.Time, error) {
+
+	fields := strings.Split(clock, ",")
+	if len(fields) < 3 {
+		return time.Time{}, fmt.Errorf("invalid schedule time %q", clock)
+	}
+
+	day, err := time.Parse("01/02/2006", date)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	hour, err := strconv.Atoi(fields[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return time.Time{}, fmt.Errorf("invalid schedule hour %q", fields[0])
+	}
+	minute, err := strconv.Atoi(fields[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return time.Time{}, fmt.Errorf("invalid schedule minute %q", fields[1])
+	}
+
+	if strings.EqualFold(fields[2], "pm") && hour < 12 {
+		hour += 12
+	}
+
+	return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute), nil
+
+}
+
+func (s *Server) fetchSchedule(w http.ResponseWriter, r *http.Request) {
+
+	id, _ := strconv.Atoi(r.FormValue("projectId"))
+
+	for _, sched := range s.schedules {
+
+		if sched.project.id != id || sched.flow != r.FormValue("flowId") {
+			continue
+		}
+
+		writeJSON(w, map[string]interface{}{"schedule": map[string]interface{}{
+			"scheduleId":     strconv.Itoa(sched.id),
+			"submitUser":     sched.user,
+			"cronExpression": sched.cron,
+			"period":         sched.period,
+			"firstSchedTime": sched.first.Format("2006-01-02 15:04:05"),
+			"executionOptions": map[string]interface{}{
+				"flowParameters": map[string]string{},
+			},
+		}})
+
+		return
+
+	}
+
+	writeJSON(w, map[string]string{})
+
+}
+
+func (s *Server) removeSchedule(w http.ResponseWriter, r *http.Request) {
+
+	id, _ := strconv.Atoi(r.FormValue("scheduleId"))
+
+	sched, ok := s.schedules[id]
+	if !ok {
+		writeJSON(w, map[string]string{"status": "error", "message": fmt.Sprintf("Schedule with ID %s does not exist", r.FormValue("scheduleId"))})
+		return
+	}
+
+	delete(s.schedules, id)
+
+	writeJSON(w, map[string]string{"status": "success", "message": fmt.Sprintf("flow %s removed from Schedules.", sched.flow)})
+
+}
+
+func (s *Server) scheduleOf(w http.ResponseWriter, r *http.Request) *schedule {
+
+	id, _ := strconv.Atoi(r.FormValue("scheduleId"))
+
+	sched, ok := s.schedules[id]
+	if !ok {
+		writeError(w, "Schedule with ID %s does not exist", r.FormValue("scheduleId"))
+	}
+
+	return sched
+
+}
+
+// settings are answered as azkaban does, durations as period strings and actions as a list
+func (s *Server) slaInfo(w http.ResponseWriter, r *http.Request) {
+
+	sched := s.scheduleOf(w, r)
+	if sched == nil {
+		return
+	}
+
+	settings := []map[string]interface{}{}
+
+	for _, setting := range sched.slaSettings {
+
+		// job,rule,hh:mm,email,kill
+		parts := strings.Split(setting, ",")
+		clock := strings.Split(parts[2], ":")
+		hours, _ := strconv.Atoi(clock[0])
+		minutes, _ := strconv.Atoi(clock[1])
+
+		actions := []string{}
+		if parts[3] == "true" {
+			actions = append(actions, "EMAIL")
+		}
+		if parts[4] == "true" {
+			actions = append(actions, "KILL")
+		}
+
+		settings = append(settings, map[string]interface{}{
+			"id":       parts[0],
+			"rule":     parts[1],
+			"duration": fmt.Sprintf("%dm", hours*60+minutes),
+			"actions":  actions,
+		})
+
+	}
+
+	jobs := []string{}
+	if sched.project.local != nil {
+		for _, node := range sched.project.local.FlowGraph(sched.flow).Nodes {
+			jobs = append(jobs, node.ID)
+		}
+	}
+
+	writeJSON(w, map[string]interface{}{"slaEmails": sched.slaEmails, "allJobNames": jobs, "settings": settings})
+
+}
+
+func (s *Server) setSla(w http.ResponseWriter, r *http.Request, user string) {
+
+	sched := s.scheduleOf(w, r)
+	if sched == nil {
+		return
+	}
+
+	settings := formMap(r, "settings")
+
+	// settings[0], settings[1]...
+	var keys []string
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, _ := strconv.Atoi(keys[i])
+		b, _ := strconv.Atoi(keys[j])
+		return a < b
+	})
+
+	var valid []string
+	for _, key := range keys {
+		parts := strings.Split(settings[key], ",")
+		if len(parts) != 5 || len(strings.Split(parts[2], ":")) != 2 {
+			writeError(w, "Invalid SLA setting %s", settings[key])
+			return
+		}
+		valid = append(valid, settings[key])
+	}
+
+	sched.slaEmails = splitEmails(r.FormValue("slaEmails"))
+	sched.slaSettings = valid
+
+	sched.project.log(s.now(), user, azkaban.EventType.SLA, "SLA for flow %s has been added/changed.", sched.flow)
+
+	writeJSON(w, map[string]string{})
+
+}
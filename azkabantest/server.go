@@ -0,0 +1,228 @@
+// Package azkabantest provides an in-process fake Azkaban server, so code using the azkaban
+// package can be tested offline.
+//
+//	server := azkabantest.NewServer()
+//	defer server.Close()
+//
+//	client, err := server.Client()
+//
+// The server keeps projects, executions and schedules in memory. Executions are simulated:
+// jobs run level by level, each taking JobDuration, and succeed unless marked with FailJob.
+// Executor servers started with AddExecutor make it a multi-executor cluster.
+package azkabantest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/wanglun/azkaban"
+)
+
+// DefaultUser and DefaultPassword are the credentials every new server accepts.
+const (
+	DefaultUser     = "azkaban"
+	DefaultPassword = "azkaban"
+)
+
+// Server is a fake azkaban web server.
+type Server struct {
+	*httptest.Server
+
+	// JobDuration is how long every simulated job runs, jobs finish as soon as they start when 0.
+	JobDuration time.Duration
+
+	// Now is the clock of the server, time.Now when nil.
+	Now func() time.Time
+
+	mu         sync.Mutex
+	users      map[string]string
+	sessions   map[string]string
+	projects   map[string]*project
+	executions map[int64]*execution
+	schedules  map[int]*schedule
+	executors  []*executor
+	failing    map[[2]string]bool
+	lastID     int
+}
+
+// NewServer starts a fake server accepting DefaultUser and DefaultPassword.
+func NewServer() *Server {
+
+	s := &Server{
+		users:      map[string]string{DefaultUser: DefaultPassword},
+		sessions:   map[string]string{},
+		projects:   map[string]*project{},
+		executions: map[int64]*execution{},
+		schedules:  map[int]*schedule{},
+		failing:    map[[2]string]bool{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleLogin)
+	mux.HandleFunc("/index", s.authenticated(s.handleIndex))
+	mux.HandleFunc("/manager", s.authenticated(s.handleManager))
+	mux.HandleFunc("/executor", s.authenticated(s.handleExecutor))
+	mux.HandleFunc("/schedule", s.authenticated(s.handleSchedule))
+
+	s.Server = httptest.NewServer(mux)
+
+	return s
+
+}
+
+// AddUser adds a user the server accepts.
+func (s *Server) AddUser(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[username] = password
+}
+
+// FailJob makes a job of a project fail in every following execution.
+func (s *Server) FailJob(project, job string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing[[2]string{project, job}] = true
+}
+
+// ExpireSessions forgets every session, as a restarted server does.
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = map[string]string{}
+}
+
+// Client returns a client of the server, authenticated as DefaultUser.
+func (s *Server) Client() (*azkaban.Client, error) {
+
+	client := azkaban.New(s.URL)
+
+	if err := client.Authenticate(DefaultUser, DefaultPassword); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+
+}
+
+func (s *Server) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// milliseconds since the epoch, as azkaban sends times
+func millis(t time.Time) int64 {
+	if t.IsZero() {
+		return -1
+	}
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// ids shared by projects, executions and schedules, so they are never confused in tests
+func (s *Server) nextID() int {
+	s.lastID++
+	return s.lastID
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, format string, args ...interface{}) {
+	writeJSON(w, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+// azkaban answers some non ajax calls with a page
+func writePage(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html")
+	w.Write([]byte("<html><body></body></html>"))
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if r.FormValue("action") != "login" {
+		writePage(w)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	username := r.FormValue("username")
+	if password, ok := s.users[username]; !ok || password != r.FormValue("password") {
+		writeError(w, "Incorrect Login. Username/Password not found.")
+		return
+	}
+
+	token := make([]byte, 16)
+	rand.Read(token)
+	session := hex.EncodeToString(token)
+	s.sessions[session] = username
+
+	writeJSON(w, map[string]string{"session.id": session, "status": "success"})
+
+}
+
+// a handler called with the lock held and the user of the session
+type handler func(w http.ResponseWriter, r *http.Request, user string)
+
+func (s *Server) authenticated(next handler) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		// uploads are multipart forms
+		if r.Method == http.MethodPost && r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+			if err := r.ParseMultipartForm(32 << 20); err != nil {
+				writeError(w, "%v", err)
+				return
+			}
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		user, ok := s.sessions[r.FormValue("session.id")]
+		if !ok {
+			writeError(w, "session")
+			return
+		}
+
+		next(w, r, user)
+
+	}
+
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request, user string) {
+
+	if r.FormValue("ajax") != "fetchuserprojects" {
+		writePage(w)
+		return
+	}
+
+	projects := []map[string]interface{}{}
+	for _, p := range s.sortedProjects() {
+		projects = append(projects, map[string]interface{}{
+			"projectId":   p.id,
+			"projectName": p.name,
+			"createdBy":   p.createdBy,
+			"createdTime": millis(p.createdAt),
+		})
+	}
+
+	writeJSON(w, map[string]interface{}{"projects": projects})
+
+}
@@ -0,0 +1,217 @@
+package azkabantest
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wanglun/azkaban"
+)
+
+// a clock the test moves forward
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newServer(t *testing.T) (*Server, *clock, *azkaban.Client) {
+
+	c := &clock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	server := NewServer()
+	server.JobDuration = time.Minute
+	server.Now = c.Now
+
+	client, err := server.Client()
+	assert.Nil(t, err)
+
+	//   a -> b -> end, a -> sub -> end, sub embeds c -> d
+	flow := azkaban.NewFlow("end").
+		Job("a", azkaban.CommandJob{Commands: []string{"echo ${greeting}"}}, azkaban.Property("greeting", "hello")).
+		Job("b", azkaban.CommandJob{Commands: []string{"echo b"}}, azkaban.DependsOn("a")).
+		Job("c", azkaban.CommandJob{Commands: []string{"echo c"}}).
+		Job("d", azkaban.NoopJob{}, azkaban.DependsOn("c")).
+		Job("sub", azkaban.EmbeddedFlowJob{Flow: "d"}, azkaban.DependsOn("a")).
+		Job("end", azkaban.NoopJob{}, azkaban.DependsOn("b", "sub"))
+
+	var archive bytes.Buffer
+	assert.Nil(t, flow.WriteZip(&archive))
+
+	_, err = client.CreateProject("project", "")
+	assert.Nil(t, err)
+
+	_, err = client.UploadProject(context.Background(), "project", &archive, "project.zip", nil)
+	assert.Nil(t, err)
+
+	return server, c, client
+
+}
+
+func statuses(t *testing.T, client *azkaban.Client, id int64) map[string]string {
+	flow, err := client.FetchExecutionFlow(id)
+	assert.Nil(t, err)
+	statuses := flow.Statuses()
+	statuses[""] = flow.Status
+	return statuses
+}
+
+func TestExecution(t *testing.T) {
+	server, clock, client := newServer(t)
+	defer server.Close()
+
+	flows, err := client.FetchFlows("project")
+	assert.Nil(t, err)
+	assert.Equal(t, []azkaban.Flow{{IdFlow: "d"}, {IdFlow: "end"}}, flows.Flows)
+
+	execute, err := client.ExecuteFlow("project", "end", azkaban.ConcurrentOptionDefault, map[string]string{"greeting": "hi"})
+	assert.Nil(t, err)
+
+	running, err := client.FetchRunningExecutions("project", "end")
+	assert.Nil(t, err)
+	assert.Len(t, running.IdsExecution, 1)
+
+	_, err = client.ExecuteFlow("project", "end", azkaban.ConcurrentOptionSkip, nil)
+	assert.EqualError(t, err, "Flow end is already running. Skipping execution.")
+
+	assert.Equal(t, map[string]string{
+		"": "RUNNING", "a": "RUNNING", "b": "READY", "sub": "READY", "sub:c": "READY", "sub:d": "READY", "end": "READY",
+	}, statuses(t, client, execute.IdExecution))
+
+	clock.Advance(90 * time.Second)
+	assert.Equal(t, map[string]string{
+		"": "RUNNING", "a": "SUCCEEDED", "b": "RUNNING", "sub": "RUNNING", "sub:c": "RUNNING", "sub:d": "READY", "end": "READY",
+	}, statuses(t, client, execute.IdExecution))
+
+	// the job file wins over the flow parameters
+	logs, err := client.FetchExecutionJobLogs(execute.IdExecution, "a", 0, 1000)
+	assert.Nil(t, err)
+	assert.Contains(t, logs.Data, "Command: echo hello\n")
+	assert.Contains(t, logs.Data, "with status SUCCEEDED\n")
+
+	clock.Advance(5 * time.Minute)
+	assert.Equal(t, map[string]string{
+		"": "SUCCEEDED", "a": "SUCCEEDED", "b": "SUCCEEDED", "sub": "SUCCEEDED", "sub:c": "SUCCEEDED", "sub:d": "SUCCEEDED", "end": "SUCCEEDED",
+	}, statuses(t, client, execute.IdExecution))
+
+	flow, err := client.FetchExecutionFlow(execute.IdExecution)
+	assert.Nil(t, err)
+	assert.Equal(t, 4*time.Minute, time.Duration(flow.EndTime-flow.StartTime)*time.Millisecond)
+
+	options, err := client.FetchExecutionOptions(execute.IdExecution)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"greeting": "hi"}, options.FlowParameters)
+
+	executions, err := client.FindExecutions(azkaban.ExecutionFilter{Status: []string{azkaban.ExecutionStatus.Succeeded}})
+	assert.Nil(t, err)
+	assert.Len(t, executions, 1)
+}
+
+func TestFailedExecution(t *testing.T) {
+	server, clock, client := newServer(t)
+	defer server.Close()
+
+	server.FailJob("project", "c")
+
+	execute, err := client.ExecuteFlow("project", "end", azkaban.ConcurrentOptionDefault, nil)
+	assert.Nil(t, err)
+
+	clock.Advance(150 * time.Second)
+	assert.Equal(t, map[string]string{
+		"": "FAILED", "a": "SUCCEEDED", "b": "SUCCEEDED", "sub": "FAILED", "sub:c": "FAILED", "sub:d": "CANCELLED", "end": "CANCELLED",
+	}, statuses(t, client, execute.IdExecution))
+}
+
+func TestCancelledExecution(t *testing.T) {
+	server, clock, client := newServer(t)
+	defer server.Close()
+
+	execute, err := client.ExecuteFlow("project", "end", azkaban.ConcurrentOptionDefault, nil)
+	assert.Nil(t, err)
+
+	clock.Advance(90 * time.Second)
+	assert.Nil(t, client.CancelFlow("project", "end"))
+	assert.EqualError(t, client.CancelFlow("project", "end"), "Flow end isn't running.")
+
+	clock.Advance(time.Hour)
+	assert.Equal(t, map[string]string{
+		"": "KILLED", "a": "SUCCEEDED", "b": "KILLED", "sub": "KILLED", "sub:c": "KILLED", "sub:d": "CANCELLED", "end": "CANCELLED",
+	}, statuses(t, client, execute.IdExecution))
+}
+
+func TestProject(t *testing.T) {
+	server, _, client := newServer(t)
+	defer server.Close()
+
+	_, err := client.CreateProject("project", "")
+	assert.EqualError(t, err, "Project already exists.")
+
+	projects, err := client.FetchProjects()
+	assert.Nil(t, err)
+	assert.Len(t, projects, 1)
+	assert.Equal(t, "project", projects[0].Name)
+
+	var archive bytes.Buffer
+	assert.Nil(t, client.DownloadProject(context.Background(), "project", 1, &archive))
+	assert.NotZero(t, archive.Len())
+	assert.Equal(t, azkaban.ArchiveNotFound, client.DownloadProject(context.Background(), "project", 2, &archive))
+
+	// cycles are refused, FlowBuilder does not write them
+	archive.Reset()
+	w := zip.NewWriter(&archive)
+	for name, body := range map[string]string{"a.job": "type=noop\ndependencies=b\n", "b.job": "type=noop\ndependencies=a\n"} {
+		f, err := w.Create(name)
+		assert.Nil(t, err)
+		f.Write([]byte(body))
+	}
+	assert.Nil(t, w.Close())
+	_, err = client.UploadProject(context.Background(), "project", &archive, "cyclic.zip", nil)
+	assert.Error(t, err)
+
+	// job overrides
+	assert.Nil(t, client.SetJobOverride("project", "end", "a", map[string]string{"greeting": "bonjour"}))
+	info, err := client.FetchJobInfo("project", "end", "a")
+	assert.Nil(t, err)
+	assert.Equal(t, "bonjour", info.Effective()["greeting"])
+
+	var events []string
+	for it := client.ProjectLogs("project", 1); it.Next(); {
+		events = append(events, it.Event().Type)
+	}
+	assert.Equal(t, []string{"PROPERTY_OVERRIDE", "UPLOADED", "CREATED"}, events)
+
+	_, err = client.DeleteProject("project")
+	assert.Nil(t, err)
+	_, err = client.GetProject("project")
+	assert.Equal(t, azkaban.ProjectNotFound, err)
+}
+
+func TestSchedule(t *testing.T) {
+	server, _, client := newServer(t)
+	defer server.Close()
+
+	project, err := client.GetProject("project")
+	assert.Nil(t, err)
+
+	detail, err := client.ScheduleFlow(project, "end", time.Date(2020, 1, 2, 15, 30, 0, 0, time.UTC), "on", "1d")
+	assert.Nil(t, err)
+	assert.Equal(t, "success", detail.Status)
+
+	_, err = client.ScheduleFlow(project, "missing", time.Now(), "on", "1d")
+	assert.EqualError(t, err, "Flow missing cannot be found in project project")
+}
@@ -0,0 +1,420 @@
+package azkaban
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// how far apart the dates of a backfill are
+var Granularity = struct{ Hourly, Daily, Weekly, Monthly string }{"hourly", "daily", "weekly", "monthly"}
+
+// BackfillOptions tells Backfill how to run a flow over a range of dates.
+type BackfillOptions struct {
+	// Granularity is the period between two dates, daily by default
+	Granularity string
+
+	// Params are the flow parameters of every execution, ${pattern} is replaced by the date
+	// formatted with a Java date pattern, such as date=${yyyy-MM-dd}
+	Params map[string]string
+
+	// Parallelism is the number of executions running at once, 1 by default
+	Parallelism int
+
+	// Concurrent is the concurrent option of the executions
+	Concurrent concurrentOption
+
+	// StopOnFailure stops submitting executions once one failed, the running ones are waited for
+	StopOnFailure bool
+
+	// StateFile keeps the progress of the backfill, an interrupted backfill run again with the
+	// same state file resumes where it stopped
+	StateFile string
+
+	// Interval is how often running executions are polled, 10 seconds by default
+	Interval time.Duration
+
+	// Progress is called, one call at a time, when an execution is submitted and when it finishes
+	Progress func(run BackfillRun)
+}
+
+// BackfillReport is the outcome of Backfill, it is also the state resumed from.
+type BackfillReport struct {
+	Project     string         `json:"project"`
+	Flow        string         `json:"flow"`
+	Granularity string         `json:"granularity"`
+	Runs        []*BackfillRun `json:"runs"`
+}
+
+// BackfillRun is the execution of a date of a backfill.
+type BackfillRun struct {
+	Date        time.Time         `json:"date"`
+	Params      map[string]string `json:"params,omitempty"`
+	IdExecution int64             `json:"execid,omitempty"`
+
+	// Status is the last status of the execution, empty until it is submitted
+	Status string `json:"status,omitempty"`
+
+	// Error is why the execution could not be submitted or followed
+	Error string `json:"error,omitempty"`
+}
+
+// Succeeded tells whether the execution of the date succeeded.
+func (this *BackfillRun) Succeeded() bool {
+	return this.Status == ExecutionStatus.Succeeded
+}
+
+// Failed tells whether the date could not be submitted, or its execution did not succeed.
+func (this *BackfillRun) Failed() bool {
+	return this.Error != "" || (FinalStatus(this.Status) && !this.Succeeded())
+}
+
+// the format of the dates of a granularity
+func dateLayout(granularity string) string {
+	if granularity == Granularity.Hourly {
+		return "2006-01-02 15:04"
+	}
+	return "2006-01-02"
+}
+
+// String reports the counts of executions, then a line per date.
+func (this *BackfillReport) String() string {
+
+	var succeeded, failed, pending int
+	for _, run := range this.Runs {
+		switch {
+		case run.Succeeded():
+			succeeded++
+		case run.Failed():
+			failed++
+		default:
+			pending++
+		}
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "backfill of %s.%s: %d succeeded, %d failed, %d pending\n", this.Project, this.Flow, succeeded, failed, pending)
+
+	for _, run := range this.Runs {
+
+		id, status := "-", run.Status
+		if run.IdExecution != 0 {
+			id = fmt.Sprint(run.IdExecution)
+		}
+		if run.Error != "" {
+			status = run.Error
+		}
+		if status == "" {
+			status = "pending"
+		}
+
+		fmt.Fprintf(&b, "%s  %s  %s\n", run.Date.Format(dateLayout(this.Granularity)), id, status)
+
+	}
+
+	return b.String()
+
+}
+
+// the dates from first to last included, a number of periods after first
+func backfillDates(first, last time.Time, granularity string) ([]time.Time, error) {
+
+	var next func(i int) time.Time
+
+	switch granularity {
+	case Granularity.Hourly:
+		next = func(i int) time.Time { return first.Add(time.Duration(i) * time.Hour) }
+	case Granularity.Daily:
+		next = func(i int) time.Time { return first.AddDate(0, 0, i) }
+	case Granularity.Weekly:
+		next = func(i int) time.Time { return first.AddDate(0, 0, 7*i) }
+	case Granularity.Monthly:
+		// later days would move to the next month in shorter months
+		if first.Day() > 28 {
+			return nil, fmt.Errorf("a monthly backfill cannot start on the %dth", first.Day())
+		}
+		next = func(i int) time.Time { return first.AddDate(0, i, 0) }
+	default:
+		return nil, fmt.Errorf("unknown granularity %s", granularity)
+	}
+
+	if last.Before(first) {
+		return nil, fmt.Errorf("the backfill ends before it starts")
+	}
+
+	var dates []time.Time
+	for i := 0; !next(i).After(last); i++ {
+		dates = append(dates, next(i))
+	}
+
+	return dates, nil
+
+}
+
+// the start of the hour of a date for hourly backfills, of its day otherwise, in the location of
+// the client or the date's own when the client has none
+func (this *Client) backfillDate(date time.Time, granularity string) time.Time {
+
+	date = this.localize(date)
+
+	hour := 0
+	if granularity == Granularity.Hourly {
+		hour = date.Hour()
+	}
+
+	return time.Date(date.Year(), date.Month(), date.Day(), hour, 0, 0, 0, date.Location())
+
+}
+
+// the key of a date in the state of a backfill, the same whatever the time of day or location it
+// was given with
+func (this *Client) backfillKey(date time.Time, granularity string) string {
+	return this.backfillDate(date, granularity).Format("2006-01-02T15")
+}
+
+var datePattern = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// Java date patterns and their Go layout, longer ones first
+var javaLayout = strings.NewReplacer("yyyy", "2006", "yy", "06", "MM", "01", "dd", "02", "HH", "15", "mm", "04", "ss", "05")
+
+// FormatDateTemplate replaces the ${pattern} of a template by a date formatted with the Java date
+// pattern, which may use yyyy, yy, MM, dd, HH, mm and ss.
+func FormatDateTemplate(template string, date time.Time) string {
+	return datePattern.ReplaceAllStringFunc(template, func(match string) string {
+		return date.Format(javaLayout.Replace(match[2 : len(match)-1]))
+	})
+}
+
+// the state of an interrupted backfill, a new report when there is none
+func loadBackfillReport(path, project, flow, granularity string) (*BackfillReport, error) {
+
+	report := &BackfillReport{Project: project, Flow: flow, Granularity: granularity}
+	if path == "" {
+		return report, nil
+	}
+
+	found, err := readJSONFile(path, report)
+	if err != nil || !found {
+		return report, err
+	}
+
+	if report.Project != project || report.Flow != flow || report.Granularity != granularity {
+		return nil, fmt.Errorf("%s is the state of a %s backfill of %s.%s", path, report.Granularity, report.Project, report.Flow)
+	}
+
+	return report, nil
+
+}
+
+func (this *BackfillReport) save(path string) error {
+
+	if path == "" {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(this, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, content, 0644)
+
+}
+
+// Backfill executes a flow for every date from first to last, each with its own flow parameters,
+// and follows the executions until they finish or ctx is done. The dates start at the hour of
+// first for hourly backfills and at its day otherwise, in the location of the client when it has
+// one, so a run resumed with first at another time of the same day covers the same dates. With a state file, the dates which
+// succeeded are not run again, the executions still running are followed again, and the dates
+// which failed are submitted again.
+//
+// The report is returned along with an error when a date failed, when the backfill was stopped
+// on failure or when ctx is done.
+func (this *Client) Backfill(ctx context.Context, project, flow string, first, last time.Time, options BackfillOptions) (*BackfillReport, error) {
+
+	if options.Granularity == "" {
+		options.Granularity = Granularity.Daily
+	}
+	if options.Parallelism <= 0 {
+		options.Parallelism = 1
+	}
+	if options.Interval <= 0 {
+		options.Interval = 10 * time.Second
+	}
+
+	dates, err := backfillDates(this.backfillDate(first, options.Granularity), this.backfillDate(last, options.Granularity), options.Granularity)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := loadBackfillReport(options.StateFile, project, flow, options.Granularity)
+	if err != nil {
+		return nil, err
+	}
+
+	// the runs of the range, in order, those of the state are kept
+	runs := map[string]*BackfillRun{}
+	for _, run := range report.Runs {
+		runs[this.backfillKey(run.Date, options.Granularity)] = run
+	}
+
+	report.Runs = nil
+	for _, date := range dates {
+		run, ok := runs[this.backfillKey(date, options.Granularity)]
+		if !ok {
+			run = &BackfillRun{Date: date}
+		}
+		report.Runs = append(report.Runs, run)
+	}
+
+	b := &backfill{client: this, project: project, flow: flow, options: options, report: report}
+
+	if err = report.save(options.StateFile); err != nil {
+		return report, err
+	}
+
+	queue := make(chan *BackfillRun)
+
+	var workers sync.WaitGroup
+	for i := 0; i < options.Parallelism; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for run := range queue {
+				b.run(ctx, run)
+			}
+		}()
+	}
+
+	for _, run := range report.Runs {
+		if run.Succeeded() {
+			continue
+		}
+		if b.stopped() || ctx.Err() != nil {
+			break
+		}
+		queue <- run
+	}
+
+	close(queue)
+	workers.Wait()
+
+	if b.err != nil {
+		return report, b.err
+	}
+
+	if err = ctx.Err(); err != nil {
+		return report, err
+	}
+
+	failed := 0
+	for _, run := range report.Runs {
+		if !run.Succeeded() {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return report, fmt.Errorf("%d of %d dates did not succeed", failed, len(report.Runs))
+	}
+
+	return report, nil
+
+}
+
+type backfill struct {
+	client        *Client
+	project, flow string
+	options       BackfillOptions
+	report        *BackfillReport
+
+	// guards the report, the failure and the state file
+	mu     sync.Mutex
+	failed bool
+	err    error
+}
+
+func (this *backfill) stopped() bool {
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	return this.err != nil || (this.failed && this.options.StopOnFailure)
+
+}
+
+// record a change of a run, saved in the state file and reported to the progress callback
+func (this *backfill) update(run *BackfillRun, change func()) {
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	change()
+
+	if run.Failed() {
+		this.failed = true
+	}
+
+	if err := this.report.save(this.options.StateFile); err != nil && this.err == nil {
+		this.err = err
+	}
+
+	if this.options.Progress != nil {
+		this.options.Progress(*run)
+	}
+
+}
+
+// submit the execution of a date, unless a previous backfill did and it may still be running,
+// and wait for it
+func (this *backfill) run(ctx context.Context, run *BackfillRun) {
+
+	if run.IdExecution == 0 || FinalStatus(run.Status) {
+
+		if this.stopped() || ctx.Err() != nil {
+			return
+		}
+
+		params := map[string]string{}
+		for key, template := range this.options.Params {
+			params[key] = FormatDateTemplate(template, run.Date)
+		}
+
+		execute, err := this.client.ExecuteFlow(this.project, this.flow, this.options.Concurrent, params)
+
+		this.update(run, func() {
+			run.Params, run.IdExecution, run.Status, run.Error = params, 0, "", ""
+			if err != nil {
+				run.Error = err.Error()
+				return
+			}
+			run.IdExecution, run.Status = execute.IdExecution, ExecutionStatus.Preparing
+		})
+
+		if err != nil {
+			return
+		}
+
+	}
+
+	execution, err := this.client.WaitExecution(ctx, run.IdExecution, this.options.Interval)
+
+	// followed again when the backfill is resumed
+	if err == ctx.Err() && err != nil {
+		return
+	}
+
+	this.update(run, func() {
+		if err != nil {
+			run.Error = err.Error()
+			return
+		}
+		run.Status, run.Error = execution.Status, ""
+	})
+
+}
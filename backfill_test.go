@@ -0,0 +1,99 @@
+package azkaban_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wanglun/azkaban"
+	"github.com/wanglun/azkaban/azkabantest"
+)
+
+func TestFormatDateTemplate(t *testing.T) {
+
+	date := time.Date(2030, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	assert.Equal(t, "2030-03-04", azkaban.FormatDateTemplate("${yyyy-MM-dd}", date))
+	assert.Equal(t, "dt=300304/05:06:07", azkaban.FormatDateTemplate("dt=${yyMMdd}/${HH:mm:ss}", date))
+	assert.Equal(t, "static", azkaban.FormatDateTemplate("static", date))
+
+}
+
+func TestBackfill(t *testing.T) {
+
+	server := azkabantest.NewServer()
+	defer server.Close()
+
+	client, err := server.Client()
+	assert.Nil(t, err)
+
+	_, err = client.CreateProject(PROJECT_NAME, PROJECT_DESC)
+	assert.Nil(t, err)
+	assert.Nil(t, client.UploadProjectZip(PROJECT_NAME, FLOW_ZIP_PATH))
+
+	first := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 0, 3)
+	state := filepath.Join(t.TempDir(), "backfill.json")
+
+	// interrupted once the first date finished
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	options := azkaban.BackfillOptions{
+		Params:    map[string]string{"date": "${yyyy-MM-dd}"},
+		StateFile: state,
+		Interval:  time.Millisecond,
+		Progress: func(run azkaban.BackfillRun) {
+			if run.Succeeded() {
+				cancel()
+			}
+		},
+	}
+
+	report, err := client.Backfill(ctx, PROJECT_NAME, "bar", first, last, options)
+	assert.Equal(t, context.Canceled, err)
+	assert.Len(t, report.Runs, 4)
+	assert.True(t, report.Runs[0].Succeeded())
+	assert.Equal(t, map[string]string{"date": "2030-01-01"}, report.Runs[0].Params)
+	assert.Zero(t, report.Runs[1].IdExecution)
+
+	// resumed from another time of the same day, the first date is not run again
+	options.Progress = nil
+	options.Parallelism = 2
+
+	report, err = client.Backfill(context.Background(), PROJECT_NAME, "bar", first.Add(10*time.Hour).In(time.FixedZone("CET", 3600)), last, options)
+	assert.Nil(t, err)
+	for _, run := range report.Runs {
+		assert.True(t, run.Succeeded())
+	}
+	assert.Equal(t, map[string]string{"date": "2030-01-04"}, report.Runs[3].Params)
+	assert.Contains(t, report.String(), "backfill of "+PROJECT_NAME+".bar: 4 succeeded, 0 failed, 0 pending\n2030-01-01  ")
+
+	executions, err := client.FetchExecutions(PROJECT_NAME, "bar", 0, 10)
+	assert.Nil(t, err)
+	assert.Len(t, executions.Execution, 4)
+
+	// the state belongs to this backfill
+	_, err = client.Backfill(context.Background(), PROJECT_NAME, "bar", first, last, azkaban.BackfillOptions{Granularity: azkaban.Granularity.Weekly, StateFile: state})
+	assert.NotNil(t, err)
+
+	// failures stop the backfill, or not
+	server.FailJob(PROJECT_NAME, "foo")
+
+	report, err = client.Backfill(context.Background(), PROJECT_NAME, "bar", first, last, azkaban.BackfillOptions{StopOnFailure: true, Interval: time.Millisecond})
+	assert.NotNil(t, err)
+	assert.Equal(t, azkaban.ExecutionStatus.Failed, report.Runs[0].Status)
+	assert.Equal(t, "", report.Runs[1].Status)
+
+	report, err = client.Backfill(context.Background(), PROJECT_NAME, "bar", first, last, azkaban.BackfillOptions{Parallelism: 4, Interval: time.Millisecond})
+	assert.Equal(t, "4 of 4 dates did not succeed", err.Error())
+	assert.True(t, report.Runs[3].Failed())
+
+	// monthly dates keep their day
+	report, err = client.Backfill(context.Background(), PROJECT_NAME, "bar", time.Date(2030, 1, 31, 0, 0, 0, 0, time.UTC), last, azkaban.BackfillOptions{Granularity: azkaban.Granularity.Monthly})
+	assert.Nil(t, report)
+	assert.NotNil(t, err)
+
+}
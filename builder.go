@@ -0,0 +1,391 @@
+package azkaban
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// job types understood by azkaban and its common jobtype plugins
+var JobType = struct {
+	Command, JavaProcess, HadoopJava, Hive, Spark, Pig, Noop, Flow string
+}{"command", "javaprocess", "hadoopJava", "hive", "spark", "pig", "noop", "flow"}
+
+// JobDefinition describes the type specific part of a job file.
+type JobDefinition interface {
+	// Type returns the value of the job's type property.
+	Type() string
+	// Properties returns the type specific properties of the job, without type.
+	Properties() map[string]string
+}
+
+// CommandJob runs one or more shell commands in sequence.
+type CommandJob struct {
+	Commands []string
+}
+
+func (this CommandJob) Type() string { return JobType.Command }
+
+func (this CommandJob) Properties() map[string]string {
+	props := map[string]string{}
+	for i, cmd := range this.Commands {
+		if i == 0 {
+			props["command"] = cmd
+		} else {
+			props["command."+strconv.Itoa(i)] = cmd
+		}
+	}
+	return props
+}
+
+// JavaProcessJob runs the main method of a java class in a new JVM.
+type JavaProcessJob struct {
+	Class     string
+	Classpath []string
+	JVMArgs   string
+	Xms       string
+	Xmx       string
+	MainArgs  []string
+}
+
+func (this JavaProcessJob) Type() string { return JobType.JavaProcess }
+
+func (this JavaProcessJob) Properties() map[string]string {
+	props := map[string]string{"java.class": this.Class}
+	setIf(props, "classpath", strings.Join(this.Classpath, ","))
+	setIf(props, "jvm.args", this.JVMArgs)
+	setIf(props, "Xms", this.Xms)
+	setIf(props, "Xmx", this.Xmx)
+	setIf(props, "main.args", strings.Join(this.MainArgs, " "))
+	return props
+}
+
+// HadoopJavaJob runs a hadoop job class through the hadoopJava jobtype plugin.
+type HadoopJavaJob struct {
+	JobClass  string
+	Classpath []string
+	MainArgs  []string
+}
+
+func (this HadoopJavaJob) Type() string { return JobType.HadoopJava }
+
+func (this HadoopJavaJob) Properties() map[string]string {
+	props := map[string]string{"job.class": this.JobClass}
+	setIf(props, "classpath", strings.Join(this.Classpath, ","))
+	setIf(props, "main.args", strings.Join(this.MainArgs, " "))
+	return props
+}
+
+// HiveJob runs a hive script through the hive jobtype plugin.
+type HiveJob struct {
+	Script string
+}
+
+func (this HiveJob) Type() string { return JobType.Hive }
+
+func (this HiveJob) Properties() map[string]string {
+	return map[string]string{"hive.script": this.Script}
+}
+
+// SparkJob submits a spark application through the spark jobtype plugin.
+type SparkJob struct {
+	Class          string
+	ExecutionJar   string
+	Master         string
+	DriverMemory   string
+	ExecutorMemory string
+	NumExecutors   int
+	Params         []string
+}
+
+func (this SparkJob) Type() string { return JobType.Spark }
+
+func (this SparkJob) Properties() map[string]string {
+	props := map[string]string{"class": this.Class, "execution-jar": this.ExecutionJar}
+	setIf(props, "master", this.Master)
+	setIf(props, "driver-memory", this.DriverMemory)
+	setIf(props, "executor-memory", this.ExecutorMemory)
+	if this.NumExecutors > 0 {
+		props["num-executors"] = strconv.Itoa(this.NumExecutors)
+	}
+	setIf(props, "params", strings.Join(this.Params, " "))
+	return props
+}
+
+// PigJob runs a pig script through the pig jobtype plugin.
+type PigJob struct {
+	Script string
+}
+
+func (this PigJob) Type() string { return JobType.Pig }
+
+func (this PigJob) Properties() map[string]string {
+	return map[string]string{"pig.script": this.Script}
+}
+
+// NoopJob does nothing, it is useful to join or fan out dependencies.
+type NoopJob struct{}
+
+func (this NoopJob) Type() string { return JobType.Noop }
+
+func (this NoopJob) Properties() map[string]string { return map[string]string{} }
+
+// EmbeddedFlowJob runs another flow of the project as a single node.
+type EmbeddedFlowJob struct {
+	Flow string
+}
+
+func (this EmbeddedFlowJob) Type() string { return JobType.Flow }
+
+func (this EmbeddedFlowJob) Properties() map[string]string {
+	return map[string]string{"flow.name": this.Flow}
+}
+
+func setIf(props map[string]string, key, value string) {
+	if value != "" {
+		props[key] = value
+	}
+}
+
+// JobOption sets a property shared by all job types.
+type JobOption func(*builderJob)
+
+type builderJob struct {
+	name       string
+	definition JobDefinition
+	depends    []string
+	props      map[string]string
+}
+
+// DependsOn adds dependencies to the job.
+func DependsOn(jobs ...string) JobOption {
+	return func(j *builderJob) {
+		j.depends = append(j.depends, jobs...)
+	}
+}
+
+// Retries makes azkaban retry the job up to n times, waiting backoff between attempts.
+func Retries(n int, backoff time.Duration) JobOption {
+	return func(j *builderJob) {
+		j.props["retries"] = strconv.Itoa(n)
+		if backoff > 0 {
+			j.props["retry.backoff"] = strconv.FormatInt(int64(backoff/time.Millisecond), 10)
+		}
+	}
+}
+
+// WorkingDir sets the directory the job runs in.
+func WorkingDir(dir string) JobOption {
+	return Property("working.dir", dir)
+}
+
+// Property sets an arbitrary property on the job.
+func Property(key, value string) JobOption {
+	return func(j *builderJob) {
+		j.props[key] = value
+	}
+}
+
+// FlowBuilder authors a flow in the legacy .job format.
+//
+//	flow := NewFlow("daily").
+//		Job("foo", CommandJob{Commands: []string{"echo foo"}}).
+//		Job("bar", CommandJob{Commands: []string{"echo bar"}}, DependsOn("foo"))
+//
+// Azkaban names a flow after its last job, so unless a job is named after the flow,
+// a noop job with the flow name depending on every leaf job is added when rendering.
+type FlowBuilder struct {
+	name  string
+	jobs  []*builderJob
+	props map[string]string
+	err   error
+}
+
+// NewFlow starts a new flow.
+func NewFlow(name string) *FlowBuilder {
+	return &FlowBuilder{
+		name:  name,
+		props: map[string]string{},
+	}
+}
+
+// Job adds a job to the flow.
+func (this *FlowBuilder) Job(name string, definition JobDefinition, options ...JobOption) *FlowBuilder {
+
+	job := &builderJob{
+		name:       name,
+		definition: definition,
+		props:      map[string]string{},
+	}
+
+	for _, option := range options {
+		option(job)
+	}
+
+	// keep the first error, it is reported when rendering
+	if this.err == nil {
+		if name == "" || strings.ContainsAny(name, `/\`) {
+			this.err = fmt.Errorf("invalid job name %q", name)
+		} else if this.job(name) != nil {
+			this.err = fmt.Errorf("duplicate job %q", name)
+		}
+	}
+
+	this.jobs = append(this.jobs, job)
+
+	return this
+
+}
+
+// Property sets a flow level property, written to flow.properties and inherited by every job.
+func (this *FlowBuilder) Property(key, value string) *FlowBuilder {
+	this.props[key] = value
+	return this
+}
+
+func (this *FlowBuilder) job(name string) *builderJob {
+	for _, job := range this.jobs {
+		if job.name == name {
+			return job
+		}
+	}
+	return nil
+}
+
+// Files renders the flow into project files. Unknown dependencies and dependency cycles, which
+// azkaban rejects on upload, are errors, a cycle being reported as a CycleError.
+func (this *FlowBuilder) Files() ([]File, error) {
+
+	if this.err != nil {
+		return nil, this.err
+	}
+
+	jobs := this.jobs
+
+	// check dependencies and find the jobs nobody depends on
+	depended := map[string]bool{}
+	nodes := make([]Node, len(jobs))
+	for i, job := range jobs {
+		for _, dep := range job.depends {
+			if this.job(dep) == nil {
+				return nil, fmt.Errorf("job %q depends on unknown job %q", job.name, dep)
+			}
+			depended[dep] = true
+		}
+		nodes[i] = Node{ID: job.name, In: job.depends}
+	}
+
+	// azkaban rejects the upload of a cyclic flow
+	if cycles := newGraph(this.name, "", nodes).Cycles(); len(cycles) > 0 {
+		return nil, &CycleError{Cycles: cycles}
+	}
+
+	// close the flow with a job named after it
+	if this.job(this.name) == nil {
+		end := &builderJob{name: this.name, definition: NoopJob{}, props: map[string]string{}}
+		for _, job := range jobs {
+			if !depended[job.name] {
+				end.depends = append(end.depends, job.name)
+			}
+		}
+		jobs = append(jobs, end)
+	}
+
+	var files []File
+
+	if len(this.props) > 0 {
+		files = append(files, File{Name: "flow.properties", Body: formatProperties(this.props)})
+	}
+
+	for _, job := range jobs {
+		files = append(files, File{Name: job.name + ".job", Body: job.render()})
+	}
+
+	return files, nil
+
+}
+
+func (this *builderJob) render() []byte {
+
+	props := map[string]string{}
+	for k, v := range this.definition.Properties() {
+		props[k] = v
+	}
+	for k, v := range this.props {
+		props[k] = v
+	}
+	props["type"] = this.definition.Type()
+	if len(this.depends) > 0 {
+		props["dependencies"] = strings.Join(this.depends, ",")
+	}
+
+	return formatProperties(props)
+
+}
+
+// write properties sorted by key, with type first as azkaban's own examples do
+func formatProperties(props map[string]string) []byte {
+
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		if k != "type" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if _, ok := props["type"]; ok {
+		keys = append([]string{"type"}, keys...)
+	}
+
+	entries := make([]PropertyEntry, len(keys))
+	for i, k := range keys {
+		entries[i] = PropertyEntry{Key: k, Value: props[k]}
+	}
+
+	var buff bytes.Buffer
+	WriteProperties(&buff, entries)
+
+	return buff.Bytes()
+
+}
+
+// WriteDir renders the flow into a project directory.
+func (this *FlowBuilder) WriteDir(dir string) error {
+
+	files, err := this.Files()
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, file.Name), file.Body, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// WriteZip renders the flow into a project zip archive, ready for UploadProject.
+func (this *FlowBuilder) WriteZip(w io.Writer) error {
+
+	files, err := this.Files()
+	if err != nil {
+		return err
+	}
+
+	return writeZip(w, files)
+
+}
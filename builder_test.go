@@ -0,0 +1,76 @@
+package azkaban
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlowBuilder(t *testing.T) {
+	flow := NewFlow("daily").
+		Property("user.to.proxy", "etl").
+		Job("extract", CommandJob{Commands: []string{"echo extract", "echo done"}}, Retries(2, 30*time.Second)).
+		Job("load", SparkJob{Class: "Load", ExecutionJar: "load.jar", NumExecutors: 4}, DependsOn("extract"), WorkingDir("/tmp")).
+		Job("report", HiveJob{Script: "report.q"}, DependsOn("extract"))
+
+	files, err := flow.Files()
+	assert.Nil(t, err)
+
+	bodies := map[string]string{}
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name)
+		bodies[file.Name] = string(file.Body)
+	}
+
+	// the noop job named after the flow closes it
+	assert.Equal(t, []string{"flow.properties", "extract.job", "load.job", "report.job", "daily.job"}, names)
+	assert.Equal(t, "user.to.proxy=etl\n", bodies["flow.properties"])
+	assert.Equal(t, "type=command\ncommand=echo extract\ncommand.1=echo done\nretries=2\nretry.backoff=30000\n", bodies["extract.job"])
+	assert.Equal(t, "type=spark\nclass=Load\ndependencies=extract\nexecution-jar=load.jar\nnum-executors=4\nworking.dir=/tmp\n", bodies["load.job"])
+	assert.Equal(t, "type=hive\ndependencies=extract\nhive.script=report.q\n", bodies["report.job"])
+	assert.Equal(t, "type=noop\ndependencies=load,report\n", bodies["daily.job"])
+
+	// the archive reads back as the same flow
+	var archive bytes.Buffer
+	assert.Nil(t, flow.WriteZip(&archive))
+	reader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	assert.Nil(t, err)
+	read := map[string]string{}
+	for _, file := range reader.File {
+		f, err := file.Open()
+		assert.Nil(t, err)
+		body, err := ioutil.ReadAll(f)
+		assert.Nil(t, err)
+		f.Close()
+		read[file.Name] = string(body)
+	}
+	assert.Equal(t, bodies, read)
+
+	// a job named after the flow ends it
+	files, err = NewFlow("single").Job("single", NoopJob{}).Files()
+	assert.Nil(t, err)
+	if assert.Len(t, files, 1) {
+		assert.Equal(t, "type=noop\n", string(files[0].Body))
+	}
+}
+
+func TestFlowBuilderErrors(t *testing.T) {
+	_, err := NewFlow("daily").Job("a", NoopJob{}).Job("a", NoopJob{}).Files()
+	assert.EqualError(t, err, `duplicate job "a"`)
+
+	_, err = NewFlow("daily").Job("a", NoopJob{}, DependsOn("b")).Files()
+	assert.EqualError(t, err, `job "a" depends on unknown job "b"`)
+
+	_, err = NewFlow("daily").Job("a/b", NoopJob{}).Files()
+	assert.EqualError(t, err, `invalid job name "a/b"`)
+
+	_, err = NewFlow("daily").Job("a", NoopJob{}, DependsOn("c")).Job("b", NoopJob{}, DependsOn("a")).Job("c", NoopJob{}, DependsOn("b")).Job("d", NoopJob{}, DependsOn("d")).Files()
+	assert.Equal(t, &CycleError{Cycles: [][]string{{"a", "b", "c"}, {"d"}}}, err)
+
+	assert.NotNil(t, NewFlow("daily").Job("a", NoopJob{}).Job("a", NoopJob{}).WriteZip(&bytes.Buffer{}))
+}
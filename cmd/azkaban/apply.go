@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/wanglun/azkaban"
+)
+
+func (c *cli) apply(args []string) error {
+
+	flags := c.flags()
+	dryRun := flags.Bool("dry-run", false, "print the plan without changing anything")
+
+	args, err := c.parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	spec, err := azkaban.LoadProjectSpec(args[0])
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	plan, err := client.Apply(spec, *dryRun)
+	if plan == nil {
+		return err
+	}
+
+	// the plan is printed even when a change failed, to show where it stopped
+	if c.format == "table" {
+		fmt.Fprint(c.stdout, plan.String())
+	} else if perr := c.print(plan, nil, nil); err == nil {
+		err = perr
+	}
+
+	return err
+
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wanglun/azkaban"
+)
+
+// a date of the command line, with an hour for hourly backfills
+func parseDate(value string, location *time.Location) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if date, err := time.ParseInLocation(layout, value, location); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, usageError("invalid date %s, expected yyyy-mm-dd or yyyy-mm-ddThh:mm", value)
+}
+
+func (c *cli) backfill(args []string) error {
+
+	flags := c.flags()
+	templates := params{}
+	flags.Var(templates, "param", "flow parameter as key=template, ${yyyy-MM-dd} is replaced by the date, repeatable")
+	granularity := flags.String("granularity", azkaban.Granularity.Daily, "hourly, daily, weekly or monthly")
+	parallelism := flags.Int("parallel", 1, "executions running at once")
+	concurrent := flags.String("concurrent", "", "what to do when the flow is already running: ignore, pipeline or skip")
+	stop := flags.Bool("stop-on-failure", false, "submit no more executions once one failed")
+	state := flags.String("state", "", "file keeping the progress, to resume an interrupted backfill")
+	interval := flags.Duration("interval", 10*time.Second, "polling interval of the executions")
+
+	args, err := c.parse(flags, args, 4, 4)
+	if err != nil {
+		return err
+	}
+
+	options := azkaban.BackfillOptions{
+		Granularity:   *granularity,
+		Params:        templates,
+		Parallelism:   *parallelism,
+		StopOnFailure: *stop,
+		StateFile:     *state,
+		Interval:      *interval,
+	}
+
+	switch *concurrent {
+	case "":
+	case "ignore":
+		options.Concurrent = azkaban.ConcurrentOptionIgnore
+	case "pipeline":
+		options.Concurrent = azkaban.ConcurrentOptionPipeline
+	case "skip":
+		options.Concurrent = azkaban.ConcurrentOptionSkip
+	default:
+		return usageError("unknown concurrent option %s", *concurrent)
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	location := client.Location
+	if location == nil {
+		location = time.Local
+	}
+
+	first, err := parseDate(args[2], location)
+	if err != nil {
+		return err
+	}
+	last, err := parseDate(args[3], location)
+	if err != nil {
+		return err
+	}
+
+	options.Progress = func(run azkaban.BackfillRun) {
+		status := run.Status
+		if run.Error != "" {
+			status = run.Error
+		}
+		fmt.Fprintf(c.stderr, "%s execution %d %s\n", run.Date.Format("2006-01-02 15:04"), run.IdExecution, status)
+	}
+
+	report, err := client.Backfill(context.Background(), args[0], args[1], first, last, options)
+	if report == nil {
+		return err
+	}
+
+	// the report is printed even when dates failed, to show which ones
+	if c.format == "table" {
+		fmt.Fprint(c.stdout, report.String())
+	} else if perr := c.print(report, nil, nil); err == nil {
+		err = perr
+	}
+
+	if err != nil {
+		return &codeError{code: exitFailed, err: err}
+	}
+
+	return nil
+
+}
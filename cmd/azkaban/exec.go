@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wanglun/azkaban"
+)
+
+// key=value flags, repeatable
+type params map[string]string
+
+func (p params) String() string {
+	var pairs []string
+	for k, v := range p {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (p params) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i <= 0 {
+		return fmt.Errorf("%q is not key=value", value)
+	}
+	p[value[:i]] = value[i+1:]
+	return nil
+}
+
+func parseExecID(arg string) (int64, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, usageError("invalid execution id %s", arg)
+	}
+	return id, nil
+}
+
+func (c *cli) execRun(args []string) error {
+
+	flags := c.flags()
+	overrides := params{}
+	flags.Var(overrides, "param", "flow parameter as key=value, repeatable")
+	concurrent := flags.String("concurrent", "", "what to do when the flow is already running: ignore, pipeline or skip")
+	executor := flags.Int("executor", 0, "id of the executor to run on, the web server picks one by default")
+	wait := flags.Bool("wait", false, "wait for the execution to finish, the exit code reports its status")
+	interval := flags.Duration("interval", 5*time.Second, "polling interval when waiting")
+
+	args, err := c.parse(flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	option := azkaban.ConcurrentOptionDefault
+	switch *concurrent {
+	case "":
+	case "ignore":
+		option = azkaban.ConcurrentOptionIgnore
+	case "pipeline":
+		option = azkaban.ConcurrentOptionPipeline
+	case "skip":
+		option = azkaban.ConcurrentOptionSkip
+	default:
+		return usageError("unknown concurrent option %s", *concurrent)
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	var execute *azkaban.Execute
+	if *executor != 0 {
+		execute, err = client.ExecuteFlowOnExecutor(args[0], args[1], *executor, option, overrides)
+	} else {
+		execute, err = client.ExecuteFlow(args[0], args[1], option, overrides)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !*wait {
+		return c.print(execute, []string{"EXECID", "PROJECT", "FLOW"}, [][]string{{strconv.FormatInt(execute.IdExecution, 10), execute.Project, execute.Flow}})
+	}
+
+	fmt.Fprintf(c.stderr, "execution %d submitted\n", execute.IdExecution)
+
+	return c.wait(client, execute.IdExecution, *interval)
+
+}
+
+func (c *cli) execStatus(args []string) error {
+
+	args, err := c.parse(c.flags(), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := parseExecID(args[0])
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	flow, err := client.FetchExecutionFlow(id)
+	if err != nil {
+		return err
+	}
+
+	return c.printExecution(flow)
+
+}
+
+func (c *cli) execWait(args []string) error {
+
+	flags := c.flags()
+	interval := flags.Duration("interval", 5*time.Second, "polling interval")
+
+	args, err := c.parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := parseExecID(args[0])
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	return c.wait(client, id, *interval)
+
+}
+
+func (c *cli) wait(client *azkaban.Client, id int64, interval time.Duration) error {
+
+	flow, err := client.WaitExecution(context.Background(), id, interval)
+	if err != nil {
+		return err
+	}
+
+	return c.printExecution(flow)
+
+}
+
+// print the state of an execution, the exit code reports its status
+func (c *cli) printExecution(flow *azkaban.ExecutionFlow) error {
+
+	rows := [][]string{{flow.Flow, flow.Status, formatTime(flow.StartedAt), formatTime(flow.FinishedAt), ""}}
+
+	durations := flow.Durations()
+
+	var walk func(prefix string, nodes []azkaban.ExecutionNode)
+	walk = func(prefix string, nodes []azkaban.ExecutionNode) {
+		for _, node := range nodes {
+			id := prefix + node.ID
+			duration := ""
+			if d, ok := durations[id]; ok {
+				duration = d.String()
+			}
+			rows = append(rows, []string{"  " + id, node.Status, formatTime(node.StartedAt), formatTime(node.FinishedAt), duration})
+			walk(id+":", node.Nodes)
+		}
+	}
+
+	walk("", flow.Nodes)
+
+	if err := c.print(flow, []string{"NODE", "STATUS", "STARTED", "FINISHED", "DURATION"}, rows); err != nil {
+		return err
+	}
+
+	if code := statusCode(flow.Status); code != exitOK {
+		return &codeError{code: code, err: fmt.Errorf("execution %d is %s", flow.IdExecution, strings.ToLower(flow.Status))}
+	}
+
+	return nil
+
+}
+
+func (c *cli) execCancel(args []string) error {
+
+	args, err := c.parse(c.flags(), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := parseExecID(args[0])
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	if err = client.CancelExecution(id); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stderr, "execution %d cancelled\n", id)
+
+	return nil
+
+}
+
+// log text is fetched by chunks of this size
+const logChunk = 50000
+
+func (c *cli) execLogs(args []string) error {
+
+	flags := c.flags()
+	follow := flags.Bool("follow", false, "keep printing the log until the job finishes")
+	interval := flags.Duration("interval", 2*time.Second, "polling interval when following")
+
+	args, err := c.parse(flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	id, err := parseExecID(args[0])
+	if err != nil {
+		return err
+	}
+
+	job := args[1]
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	// whether the job was seen finished, the log is then read to its end
+	finished := false
+
+	for offset := 0; ; {
+
+		logs, err := client.FetchExecutionJobLogs(id, job, offset, logChunk)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprint(c.stdout, logs.Data)
+		offset += logs.Length
+
+		// more to read now
+		if logs.Length > 0 {
+			continue
+		}
+
+		if !*follow || finished {
+			return nil
+		}
+
+		// the log is complete once the job finished, what it wrote before finishing is read again
+		flow, err := client.FetchExecutionFlow(id)
+		if err != nil {
+			return err
+		}
+
+		if status, ok := flow.Statuses()[job]; !ok || azkaban.FinalStatus(status) || flow.Finished() {
+			finished = true
+			continue
+		}
+
+		time.Sleep(*interval)
+
+	}
+
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/wanglun/azkaban"
+)
+
+// the executor of an id argument, as the web server knows it
+func findExecutor(client *azkaban.Client, arg string) (azkaban.Executor, error) {
+
+	id, err := strconv.Atoi(arg)
+	if err != nil {
+		return azkaban.Executor{}, usageError("invalid executor id %s", arg)
+	}
+
+	executors, err := client.FetchExecutors()
+	if err != nil {
+		return azkaban.Executor{}, err
+	}
+
+	for _, executor := range executors {
+		if executor.ID == id {
+			return executor, nil
+		}
+	}
+
+	return azkaban.Executor{}, fmt.Errorf("executor %d not found", id)
+
+}
+
+func (c *cli) executorList(args []string) error {
+
+	if _, err := c.parse(c.flags(), args, 0, 0); err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	executors, err := client.FetchExecutors()
+	if err != nil {
+		return err
+	}
+
+	var rows [][]string
+	for _, e := range executors {
+		rows = append(rows, []string{strconv.Itoa(e.ID), e.Host, strconv.Itoa(e.Port), strconv.FormatBool(e.Active)})
+	}
+
+	return c.print(executors, []string{"ID", "HOST", "PORT", "ACTIVE"}, rows)
+
+}
+
+func (c *cli) executorCheck(args []string) error {
+
+	if _, err := c.parse(c.flags(), args, 0, 0); err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	checks, err := client.CheckExecutors()
+	if err != nil {
+		return err
+	}
+
+	var (
+		rows      [][]string
+		unhealthy int
+	)
+
+	for _, check := range checks {
+		e := check.Executor
+		if !check.Healthy() {
+			unhealthy++
+			rows = append(rows, []string{strconv.Itoa(e.ID), fmt.Sprintf("%s:%d", e.Host, e.Port), "-", "-", "-", check.Error})
+			continue
+		}
+		rows = append(rows, []string{
+			strconv.Itoa(e.ID), fmt.Sprintf("%s:%d", e.Host, e.Port), strconv.FormatBool(e.Active),
+			strconv.Itoa(e.Stats.NumberOfAssignedFlows), fmt.Sprintf("%.0f%%", e.Stats.RemainingMemoryPercent), "ok",
+		})
+	}
+
+	if err = c.print(checks, []string{"ID", "ADDRESS", "ACTIVE", "FLOWS", "FREE MEMORY", "HEALTH"}, rows); err != nil {
+		return err
+	}
+
+	if unhealthy > 0 {
+		return fmt.Errorf("%d of %d executors are unhealthy", unhealthy, len(checks))
+	}
+
+	return nil
+
+}
+
+func (c *cli) executorActivate(args []string) error {
+	return c.setExecutorActive(args, true)
+}
+
+func (c *cli) executorDeactivate(args []string) error {
+	return c.setExecutorActive(args, false)
+}
+
+// the web server reloads the executors, so the change takes effect at once
+func (c *cli) setExecutorActive(args []string, active bool) error {
+
+	args, err := c.parse(c.flags(), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	executor, err := findExecutor(client, args[0])
+	if err != nil {
+		return err
+	}
+
+	if active {
+		err = client.ActivateExecutor(executor)
+	} else {
+		err = client.DeactivateExecutor(executor)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = client.ReloadExecutors(); err != nil {
+		return err
+	}
+
+	executor.Active = active
+
+	return c.print(executor, []string{"ID", "HOST", "PORT", "ACTIVE"}, [][]string{{strconv.Itoa(executor.ID), executor.Host, strconv.Itoa(executor.Port), strconv.FormatBool(active)}})
+
+}
+
+func (c *cli) executorDrain(args []string) error {
+
+	flags := c.flags()
+	interval := flags.Duration("interval", 10*time.Second, "polling interval of the executions left")
+	timeout := flags.Duration("timeout", 0, "how long to wait for the executions, no limit by default")
+
+	args, err := c.parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	executor, err := findExecutor(client, args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	fmt.Fprintf(c.stderr, "draining executor %d\n", executor.ID)
+
+	if err = client.DrainExecutor(ctx, executor, *interval); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stderr, "executor %d is drained\n", executor.ID)
+
+	return nil
+
+}
+
+func (c *cli) executorReload(args []string) error {
+
+	if _, err := c.parse(c.flags(), args, 0, 0); err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	return client.ReloadExecutors()
+
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/wanglun/azkaban"
+)
+
+func (c *cli) flowList(args []string) error {
+
+	args, err := c.parse(c.flags(), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	flows, err := client.FetchFlows(args[0])
+	if err != nil {
+		return err
+	}
+
+	var rows [][]string
+	for _, flow := range flows.Flows {
+		rows = append(rows, []string{flow.IdFlow})
+	}
+
+	return c.print(flows, []string{"FLOW"}, rows)
+
+}
+
+func (c *cli) flowGraph(args []string) error {
+
+	flags := c.flags()
+	format := flags.String("format", "dot", "graph format: dot or mermaid")
+	execution := flags.Int64("exec", 0, "colour the nodes by their status in this execution")
+
+	args, err := c.parse(flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	if *format != "dot" && *format != "mermaid" {
+		return usageError("unknown graph format %s", *format)
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	jobs, err := client.FetchJobs(args[0], args[1])
+	if err != nil {
+		return err
+	}
+
+	var options azkaban.RenderOptions
+	if *execution != 0 {
+		if options.Execution, err = client.FetchExecutionFlow(*execution); err != nil {
+			return err
+		}
+	}
+
+	graph := azkaban.NewGraph(jobs)
+
+	// the graph is the output, json and yaml give the nodes
+	switch {
+	case c.format != "table":
+		return c.print(jobs, nil, nil)
+	case *format == "mermaid":
+		fmt.Fprint(c.stdout, azkaban.RenderMermaid(graph, options))
+	default:
+		fmt.Fprint(c.stdout, azkaban.RenderDOT(graph, options))
+	}
+
+	return nil
+
+}
@@ -0,0 +1,535 @@
+// Command azkaban manages projects, flows, executions and schedules of an Azkaban server.
+//
+//	azkaban login -endpoint https://azkaban:8443 -user azkaban
+//	azkaban login -profile prod
+//	azkaban logout
+//	azkaban project create|delete|list|upload|download|diff
+//	azkaban flow list|graph
+//	azkaban exec run|status|wait|cancel|logs
+//	azkaban schedule add|list|rm
+//	azkaban executor list|check|activate|deactivate|drain|reload
+//	azkaban apply -dry-run project.yaml
+//	azkaban migrate -to new -all-versions -state migration.json
+//	azkaban backfill -param 'date=${yyyy-MM-dd}' -parallel 4 -state backfill.json etl load 2030-01-01 2030-01-31
+//
+// Profiles are read from the config file of the azkaban package, ~/.config/azkaban/config.yaml.
+// The session of login is cached and used by the other commands. A command failing because the
+// session expired drops it from the cache and is not run again, the next command logs in again
+// when the password is given by the profile, $AZKABAN_PASS or ~/.netrc.
+//
+// Results are printed as a table, or as json or yaml with -o. The exit code is 0 on success, 1 on
+// errors and 2 on usage errors. The commands reporting an execution exit with 3 when it failed,
+// 4 when it was killed or cancelled and 5 when it is still running, backfill exits with 3 when a
+// date did not succeed.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/wanglun/azkaban"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+// exit codes
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+	exitFailed
+	exitKilled
+	exitRunning
+)
+
+// an error carrying the exit code of the command
+type codeError struct {
+	code int
+	err  error
+}
+
+func (e *codeError) Error() string {
+	return e.err.Error()
+}
+
+func usageError(format string, args ...interface{}) error {
+	return &codeError{code: exitUsage, err: fmt.Errorf(format, args...)}
+}
+
+// the exit code reporting an execution status
+func statusCode(status string) int {
+	switch status {
+	case azkaban.ExecutionStatus.Succeeded, azkaban.ExecutionStatus.Skipped:
+		return exitOK
+	case azkaban.ExecutionStatus.Failed:
+		return exitFailed
+	case azkaban.ExecutionStatus.Killed, azkaban.ExecutionStatus.Cancelled:
+		return exitKilled
+	}
+	return exitRunning
+}
+
+type command struct {
+	usage string
+	run   func(c *cli, args []string) error
+}
+
+var commands = map[string]map[string]command{
+	"login": {
+		"": {"login [-profile name] [-endpoint url] [-user name]", (*cli).login},
+	},
+	"logout": {
+		"": {"logout", (*cli).logout},
+	},
+	"apply": {
+		"": {"apply [-dry-run] <spec.yaml>", (*cli).apply},
+	},
+	"backfill": {
+		"": {"backfill [-param key=template]... [-granularity hourly|daily|weekly|monthly] [-parallel n] [-concurrent ignore|pipeline|skip] [-stop-on-failure] [-state file] <project> <flow> <from> <to>", (*cli).backfill},
+	},
+	"migrate": {
+		"": {"migrate -to <profile> [-all-versions] [-state file] [project]...", (*cli).migrate},
+	},
+	"project": {
+		"create":   {"project create [-description text] <project>", (*cli).projectCreate},
+		"delete":   {"project delete <project>", (*cli).projectDelete},
+		"list":     {"project list", (*cli).projectList},
+		"upload":   {"project upload <project> <dir|zip>", (*cli).projectUpload},
+		"download": {"project download [-version n] [-out file] <project>", (*cli).projectDownload},
+		"diff":     {"project diff [-version n] <project> <dir|zip>", (*cli).projectDiff},
+	},
+	"flow": {
+		"list":  {"flow list <project>", (*cli).flowList},
+		"graph": {"flow graph [-format dot|mermaid] [-exec id] <project> <flow>", (*cli).flowGraph},
+	},
+	"exec": {
+		"run":    {"exec run [-param key=value]... [-concurrent ignore|pipeline|skip] [-executor id] [-wait] <project> <flow>", (*cli).execRun},
+		"status": {"exec status <execid>", (*cli).execStatus},
+		"wait":   {"exec wait [-interval duration] <execid>", (*cli).execWait},
+		"cancel": {"exec cancel <execid>", (*cli).execCancel},
+		"logs":   {"exec logs [-follow] <execid> <job>", (*cli).execLogs},
+	},
+	"executor": {
+		"list":       {"executor list", (*cli).executorList},
+		"check":      {"executor check", (*cli).executorCheck},
+		"activate":   {"executor activate <executorid>", (*cli).executorActivate},
+		"deactivate": {"executor deactivate <executorid>", (*cli).executorDeactivate},
+		"drain":      {"executor drain [-interval duration] [-timeout duration] <executorid>", (*cli).executorDrain},
+		"reload":     {"executor reload", (*cli).executorReload},
+	},
+	"schedule": {
+		"add":  {"schedule add <project> <flow> <quartz cron>", (*cli).scheduleAdd},
+		"list": {"schedule list [project]", (*cli).scheduleList},
+		"rm":   {"schedule rm <scheduleid>", (*cli).scheduleRemove},
+	},
+}
+
+func usage(w io.Writer) {
+
+	var lines []string
+	for _, subcommands := range commands {
+		for _, c := range subcommands {
+			lines = append(lines, "  azkaban "+c.usage)
+		}
+	}
+	sort.Strings(lines)
+
+	fmt.Fprintf(w, "usage:\n%s\n\nevery command accepts -o table|json|yaml\n", strings.Join(lines, "\n"))
+
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+
+	subcommands, ok := commands[args[0]]
+	if !ok {
+		usage(stderr)
+		return exitUsage
+	}
+
+	name, rest := "", args[1:]
+	if _, single := subcommands[""]; !single {
+		if len(rest) == 0 {
+			usage(stderr)
+			return exitUsage
+		}
+		name, rest = rest[0], rest[1:]
+	}
+
+	cmd, ok := subcommands[name]
+	if !ok {
+		usage(stderr)
+		return exitUsage
+	}
+
+	c := &cli{stdout: stdout, stderr: stderr, usage: cmd.usage}
+
+	err := cmd.run(c, rest)
+
+	var exit *codeError
+	switch {
+	case err == nil:
+		return exitOK
+	case err == flag.ErrHelp:
+		return exitUsage
+	case errors.As(err, &exit):
+		if exit.err != nil {
+			fmt.Fprintf(stderr, "azkaban: %v\n", exit.err)
+		}
+		if exit.code == exitUsage {
+			fmt.Fprintf(stderr, "usage: azkaban %s\n", cmd.usage)
+		}
+		return exit.code
+	}
+
+	// the next command logs in again
+	if errors.Is(err, azkaban.SessionExpired) && c.target != nil {
+		sessions.Delete(c.target.Endpoint, c.target.User)
+		err = errors.New("session expired, run the command again or azkaban login")
+	}
+
+	fmt.Fprintf(stderr, "azkaban: %v\n", err)
+
+	return exitError
+
+}
+
+// the state of a command
+type cli struct {
+	stdout, stderr io.Writer
+	usage          string
+	format         string
+
+	// the target of the client, once created
+	target *target
+}
+
+// flags of a command, with the output format every command accepts
+func (c *cli) flags() *flag.FlagSet {
+
+	flags := flag.NewFlagSet("azkaban", flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.StringVar(&c.format, "o", "table", "output format: table, json or yaml")
+	flags.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: azkaban %s\n", c.usage)
+		flags.PrintDefaults()
+	}
+
+	return flags
+
+}
+
+// parse the flags of a command and check the number of its arguments, a negative max is no maximum
+func (c *cli) parse(flags *flag.FlagSet, args []string, min, max int) ([]string, error) {
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	switch c.format {
+	case "table", "json", "yaml":
+	default:
+		return nil, usageError("unknown output format %s", c.format)
+	}
+
+	if flags.NArg() < min || (max >= 0 && flags.NArg() > max) {
+		return nil, usageError("wrong number of arguments")
+	}
+
+	return flags.Args(), nil
+
+}
+
+// print a value as json or yaml, or its rows as a table
+func (c *cli) print(value interface{}, header []string, rows [][]string) error {
+
+	switch c.format {
+
+	case "json":
+		encoder := json.NewEncoder(c.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+
+	case "yaml":
+		// through json, so yaml keys are the json ones
+		body, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		var generic interface{}
+		if err = json.Unmarshal(body, &generic); err != nil {
+			return err
+		}
+		body, err = yaml.Marshal(generic)
+		if err != nil {
+			return err
+		}
+		_, err = c.stdout.Write(body)
+		return err
+
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	return w.Flush()
+
+}
+
+// the server and the user of the last login, their session is in the session cache
+type target struct {
+	Profile  string `json:"profile,omitempty"`
+	Endpoint string `json:"endpoint"`
+	User     string `json:"user"`
+}
+
+func targetFile() (string, error) {
+	dir, err := azkaban.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "login.json"), nil
+}
+
+// sessions are cached with the default settings
+var sessions = &azkaban.SessionCache{}
+
+// a client of the target and the credentials to log in with, set up by a profile of the config
+// file when the target has one
+func (t *target) connect() (*azkaban.Client, azkaban.CredentialProvider, error) {
+
+	endpoint := strings.TrimSuffix(t.Endpoint, "/")
+	if t.Profile == "" {
+		return azkaban.New(endpoint), azkaban.CredentialChain{azkaban.EnvCredentials{}, azkaban.NetrcCredentials{}}, nil
+	}
+
+	config, err := azkaban.LoadConfig("")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	profile, err := config.Profile(t.Profile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := profile.NewClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	client.Endpoint = endpoint
+
+	return client, profile.Credentials(), nil
+
+}
+
+// a client with the cached session of the last login, logged in again when the cache has none
+// and the credentials are at hand
+func (c *cli) client() (*azkaban.Client, error) {
+
+	name, err := targetFile()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil, errors.New("not logged in, run azkaban login first")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.target = &target{}
+	if err = json.Unmarshal(body, c.target); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	client, credentials, err := c.target.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.Login(credentials, c.target.User, sessions)
+	if err == azkaban.CredentialsNotFound {
+		return nil, errors.New("session expired, run azkaban login again")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+
+}
+
+// asks the password of a user on the terminal, without echoing it
+type promptCredentials struct {
+	stderr io.Writer
+}
+
+func (p promptCredentials) Credentials(endpoint, username string) (string, string, error) {
+
+	if username == "" {
+		return "", "", azkaban.CredentialsNotFound
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", "", fmt.Errorf("no terminal to ask the password of %s, set $%s, ~/.netrc or the password of a profile", username, azkaban.ConfigEnv.Password)
+	}
+
+	fmt.Fprintf(p.stderr, "password of %s: ", username)
+
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(p.stderr)
+	if err != nil {
+		return "", "", err
+	}
+
+	return username, string(password), nil
+
+}
+
+func (c *cli) login(args []string) error {
+
+	flags := c.flags()
+	profile := flags.String("profile", "", "profile of the config file to log in with")
+	endpoint := flags.String("endpoint", "", "url of the azkaban server, $AZKABAN_ENDPOINT by default")
+	user := flags.String("user", "", "user name, $AZKABAN_USER by default")
+
+	if _, err := c.parse(flags, args, 0, 0); err != nil {
+		return err
+	}
+
+	// flags take precedence over the profile, which takes the environment into account
+	t := target{Profile: *profile, Endpoint: *endpoint, User: *user}
+	if t.Profile != "" {
+		config, err := azkaban.LoadConfig("")
+		if err != nil {
+			return err
+		}
+		p, err := config.Profile(t.Profile)
+		if err != nil {
+			return err
+		}
+		if t.Endpoint == "" {
+			t.Endpoint = p.Endpoint
+		}
+		if t.User == "" {
+			t.User = p.Username
+		}
+	}
+	if t.Endpoint == "" {
+		t.Endpoint = os.Getenv(azkaban.ConfigEnv.Endpoint)
+	}
+	if t.User == "" {
+		t.User = os.Getenv(azkaban.ConfigEnv.User)
+	}
+	t.Endpoint = strings.TrimSuffix(t.Endpoint, "/")
+
+	if t.Endpoint == "" {
+		return usageError("the endpoint is required")
+	}
+
+	client, credentials, err := t.connect()
+	if err != nil {
+		return err
+	}
+
+	// always a new session, asking the password when no provider has it
+	if err = sessions.Delete(t.Endpoint, t.User); err != nil {
+		return err
+	}
+
+	credentials = azkaban.CredentialChain{credentials, promptCredentials{c.stderr}}
+	if err = client.Login(credentials, t.User, sessions); err == azkaban.CredentialsNotFound {
+		return usageError("the user is required")
+	}
+	if err != nil {
+		return err
+	}
+
+	name, err := targetFile()
+	if err != nil {
+		return err
+	}
+
+	body, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+		return err
+	}
+
+	if err = ioutil.WriteFile(name, body, 0600); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stderr, "logged in to %s\n", t.Endpoint)
+
+	return nil
+
+}
+
+func (c *cli) logout(args []string) error {
+
+	if _, err := c.parse(c.flags(), args, 0, 0); err != nil {
+		return err
+	}
+
+	name, err := targetFile()
+	if err != nil {
+		return err
+	}
+
+	body, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var t target
+	if err = json.Unmarshal(body, &t); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	if err = sessions.Delete(t.Endpoint, t.User); err != nil {
+		return err
+	}
+
+	return os.Remove(name)
+
+}
+
+// format a time for tables, "-" when it is not known
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wanglun/azkaban"
+	"github.com/wanglun/azkaban/azkabantest"
+)
+
+// run a command, returning its exit code and output
+func azkabanRun(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCLI(t *testing.T) {
+
+	server := azkabantest.NewServer()
+	defer server.Close()
+
+	// the session is saved in a temporary config dir
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AZKABAN_PASS", azkabantest.DefaultPassword)
+
+	code, _, _ := azkabanRun("project", "list")
+	assert.Equal(t, exitError, code)
+
+	code, _, _ = azkabanRun("login", "-endpoint", server.URL, "-user", azkabantest.DefaultUser)
+	assert.Equal(t, exitOK, code)
+
+	// a profile of the config file
+	config := "profiles:\n  fake:\n    endpoint: " + server.URL + "\n    username: azkaban\n"
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "azkaban"), 0700))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "azkaban", "config.yaml"), []byte(config), 0600))
+
+	code, _, stderr := azkabanRun("login", "-profile", "fake")
+	assert.Equal(t, exitOK, code, stderr)
+
+	code, _, _ = azkabanRun("login", "-profile", "missing")
+	assert.Equal(t, exitError, code)
+
+	code, _, _ = azkabanRun("project", "create", "cli")
+	assert.Equal(t, exitOK, code)
+
+	code, _, stderr = azkabanRun("project", "upload", "cli", filepath.Join("..", "..", "testdata", "testflow"))
+	assert.Equal(t, exitOK, code, stderr)
+
+	code, stdout, _ := azkabanRun("project", "diff", "cli", filepath.Join("..", "..", "testdata", "testflow"))
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "no changes\n", stdout)
+
+	code, stdout, _ = azkabanRun("flow", "list", "-o", "json", "cli")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, `"bar"`)
+
+	code, stdout, _ = azkabanRun("exec", "run", "-wait", "-interval", "1ms", "cli", "bar")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "SUCCEEDED")
+
+	server.FailJob("cli", "foo")
+
+	code, stdout, _ = azkabanRun("exec", "run", "-o", "json", "cli", "bar")
+	assert.Equal(t, exitOK, code)
+
+	var execute azkaban.Execute
+	assert.Nil(t, json.Unmarshal([]byte(stdout), &execute))
+
+	id := execute.IdExecution
+	code, _, _ = azkabanRun("exec", "wait", "-interval", "1ms", strconv.FormatInt(id, 10))
+	assert.Equal(t, exitFailed, code)
+
+	code, stdout, _ = azkabanRun("exec", "logs", strconv.FormatInt(id, 10), "foo")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "hello foo")
+
+	code, stdout, _ = azkabanRun("schedule", "add", "-o", "json", "cli", "bar", "0 0 1 ? * *")
+	assert.Equal(t, exitOK, code)
+
+	var schedule azkaban.Schedule
+	assert.Nil(t, json.Unmarshal([]byte(stdout), &schedule))
+
+	code, stdout, _ = azkabanRun("schedule", "list", "-o", "yaml")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "0 0 1 ? * *")
+
+	code, _, _ = azkabanRun("schedule", "rm", strconv.Itoa(schedule.ID))
+	assert.Equal(t, exitOK, code)
+
+	// expired sessions are dropped, the next command logs in again
+	server.ExpireSessions()
+	code, _, stderr = azkabanRun("project", "list")
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "session expired")
+	code, _, _ = azkabanRun("project", "list")
+	assert.Equal(t, exitOK, code)
+
+	code, _, _ = azkabanRun("exec", "status", "nope")
+	assert.Equal(t, exitUsage, code)
+
+	code, _, _ = azkabanRun("project", "delete", "cli")
+	assert.Equal(t, exitOK, code)
+
+	// a project spec
+	spec := filepath.Join(dir, "project.yaml")
+	source, _ := filepath.Abs(filepath.Join("..", "..", "testdata", "testflow"))
+	assert.Nil(t, ioutil.WriteFile(spec, []byte("name: applied\nsource: "+source+"\nproxy_users: [etl]\n"), 0600))
+
+	code, stdout, _ = azkabanRun("apply", "-dry-run", spec)
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "+ project applied")
+	code, _, _ = azkabanRun("apply", spec)
+	assert.Equal(t, exitOK, code)
+	code, stdout, _ = azkabanRun("apply", spec)
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "project applied is up to date\n", stdout)
+
+	// migrated to the server of another profile
+	other := azkabantest.NewServer()
+	defer other.Close()
+
+	config += "  other:\n    endpoint: " + other.URL + "\n    username: azkaban\n"
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "azkaban", "config.yaml"), []byte(config), 0600))
+
+	code, _, _ = azkabanRun("migrate", "applied")
+	assert.Equal(t, exitUsage, code)
+	code, stdout, stderr = azkabanRun("migrate", "-to", "other", "-state", filepath.Join(dir, "migration.json"), "applied")
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, "project applied: 1 versions, 0 permissions, 1 proxy users, 0 schedules\n", stdout)
+
+	// a backfill of two days
+	code, stdout, stderr = azkabanRun("backfill", "-interval", "1ms", "-param", "date=${yyyy-MM-dd}", "applied", "bar", "2030-01-01", "2030-01-02")
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "backfill of applied.bar: 2 succeeded, 0 failed, 0 pending\n")
+	code, _, _ = azkabanRun("backfill", "applied", "bar", "2030-01-02", "yesterday")
+	assert.Equal(t, exitUsage, code)
+
+	// executors of a multi-executor cluster
+	executor := strconv.Itoa(server.AddExecutor().ID)
+
+	code, stdout, _ = azkabanRun("executor", "list")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "127.0.0.1")
+	code, stdout, _ = azkabanRun("executor", "check")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, "ok")
+	code, stdout, stderr = azkabanRun("exec", "run", "-executor", executor, "-wait", "-interval", "1ms", "applied", "bar")
+	assert.Equal(t, exitOK, code, stderr)
+	code, _, stderr = azkabanRun("executor", "drain", "-interval", "1ms", executor)
+	assert.Equal(t, exitOK, code, stderr)
+	code, stdout, _ = azkabanRun("executor", "activate", "-o", "json", executor)
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout, `"active": true`)
+	code, _, _ = azkabanRun("executor", "deactivate", "999")
+	assert.Equal(t, exitError, code)
+
+	code, _, _ = azkabanRun("logout")
+	assert.Equal(t, exitOK, code)
+	code, _, _ = azkabanRun("project", "list")
+	assert.Equal(t, exitError, code)
+
+}
+
+func TestExecLogsFollow(t *testing.T) {
+
+	// the job writes its last line as it finishes, after the log was read to its end
+	log, finished := "start\n", false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		switch {
+		case r.FormValue("action") == "login":
+			response = map[string]string{"session.id": "session", "status": "success"}
+		case r.FormValue("ajax") == "fetchexecflow":
+			if !finished {
+				log, finished = log+"end\n", true
+			}
+			response = map[string]interface{}{"execid": 1, "status": "SUCCEEDED", "nodes": []map[string]string{{"id": "foo", "status": "SUCCEEDED"}}}
+		case r.FormValue("ajax") == "fetchExecJobLogs":
+			offset, _ := strconv.Atoi(r.FormValue("offset"))
+			response = map[string]interface{}{"data": log[offset:], "offset": offset, "length": len(log) - offset}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AZKABAN_PASS", "password")
+
+	code, _, stderr := azkabanRun("login", "-endpoint", server.URL, "-user", "azkaban")
+	assert.Equal(t, exitOK, code, stderr)
+
+	code, stdout, stderr := azkabanRun("exec", "logs", "-follow", "-interval", "1ms", "1", "foo")
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, "start\nend\n", stdout)
+
+}
+
+func TestLoginWithoutTerminal(t *testing.T) {
+
+	server := azkabantest.NewServer()
+	defer server.Close()
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AZKABAN_PASS", "")
+
+	// the password is not read from a pipe, where it would be echoed
+	stdin, input, err := os.Pipe()
+	assert.Nil(t, err)
+	defer input.Close()
+	defer func(previous *os.File) { os.Stdin = previous }(os.Stdin)
+	os.Stdin = stdin
+
+	_, err = input.WriteString(azkabantest.DefaultPassword + "\n")
+	assert.Nil(t, err)
+
+	code, _, stderr := azkabanRun("login", "-endpoint", server.URL, "-user", azkabantest.DefaultUser)
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr, "no terminal to ask the password of azkaban")
+
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/wanglun/azkaban"
+)
+
+func (c *cli) migrate(args []string) error {
+
+	flags := c.flags()
+	to := flags.String("to", "", "profile of the server to migrate to")
+	allVersions := flags.Bool("all-versions", false, "upload every version of the archives, not only the latest")
+	state := flags.String("state", "", "file keeping the progress, to resume an interrupted migration")
+
+	projects, err := c.parse(flags, args, 0, -1)
+	if err != nil {
+		return err
+	}
+	if *to == "" {
+		return usageError("-to is required")
+	}
+
+	source, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	target, err := azkaban.NewFromProfile(*to)
+	if err != nil {
+		return err
+	}
+
+	report, err := azkaban.Migrate(source, target, azkaban.MigrateOptions{Projects: projects, AllVersions: *allVersions, StateFile: *state})
+	if report == nil {
+		return err
+	}
+
+	// the report is printed even when projects failed, to show what is left to migrate
+	if c.format == "table" {
+		fmt.Fprint(c.stdout, report.String())
+	} else if perr := c.print(report, nil, nil); err == nil {
+		err = perr
+	}
+
+	return err
+
+}
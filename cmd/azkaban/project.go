@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/wanglun/azkaban"
+)
+
+func (c *cli) projectCreate(args []string) error {
+
+	flags := c.flags()
+	description := flags.String("description", "", "description of the project")
+
+	args, err := c.parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	object, err := client.CreateProject(args[0], *description)
+	if err != nil {
+		return err
+	}
+
+	return c.print(object, []string{"PROJECT", "STATUS"}, [][]string{{object.Project, object.Status}})
+
+}
+
+func (c *cli) projectDelete(args []string) error {
+
+	args, err := c.parse(c.flags(), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	object, err := client.DeleteProject(args[0])
+	if err != nil {
+		return err
+	}
+
+	return c.print(object, []string{"PROJECT", "STATUS"}, [][]string{{object.Project, object.Status}})
+
+}
+
+func (c *cli) projectList(args []string) error {
+
+	if _, err := c.parse(c.flags(), args, 0, 0); err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	projects, err := client.FetchProjects()
+	if err != nil {
+		return err
+	}
+
+	var rows [][]string
+	for _, p := range projects {
+		rows = append(rows, []string{strconv.Itoa(p.ID), p.Name, p.CreatedBy, formatTime(p.CreatedAt)})
+	}
+
+	return c.print(projects, []string{"ID", "PROJECT", "CREATED BY", "CREATED"}, rows)
+
+}
+
+func (c *cli) projectUpload(args []string) error {
+
+	args, err := c.parse(c.flags(), args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	project, source := args[0], args[1]
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+
+	// directories are packaged in memory
+	var (
+		archive  io.Reader
+		filename = filepath.Base(source)
+	)
+
+	if info.IsDir() {
+		var buffer bytes.Buffer
+		if err = azkaban.PackageDir(&buffer, source, azkaban.PackageOptions{}); err != nil {
+			return err
+		}
+		archive, filename = &buffer, project+".zip"
+	} else {
+		f, err := os.Open(source)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		archive = f
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	upload, err := client.UploadProject(context.Background(), project, archive, filename, nil)
+	if err != nil {
+		return err
+	}
+
+	return c.print(upload, []string{"PROJECT", "ID", "VERSION"}, [][]string{{project, strconv.Itoa(upload.IdProject), upload.Version}})
+
+}
+
+func (c *cli) projectDownload(args []string) error {
+
+	flags := c.flags()
+	version := flags.Int("version", 0, "version to download, the latest by default")
+	out := flags.String("out", "", "archive to write, <project>.zip by default, - for stdout")
+
+	args, err := c.parse(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	project := args[0]
+	if *out == "" {
+		*out = project + ".zip"
+	}
+
+	if *out == "-" {
+		return client.DownloadProject(context.Background(), project, *version, c.stdout)
+	}
+
+	// downloaded next to the archive, so a failed download leaves no partial archive
+	f, err := ioutil.TempFile(filepath.Dir(*out), ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	err = client.DownloadProject(context.Background(), project, *version, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = os.Rename(f.Name(), *out); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stderr, "downloaded %s\n", *out)
+
+	return nil
+
+}
+
+func (c *cli) projectDiff(args []string) error {
+
+	flags := c.flags()
+	version := flags.Int("version", 0, "deployed version to compare with, the latest by default")
+
+	args, err := c.parse(flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	diff, err := client.DiffProject(args[1], args[0], *version)
+	if err != nil {
+		return err
+	}
+
+	if c.format == "table" {
+		fmt.Fprint(c.stdout, diff.String())
+		return nil
+	}
+
+	return c.print(diff, nil, nil)
+
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/wanglun/azkaban"
+)
+
+func (c *cli) scheduleAdd(args []string) error {
+
+	args, err := c.parse(c.flags(), args, 3, 3)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	id, err := client.ScheduleCronFlow(args[0], args[1], args[2])
+	if err != nil {
+		return err
+	}
+
+	schedule := azkaban.Schedule{ID: id, Project: args[0], Flow: args[1], Cron: args[2]}
+
+	return c.print(schedule, []string{"ID", "PROJECT", "FLOW", "CRON"}, [][]string{{strconv.Itoa(id), args[0], args[1], args[2]}})
+
+}
+
+func (c *cli) scheduleList(args []string) error {
+
+	args, err := c.parse(c.flags(), args, 0, 1)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	// every project when none is given
+	projects := args
+	if len(projects) == 0 {
+		summaries, err := client.FetchProjects()
+		if err != nil {
+			return err
+		}
+		for _, p := range summaries {
+			projects = append(projects, p.Name)
+		}
+	}
+
+	schedules := []azkaban.Schedule{}
+	for _, project := range projects {
+		found, err := client.FetchSchedules(project)
+		if err != nil {
+			return err
+		}
+		schedules = append(schedules, found...)
+	}
+
+	var rows [][]string
+	for _, s := range schedules {
+		when := s.Cron
+		if when == "" {
+			when = fmt.Sprintf("%s every %s", s.FirstSchedAt, s.Period)
+		}
+		rows = append(rows, []string{strconv.Itoa(s.ID), s.Project, s.Flow, when, s.NextExecAt, s.User})
+	}
+
+	return c.print(schedules, []string{"ID", "PROJECT", "FLOW", "SCHEDULE", "NEXT", "USER"}, rows)
+
+}
+
+func (c *cli) scheduleRemove(args []string) error {
+
+	args, err := c.parse(c.flags(), args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return usageError("invalid schedule id %s", args[0])
+	}
+
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	if err = client.RemoveSchedule(id); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stderr, "schedule %d removed\n", id)
+
+	return nil
+
+}
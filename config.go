@@ -0,0 +1,343 @@
+package azkaban
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ProfileNotFound = errors.New("Profile not found")
+
+// environment variables overriding the config file
+var ConfigEnv = struct {
+	Config   string
+	Profile  string
+	Endpoint string
+	User     string
+	Password string
+}{
+	Config:   "AZKABAN_CONFIG",
+	Profile:  "AZKABAN_PROFILE",
+	Endpoint: "AZKABAN_ENDPOINT",
+	User:     "AZKABAN_USER",
+	Password: "AZKABAN_PASS",
+}
+
+// Config is the content of the config file, a set of named profiles, one per azkaban cluster.
+//
+//	default: dev
+//	profiles:
+//	  dev:
+//	    endpoint: https://azkaban.dev:8443
+//	    username: azkaban
+//	    password_env: AZKABAN_DEV_PASS
+//	    tls:
+//	      insecure: true
+//	  staging:
+//	    endpoint: https://azkaban.staging:8443
+//	    username: deploy
+//	    credential_command: [git, credential-osxkeychain]
+//	  prod:
+//	    endpoint: https://azkaban.prod:8443
+//	    username: deploy
+//	    password_file: ~/.config/azkaban/prod.pass
+//	    timezone: Asia/Shanghai
+//	    tls:
+//	      ca: /etc/ssl/azkaban-ca.pem
+//	    defaults:
+//	      failure_emails: [oncall@example.com]
+type Config struct {
+	Default  string              `yaml:"default"`
+	Profiles map[string]*Profile `yaml:"profiles"`
+}
+
+// Profile is how to reach and log into an azkaban cluster.
+type Profile struct {
+	Name     string `yaml:"-"`
+	Endpoint string `yaml:"endpoint"`
+	Username string `yaml:"username"`
+
+	// the password, read from the first source set, from $AZKABAN_PASS or ~/.netrc when none is
+	Password          string   `yaml:"password"`
+	PasswordEnv       string   `yaml:"password_env"`
+	PasswordFile      string   `yaml:"password_file"`
+	Netrc             bool     `yaml:"netrc"`
+	CredentialCommand []string `yaml:"credential_command"`
+
+	// Timezone is the time zone of the server, see Client.Location
+	Timezone string        `yaml:"timezone"`
+	Timeout  time.Duration `yaml:"timeout"`
+	TLS      TLSConfig     `yaml:"tls"`
+
+	Defaults ExecutionDefaults `yaml:"defaults"`
+}
+
+// TLSConfig sets how the server certificate is verified, and the client certificate to present.
+// Unlike a Client without HTTPClient, the server certificate is verified unless Insecure is set.
+type TLSConfig struct {
+	Insecure   bool   `yaml:"insecure"`
+	CA         string `yaml:"ca"`
+	Cert       string `yaml:"cert"`
+	Key        string `yaml:"key"`
+	ServerName string `yaml:"server_name"`
+}
+
+// ExecutionDefaults are applied by ExecuteFlow to every execution, flow overrides given to
+// ExecuteFlow take precedence over the default ones.
+type ExecutionDefaults struct {
+	FailureEmails []string          `yaml:"failure_emails"`
+	SuccessEmails []string          `yaml:"success_emails"`
+	FlowOverrides map[string]string `yaml:"flow_overrides"`
+}
+
+// ConfigDir is the directory of the azkaban config, $XDG_CONFIG_HOME/azkaban or ~/.config/azkaban.
+func ConfigDir() (string, error) {
+
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "azkaban"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".config", "azkaban"), nil
+
+}
+
+// ConfigPath is the config file, $AZKABAN_CONFIG or config.yaml in ConfigDir.
+func ConfigPath() (string, error) {
+
+	if path := os.Getenv(ConfigEnv.Config); path != "" {
+		return path, nil
+	}
+
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "config.yaml"), nil
+
+}
+
+// LoadConfig reads a config file, ConfigPath when path is empty. A missing default config file
+// gives an empty config, so profiles can come from the environment only.
+func LoadConfig(path string) (*Config, error) {
+
+	// init return
+	config := &Config{}
+
+	explicit := path != ""
+	if !explicit {
+		var err error
+		if path, err = ConfigPath(); err != nil {
+			return nil, err
+		}
+		explicit = os.Getenv(ConfigEnv.Config) != ""
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && !explicit {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = yaml.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	for name, profile := range config.Profiles {
+		if profile == nil {
+			return nil, fmt.Errorf("%s: profile %s is empty", path, name)
+		}
+		profile.Name = name
+	}
+
+	return config, nil
+
+}
+
+// Profile returns a profile with the environment overrides applied. The profile is name, or
+// $AZKABAN_PROFILE, the default of the config or "default" when name is empty. ProfileNotFound
+// is returned when neither the config nor the environment give an endpoint and a username.
+func (this *Config) Profile(name string) (*Profile, error) {
+
+	if name == "" {
+		name = os.Getenv(ConfigEnv.Profile)
+	}
+	if name == "" {
+		name = this.Default
+	}
+	explicit := name != ""
+	if !explicit {
+		name = "default"
+	}
+
+	// a copy, so overrides do not leak into the config
+	profile := Profile{Name: name}
+	if p, ok := this.Profiles[name]; ok {
+		profile = *p
+	} else if explicit {
+		return nil, fmt.Errorf("%w: %s", ProfileNotFound, name)
+	}
+
+	if endpoint := os.Getenv(ConfigEnv.Endpoint); endpoint != "" {
+		profile.Endpoint = endpoint
+	}
+	if user := os.Getenv(ConfigEnv.User); user != "" {
+		profile.Username = user
+	}
+	if password := os.Getenv(ConfigEnv.Password); password != "" {
+		profile.Password = password
+	}
+
+	if profile.Endpoint == "" || profile.Username == "" {
+		return nil, fmt.Errorf("%w: %s has no endpoint or username", ProfileNotFound, name)
+	}
+
+	profile.Endpoint = strings.TrimSuffix(profile.Endpoint, "/")
+
+	return &profile, nil
+
+}
+
+// Credentials returns the provider of the password of the profile.
+func (this *Profile) Credentials() CredentialProvider {
+
+	switch {
+	case this.Password != "":
+		return StaticCredentials{Username: this.Username, Password: this.Password}
+	case this.PasswordEnv != "":
+		return EnvCredentials{PasswordVar: this.PasswordEnv}
+	case this.PasswordFile != "":
+		return FileCredentials{Path: this.PasswordFile}
+	case this.Netrc:
+		return NetrcCredentials{}
+	case len(this.CredentialCommand) > 0:
+		return CommandCredentials{Command: this.CredentialCommand}
+	}
+
+	return CredentialChain{EnvCredentials{}, NetrcCredentials{}}
+
+}
+
+// Secret returns the password of the profile from its source.
+func (this *Profile) Secret() (string, error) {
+
+	_, password, err := this.Credentials().Credentials(this.Endpoint, this.Username)
+	if err == CredentialsNotFound {
+		return "", fmt.Errorf("profile %s: no password found", this.Name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("profile %s: %v", this.Name, err)
+	}
+
+	return password, nil
+
+}
+
+// NewClient creates a client for the profile, without logging in.
+func (this *Profile) NewClient() (*Client, error) {
+
+	client := New(this.Endpoint)
+	client.Defaults = this.Defaults
+
+	if this.Timezone != "" {
+		location, err := time.LoadLocation(this.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %v", this.Name, err)
+		}
+		client.Location = location
+	}
+
+	config, err := this.TLS.config()
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %v", this.Name, err)
+	}
+
+	client.HTTPClient = &http.Client{
+		Timeout:   this.Timeout,
+		Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: config},
+	}
+
+	return client, nil
+
+}
+
+func (this TLSConfig) config() (*tls.Config, error) {
+
+	config := &tls.Config{InsecureSkipVerify: this.Insecure, ServerName: this.ServerName}
+
+	if this.CA != "" {
+		pem, err := ioutil.ReadFile(expandHome(this.CA))
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificate found", this.CA)
+		}
+	}
+
+	if this.Cert != "" || this.Key != "" {
+		certificate, err := tls.LoadX509KeyPair(expandHome(this.Cert), expandHome(this.Key))
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+
+}
+
+// NewFromProfile creates a client logged in with a profile of the config file, see Config.Profile.
+func NewFromProfile(name string) (*Client, error) {
+
+	config, err := LoadConfig("")
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := config.Profile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := profile.NewClient()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = client.Login(profile.Credentials(), profile.Username, nil); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", profile.Name, err)
+	}
+
+	return client, nil
+
+}
+
+// expand a leading ~ to the home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
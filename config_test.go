@@ -0,0 +1,136 @@
+package azkaban_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wanglun/azkaban"
+	"github.com/wanglun/azkaban/azkabantest"
+)
+
+// a config in a temporary dir, with the environment cleared
+func writeConfig(t *testing.T, content string) string {
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+	for _, name := range []string{"AZKABAN_PROFILE", "AZKABAN_ENDPOINT", "AZKABAN_USER", "AZKABAN_PASS"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("AZKABAN_CONFIG", path)
+
+	return dir
+
+}
+
+func TestConfigProfile(t *testing.T) {
+
+	dir := writeConfig(t, `
+default: dev
+profiles:
+  dev:
+    endpoint: https://azkaban.dev:8443/
+    username: azkaban
+    password_env: DEV_PASS
+  prod:
+    endpoint: https://azkaban.prod:8443
+    username: deploy
+    password_file: prod.pass
+    timezone: Asia/Shanghai
+    timeout: 30s
+    defaults:
+      failure_emails: [oncall@example.com]
+`)
+
+	config, err := azkaban.LoadConfig("")
+	assert.Nil(t, err)
+
+	// the default profile
+	profile, err := config.Profile("")
+	assert.Nil(t, err)
+	assert.Equal(t, "dev", profile.Name)
+	assert.Equal(t, "https://azkaban.dev:8443", profile.Endpoint)
+
+	_, err = profile.Secret()
+	assert.NotNil(t, err)
+
+	t.Setenv("DEV_PASS", "secret")
+	password, err := profile.Secret()
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", password)
+
+	// a named profile
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "prod.pass"), []byte("prod\n"), 0600))
+	profile, err = config.Profile("prod")
+	assert.Nil(t, err)
+	profile.PasswordFile = filepath.Join(dir, profile.PasswordFile)
+	password, err = profile.Secret()
+	assert.Nil(t, err)
+	assert.Equal(t, "prod", password)
+
+	client, err := profile.NewClient()
+	assert.Nil(t, err)
+	assert.Equal(t, "Asia/Shanghai", client.Location.String())
+	assert.Equal(t, []string{"oncall@example.com"}, client.Defaults.FailureEmails)
+
+	// environment overrides
+	t.Setenv("AZKABAN_PROFILE", "prod")
+	t.Setenv("AZKABAN_USER", "someone")
+	t.Setenv("AZKABAN_PASS", "overridden")
+	profile, err = config.Profile("")
+	assert.Nil(t, err)
+	assert.Equal(t, "prod", profile.Name)
+	assert.Equal(t, "someone", profile.Username)
+	password, err = profile.Secret()
+	assert.Nil(t, err)
+	assert.Equal(t, "overridden", password)
+
+	// the config is left untouched
+	assert.Equal(t, "deploy", config.Profiles["prod"].Username)
+
+	_, err = config.Profile("staging")
+	assert.ErrorIs(t, err, azkaban.ProfileNotFound)
+
+}
+
+func TestNewFromProfile(t *testing.T) {
+
+	server := azkabantest.NewServer()
+	defer server.Close()
+
+	writeConfig(t, `
+profiles:
+  default:
+    endpoint: `+server.URL+`
+    username: azkaban
+    password: azkaban
+    defaults:
+      failure_emails: [oncall@example.com]
+      flow_overrides:
+        test.p1: default
+`)
+
+	client, err := azkaban.NewFromProfile("")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, client.Session)
+
+	_, err = client.CreateProject(PROJECT_NAME, PROJECT_DESC)
+	assert.Nil(t, err)
+	assert.Nil(t, client.UploadProjectZip(PROJECT_NAME, FLOW_ZIP_PATH))
+
+	// defaults are applied to executions
+	execute, err := client.ExecuteFlow(PROJECT_NAME, "bar", azkaban.ConcurrentOptionDefault, map[string]string{"test.p2": "given"})
+	assert.Nil(t, err)
+
+	options, err := client.FetchExecutionOptions(execute.IdExecution)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"oncall@example.com"}, options.FailureEmails)
+	assert.Equal(t, map[string]string{"test.p1": "default", "test.p2": "given"}, options.FlowParameters)
+
+	_, err = azkaban.NewFromProfile("prod")
+	assert.ErrorIs(t, err, azkaban.ProfileNotFound)
+
+}
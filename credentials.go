@@ -0,0 +1,556 @@
+package azkaban
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+var CredentialsNotFound = errors.New("Credentials not found")
+
+// CredentialProvider gives the credentials to log into azkaban.
+type CredentialProvider interface {
+	// Credentials returns the user name and the password to log into endpoint. username is the
+	// user wanted, the provider chooses one when it is empty. CredentialsNotFound is returned
+	// when the provider has no credentials for them.
+	Credentials(endpoint, username string) (string, string, error)
+}
+
+// StaticCredentials are credentials known beforehand.
+type StaticCredentials struct {
+	Username string
+	Password string
+}
+
+func (this StaticCredentials) Credentials(endpoint, username string) (string, string, error) {
+
+	if this.Password == "" || (username != "" && this.Username != "" && username != this.Username) {
+		return "", "", CredentialsNotFound
+	}
+
+	if username == "" {
+		username = this.Username
+	}
+
+	return username, this.Password, nil
+
+}
+
+// EnvCredentials reads the credentials from environment variables, $AZKABAN_USER and
+// $AZKABAN_PASS unless other variables are set. The password is only given to the user of the
+// user variable, or to any user when it is not set.
+type EnvCredentials struct {
+	UserVar     string
+	PasswordVar string
+}
+
+func (this EnvCredentials) Credentials(endpoint, username string) (string, string, error) {
+
+	userVar, passwordVar := this.UserVar, this.PasswordVar
+	if userVar == "" {
+		userVar = ConfigEnv.User
+	}
+	if passwordVar == "" {
+		passwordVar = ConfigEnv.Password
+	}
+
+	password := os.Getenv(passwordVar)
+	if password == "" {
+		return "", "", CredentialsNotFound
+	}
+
+	if user := os.Getenv(userVar); user != "" {
+		if username != "" && username != user {
+			return "", "", CredentialsNotFound
+		}
+		username = user
+	}
+
+	return username, password, nil
+
+}
+
+// FileCredentials reads the credentials from a file only its owner can read. The file holds
+// either the password on its first line, or username= and password= lines.
+type FileCredentials struct {
+	Path string
+}
+
+func (this FileCredentials) Credentials(endpoint, username string) (string, string, error) {
+
+	path := expandHome(this.Path)
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", "", CredentialsNotFound
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	// group and other permissions are meaningless on windows
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return "", "", fmt.Errorf("%s can be read by other users, its permissions must be 0600", path)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	attributes, err := readCredentialAttributes(bytes.NewReader(content))
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %v", path, err)
+	}
+
+	if password, ok := attributes["password"]; ok {
+		if user := attributes["username"]; user != "" {
+			if username != "" && username != user {
+				return "", "", CredentialsNotFound
+			}
+			username = user
+		}
+		return username, password, nil
+	}
+
+	// a password file
+	password := strings.SplitN(string(content), "\n", 2)[0]
+	password = strings.TrimSuffix(password, "\r")
+	if password == "" {
+		return "", "", CredentialsNotFound
+	}
+
+	return username, password, nil
+
+}
+
+// NetrcCredentials looks the host of the endpoint up in a .netrc file, $NETRC or ~/.netrc
+// unless a path is set.
+type NetrcCredentials struct {
+	Path string
+}
+
+func (this NetrcCredentials) Credentials(endpoint, username string) (string, string, error) {
+
+	path := this.Path
+	if path == "" {
+		path = os.Getenv("NETRC")
+	}
+	if path == "" {
+		path = "~/.netrc"
+	}
+
+	content, err := ioutil.ReadFile(expandHome(path))
+	if os.IsNotExist(err) {
+		return "", "", CredentialsNotFound
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	host, err := endpointHost(endpoint)
+	if err != nil {
+		return "", "", err
+	}
+
+	// the first entry of the host, then the default entry
+	var matched, fallback *netrcEntry
+	for _, entry := range parseNetrc(string(content)) {
+		entry := entry
+		if username != "" && entry.login != "" && entry.login != username {
+			continue
+		}
+		if entry.machine == host && matched == nil {
+			matched = &entry
+		}
+		if entry.machine == "" && fallback == nil {
+			fallback = &entry
+		}
+	}
+
+	if matched == nil {
+		matched = fallback
+	}
+	if matched == nil || matched.password == "" {
+		return "", "", CredentialsNotFound
+	}
+
+	if username == "" {
+		username = matched.login
+	}
+
+	return username, matched.password, nil
+
+}
+
+// an entry of a .netrc file, machine is empty for the default entry
+type netrcEntry struct {
+	machine, login, password string
+}
+
+func parseNetrc(content string) []netrcEntry {
+
+	var (
+		entries []netrcEntry
+		entry   *netrcEntry
+	)
+
+	lines := strings.Split(content, "\n")
+
+	for i := 0; i < len(lines); i++ {
+
+		fields := strings.Fields(lines[i])
+
+		for j := 0; j < len(fields); j++ {
+
+			// the value following a keyword
+			value := func() string {
+				j++
+				if j < len(fields) {
+					return fields[j]
+				}
+				return ""
+			}
+
+			switch fields[j] {
+			case "machine":
+				entries = append(entries, netrcEntry{machine: value()})
+				entry = &entries[len(entries)-1]
+			case "default":
+				entries = append(entries, netrcEntry{})
+				entry = &entries[len(entries)-1]
+			case "login":
+				if login := value(); entry != nil {
+					entry.login = login
+				}
+			case "password":
+				if password := value(); entry != nil {
+					entry.password = password
+				}
+			case "account":
+				value()
+			case "macdef":
+				// macros run until an empty line
+				for i++; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				}
+				j = len(fields)
+			}
+
+		}
+
+	}
+
+	return entries
+
+}
+
+// CommandCredentials asks an external helper for the credentials, the way git credential helpers
+// are asked. The command is run with "get" as last argument and reads protocol=, host= and
+// username= lines on its standard input, then writes username= and password= lines.
+//
+//	CommandCredentials{Command: []string{"git", "credential-store"}}
+type CommandCredentials struct {
+	Command []string
+}
+
+func (this CommandCredentials) Credentials(endpoint, username string) (string, string, error) {
+
+	if len(this.Command) == 0 {
+		return "", "", errors.New("no credential command")
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", "", err
+	}
+
+	var input bytes.Buffer
+	fmt.Fprintf(&input, "protocol=%s\nhost=%s\n", u.Scheme, u.Host)
+	if username != "" {
+		fmt.Fprintf(&input, "username=%s\n", username)
+	}
+	input.WriteString("\n")
+
+	var stderr bytes.Buffer
+	args := append(append([]string{}, this.Command[1:]...), "get")
+	cmd := exec.Command(this.Command[0], args...)
+	cmd.Stdin = &input
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %v %s", this.Command[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	attributes, err := readCredentialAttributes(bytes.NewReader(output))
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %v", this.Command[0], err)
+	}
+
+	password, ok := attributes["password"]
+	if !ok || password == "" {
+		return "", "", CredentialsNotFound
+	}
+
+	if user := attributes["username"]; user != "" {
+		username = user
+	}
+
+	return username, password, nil
+
+}
+
+// read key=value lines, up to an empty line. Lines without = give an error, unless none has one.
+func readCredentialAttributes(r io.Reader) (map[string]string, error) {
+
+	attributes := map[string]string{}
+	invalid := ""
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			break
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			invalid = line
+			continue
+		}
+		attributes[line[:i]] = line[i+1:]
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if invalid != "" && len(attributes) > 0 {
+		return nil, errors.New("lines must be key=value")
+	}
+
+	return attributes, nil
+
+}
+
+// CredentialChain asks its providers in order, until one has credentials.
+type CredentialChain []CredentialProvider
+
+func (this CredentialChain) Credentials(endpoint, username string) (string, string, error) {
+
+	for _, provider := range this {
+		user, password, err := provider.Credentials(endpoint, username)
+		if err != CredentialsNotFound {
+			return user, password, err
+		}
+	}
+
+	return "", "", CredentialsNotFound
+
+}
+
+// the host of an endpoint, without port
+func endpointHost(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	return u.Hostname(), nil
+}
+
+// DefaultSessionTTL is how long sessions are reused, azkaban's default session.time.to.live.
+const DefaultSessionTTL = 24 * time.Hour
+
+// SessionCache keeps the session ids of logged in users on disk, so they are reused until they
+// expire. The file is only readable by its owner.
+type SessionCache struct {
+	// Path is the file of the cache, sessions.json in ConfigDir when empty
+	Path string
+
+	// TTL is how long a session is reused, DefaultSessionTTL when 0
+	TTL time.Duration
+
+	// Now is the clock of the cache, time.Now when nil
+	Now func() time.Time
+}
+
+// a session of the cache
+type cachedSession struct {
+	Session string    `json:"session.id"`
+	Created time.Time `json:"created"`
+}
+
+func (this *SessionCache) path() (string, error) {
+
+	if this.Path != "" {
+		return expandHome(this.Path), nil
+	}
+
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "sessions.json"), nil
+
+}
+
+func (this *SessionCache) ttl() time.Duration {
+	if this.TTL != 0 {
+		return this.TTL
+	}
+	return DefaultSessionTTL
+}
+
+func (this *SessionCache) now() time.Time {
+	if this.Now != nil {
+		return this.Now()
+	}
+	return time.Now()
+}
+
+// the key of a user of an endpoint
+func sessionKey(endpoint, username string) string {
+	return username + "@" + strings.TrimSuffix(endpoint, "/")
+}
+
+func (this *SessionCache) load() (map[string]cachedSession, error) {
+
+	sessions := map[string]cachedSession{}
+
+	path, err := this.path()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = readJSONFile(path, &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+
+}
+
+func (this *SessionCache) save(sessions map[string]cachedSession) error {
+
+	path, err := this.path()
+	if err != nil {
+		return err
+	}
+
+	// drop the expired sessions
+	for key, s := range sessions {
+		if this.now().Sub(s.Created) >= this.ttl() {
+			delete(sessions, key)
+		}
+	}
+
+	content, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, content, 0600)
+
+}
+
+// Get returns the session of a user of an endpoint, unless it expired.
+func (this *SessionCache) Get(endpoint, username string) (string, bool) {
+
+	sessions, err := this.load()
+	if err != nil {
+		return "", false
+	}
+
+	s, ok := sessions[sessionKey(endpoint, username)]
+	if !ok || this.now().Sub(s.Created) >= this.ttl() {
+		return "", false
+	}
+
+	return s.Session, true
+
+}
+
+// Put saves the session of a user of an endpoint.
+func (this *SessionCache) Put(endpoint, username, session string) error {
+
+	sessions, err := this.load()
+	if err != nil {
+		return err
+	}
+
+	sessions[sessionKey(endpoint, username)] = cachedSession{Session: session, Created: this.now()}
+
+	return this.save(sessions)
+
+}
+
+// Delete forgets the session of a user of an endpoint, when the server expired it before the cache did.
+func (this *SessionCache) Delete(endpoint, username string) error {
+
+	sessions, err := this.load()
+	if err != nil {
+		return err
+	}
+
+	key := sessionKey(endpoint, username)
+	if _, ok := sessions[key]; !ok {
+		return nil
+	}
+
+	delete(sessions, key)
+
+	return this.save(sessions)
+
+}
+
+// Login logs in with the credentials of a provider, for username or the user the provider
+// chooses when empty. When cache is not nil, a session it has for the user is reused, without
+// asking the provider when username is given, and the session of a new login is saved in it.
+func (this *Client) Login(provider CredentialProvider, username string, cache *SessionCache) error {
+
+	if cache != nil && username != "" {
+		if session, ok := cache.Get(this.Endpoint, username); ok {
+			this.Session, this.Username = session, username
+			return nil
+		}
+	}
+
+	user, password, err := provider.Credentials(this.Endpoint, username)
+	if err != nil {
+		return err
+	}
+
+	// the user the provider chose may have a session
+	if cache != nil && username == "" {
+		if session, ok := cache.Get(this.Endpoint, user); ok {
+			this.Session, this.Username = session, user
+			return nil
+		}
+	}
+
+	if err = this.Authenticate(user, password); err != nil {
+		return err
+	}
+
+	if cache != nil {
+		return cache.Put(this.Endpoint, user, this.Session)
+	}
+
+	return nil
+
+}
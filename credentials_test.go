@@ -0,0 +1,200 @@
+package azkaban_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wanglun/azkaban"
+	"github.com/wanglun/azkaban/azkabantest"
+)
+
+const credentialsEndpoint = "https://azkaban.example.com:8443"
+
+// the credentials a provider gives
+func credentials(provider azkaban.CredentialProvider, username string) []string {
+	user, password, err := provider.Credentials(credentialsEndpoint, username)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	return []string{user, password}
+}
+
+func TestCredentialProviders(t *testing.T) {
+
+	dir := t.TempDir()
+	notFound := []string{azkaban.CredentialsNotFound.Error()}
+
+	static := azkaban.StaticCredentials{Username: "azkaban", Password: "secret"}
+	assert.Equal(t, []string{"azkaban", "secret"}, credentials(static, ""))
+	assert.Equal(t, notFound, credentials(static, "other"))
+
+	t.Setenv("AZKABAN_USER", "env")
+	t.Setenv("AZKABAN_PASS", "from env")
+	t.Setenv("OTHER_PASS", "")
+	t.Setenv("NO_USER", "")
+	assert.Equal(t, []string{"env", "from env"}, credentials(azkaban.EnvCredentials{}, ""))
+	assert.Equal(t, []string{"env", "from env"}, credentials(azkaban.EnvCredentials{}, "env"))
+	assert.Equal(t, notFound, credentials(azkaban.EnvCredentials{}, "azkaban"))
+	assert.Equal(t, []string{"azkaban", "from env"}, credentials(azkaban.EnvCredentials{UserVar: "NO_USER"}, "azkaban"))
+	assert.Equal(t, notFound, credentials(azkaban.EnvCredentials{PasswordVar: "OTHER_PASS"}, ""))
+
+	// password files, only readable by their owner
+	file := filepath.Join(dir, "password")
+	assert.Nil(t, ioutil.WriteFile(file, []byte("p=ss\n"), 0600))
+	assert.Equal(t, []string{"azkaban", "p=ss"}, credentials(azkaban.FileCredentials{Path: file}, "azkaban"))
+
+	assert.Nil(t, ioutil.WriteFile(file, []byte("username=file\npassword=from file\n"), 0600))
+	assert.Equal(t, []string{"file", "from file"}, credentials(azkaban.FileCredentials{Path: file}, ""))
+	assert.Equal(t, notFound, credentials(azkaban.FileCredentials{Path: file}, "azkaban"))
+	assert.Equal(t, notFound, credentials(azkaban.FileCredentials{Path: file + ".missing"}, ""))
+
+	if runtime.GOOS != "windows" {
+		assert.Nil(t, ioutil.WriteFile(file+".open", []byte("secret\n"), 0644))
+		_, _, err := azkaban.FileCredentials{Path: file + ".open"}.Credentials(credentialsEndpoint, "azkaban")
+		assert.NotNil(t, err)
+		assert.NotEqual(t, azkaban.CredentialsNotFound, err)
+	}
+
+	// netrc entries of the host, then the default one
+	netrc := filepath.Join(dir, "netrc")
+	assert.Nil(t, ioutil.WriteFile(netrc, []byte(`
+machine other.example.com login other password wrong
+macdef init
+  machine azkaban.example.com login macro password wrong
+
+machine azkaban.example.com
+  login deploy
+  password "deploy"
+machine azkaban.example.com login admin password admin
+default login anonymous password guest
+`), 0600))
+	provider := azkaban.NetrcCredentials{Path: netrc}
+	assert.Equal(t, []string{"deploy", `"deploy"`}, credentials(provider, ""))
+	assert.Equal(t, []string{"admin", "admin"}, credentials(provider, "admin"))
+	assert.Equal(t, []string{"anonymous", "guest"}, credentials(provider, "anonymous"))
+	assert.Equal(t, notFound, credentials(provider, "nobody"))
+
+	// chains skip the providers without credentials
+	chain := azkaban.CredentialChain{azkaban.EnvCredentials{PasswordVar: "OTHER_PASS"}, provider}
+	assert.Equal(t, []string{"admin", "admin"}, credentials(chain, "admin"))
+	assert.Equal(t, notFound, credentials(chain, "nobody"))
+
+}
+
+func TestCommandCredentials(t *testing.T) {
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the helper is a shell script")
+	}
+
+	// a helper echoing its input back with a password
+	helper := filepath.Join(t.TempDir(), "helper")
+	assert.Nil(t, ioutil.WriteFile(helper, []byte(`#!/bin/sh
+test "$1" = get || exit 1
+while read line && test -n "$line"; do
+	case "$line" in
+	host=*) echo "password=${line#host=}" ;;
+	username=*) echo "$line" ;;
+	esac
+done
+`), 0700))
+
+	provider := azkaban.CommandCredentials{Command: []string{helper}}
+	assert.Equal(t, []string{"azkaban", "azkaban.example.com:8443"}, credentials(provider, "azkaban"))
+
+	_, _, err := azkaban.CommandCredentials{Command: []string{"false"}}.Credentials(credentialsEndpoint, "")
+	assert.NotNil(t, err)
+
+}
+
+func TestSessionCache(t *testing.T) {
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := &azkaban.SessionCache{
+		Path: filepath.Join(t.TempDir(), "azkaban", "sessions.json"),
+		TTL:  time.Hour,
+		Now:  func() time.Time { return now },
+	}
+
+	_, ok := cache.Get(credentialsEndpoint, "azkaban")
+	assert.False(t, ok)
+
+	assert.Nil(t, cache.Put(credentialsEndpoint+"/", "azkaban", "session"))
+
+	session, ok := cache.Get(credentialsEndpoint, "azkaban")
+	assert.True(t, ok)
+	assert.Equal(t, "session", session)
+
+	_, ok = cache.Get(credentialsEndpoint, "other")
+	assert.False(t, ok)
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(cache.Path)
+		assert.Nil(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+
+	now = now.Add(time.Hour)
+	_, ok = cache.Get(credentialsEndpoint, "azkaban")
+	assert.False(t, ok)
+
+	assert.Nil(t, cache.Put(credentialsEndpoint, "other", "other"))
+	assert.Nil(t, cache.Delete(credentialsEndpoint, "other"))
+	_, ok = cache.Get(credentialsEndpoint, "other")
+	assert.False(t, ok)
+
+}
+
+func TestLogin(t *testing.T) {
+
+	server := azkabantest.NewServer()
+	defer server.Close()
+
+	cache := &azkaban.SessionCache{Path: filepath.Join(t.TempDir(), "sessions.json")}
+	credentials := azkaban.StaticCredentials{Username: azkabantest.DefaultUser, Password: azkabantest.DefaultPassword}
+
+	client := azkaban.New(server.URL)
+	assert.Nil(t, client.Login(credentials, azkabantest.DefaultUser, cache))
+	assert.NotEmpty(t, client.Session)
+
+	// the cached session is reused without credentials
+	other := azkaban.New(server.URL)
+	assert.Nil(t, other.Login(azkaban.CredentialChain{}, azkabantest.DefaultUser, cache))
+	assert.Equal(t, client.Session, other.Session)
+
+	_, err := other.FetchProjects()
+	assert.Nil(t, err)
+
+	server.ExpireSessions()
+	_, err = other.FetchProjects()
+	assert.Equal(t, azkaban.SessionExpired, err)
+
+	// the session of the user the provider chose is cached for that user
+	assert.Nil(t, cache.Delete(server.URL, azkabantest.DefaultUser))
+	chosen := azkaban.New(server.URL)
+	assert.Nil(t, chosen.Login(credentials, "", cache))
+	assert.Equal(t, azkabantest.DefaultUser, chosen.Username)
+	_, ok := cache.Get(server.URL, "")
+	assert.False(t, ok)
+	session, ok := cache.Get(server.URL, azkabantest.DefaultUser)
+	assert.True(t, ok)
+	assert.Equal(t, chosen.Session, session)
+
+	again := azkaban.New(server.URL)
+	assert.Nil(t, again.Login(credentials, "", cache))
+	assert.Equal(t, chosen.Session, again.Session)
+	assert.Equal(t, azkabantest.DefaultUser, again.Username)
+
+	// wrong credentials are not cached
+	assert.Nil(t, cache.Delete(server.URL, azkabantest.DefaultUser))
+	err = other.Login(azkaban.StaticCredentials{Password: "wrong"}, azkabantest.DefaultUser, cache)
+	assert.NotNil(t, err)
+	_, ok = cache.Get(server.URL, azkabantest.DefaultUser)
+	assert.False(t, ok)
+
+}
@@ -0,0 +1,356 @@
+package azkaban
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ProjectDiff is what changes from a version of a project to another: flows and jobs added or
+// removed, dependencies and properties changed. Flow 2.0 projects are compared through their
+// legacy conversion.
+type ProjectDiff struct {
+	AddedFlows   []string         `json:"addedFlows,omitempty"`
+	RemovedFlows []string         `json:"removedFlows,omitempty"`
+	Jobs         []JobDiff        `json:"jobs,omitempty"`
+	Properties   []PropertiesDiff `json:"properties,omitempty"`
+}
+
+// JobDiff is a job added, removed or changed.
+type JobDiff struct {
+	Action              string           `json:"action"`
+	Name                string           `json:"name"`
+	File                string           `json:"file"`
+	AddedDependencies   []string         `json:"addedDependencies,omitempty"`
+	RemovedDependencies []string         `json:"removedDependencies,omitempty"`
+	Properties          []PropertyChange `json:"properties,omitempty"`
+}
+
+// PropertiesDiff is a .properties file added, removed or changed.
+type PropertiesDiff struct {
+	Action     string           `json:"action"`
+	File       string           `json:"file"`
+	Properties []PropertyChange `json:"properties,omitempty"`
+}
+
+// PropertyChange is a property added, removed or given another value.
+type PropertyChange struct {
+	Action string `json:"action"`
+	Key    string `json:"key"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+}
+
+func (this PropertyChange) String() string {
+	switch this.Action {
+	case ChangeAction.Create:
+		return fmt.Sprintf("+ %s=%s", this.Key, this.New)
+	case ChangeAction.Remove:
+		return fmt.Sprintf("- %s=%s", this.Key, this.Old)
+	}
+	return fmt.Sprintf("~ %s=%s -> %s", this.Key, this.Old, this.New)
+}
+
+// Empty tells whether both versions are the same.
+func (this *ProjectDiff) Empty() bool {
+	return len(this.AddedFlows)+len(this.RemovedFlows)+len(this.Jobs)+len(this.Properties) == 0
+}
+
+// String renders the diff for review, one line per change.
+func (this *ProjectDiff) String() string {
+
+	if this.Empty() {
+		return "no changes\n"
+	}
+
+	var b strings.Builder
+
+	for _, flow := range this.AddedFlows {
+		fmt.Fprintf(&b, "+ flow %s\n", flow)
+	}
+	for _, flow := range this.RemovedFlows {
+		fmt.Fprintf(&b, "- flow %s\n", flow)
+	}
+
+	sign := map[string]string{ChangeAction.Create: "+", ChangeAction.Update: "~", ChangeAction.Remove: "-"}
+
+	for _, job := range this.Jobs {
+		fmt.Fprintf(&b, "%s job %s (%s)\n", sign[job.Action], job.Name, job.File)
+		if job.Action != ChangeAction.Update {
+			continue
+		}
+		for _, dep := range job.AddedDependencies {
+			fmt.Fprintf(&b, "    + depends on %s\n", dep)
+		}
+		for _, dep := range job.RemovedDependencies {
+			fmt.Fprintf(&b, "    - depends on %s\n", dep)
+		}
+		for _, change := range job.Properties {
+			fmt.Fprintf(&b, "    %s\n", change)
+		}
+	}
+
+	for _, file := range this.Properties {
+		fmt.Fprintf(&b, "%s properties %s\n", sign[file.Action], file.File)
+		if file.Action != ChangeAction.Update {
+			continue
+		}
+		for _, change := range file.Properties {
+			fmt.Fprintf(&b, "    %s\n", change)
+		}
+	}
+
+	return b.String()
+
+}
+
+// DiffProjects compares two versions of a legacy project, from old to new.
+func DiffProjects(old, new *LocalProject) *ProjectDiff {
+
+	diff := &ProjectDiff{}
+
+	diff.AddedFlows, diff.RemovedFlows = diffSets(old.Flows(), new.Flows())
+
+	// jobs, by name
+	var names []string
+	for _, job := range old.Jobs {
+		if new.Job(job.Name) == nil {
+			names = append(names, job.Name)
+		}
+	}
+	for _, job := range new.Jobs {
+		names = append(names, job.Name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+
+		before, after := old.Job(name), new.Job(name)
+
+		switch {
+
+		case before == nil:
+			diff.Jobs = append(diff.Jobs, JobDiff{Action: ChangeAction.Create, Name: name, File: after.File})
+
+		case after == nil:
+			diff.Jobs = append(diff.Jobs, JobDiff{Action: ChangeAction.Remove, Name: name, File: before.File})
+
+		default:
+			job := JobDiff{Action: ChangeAction.Update, Name: name, File: after.File}
+			job.AddedDependencies, job.RemovedDependencies = diffSets(before.Dependencies(), after.Dependencies())
+			job.Properties = diffProperties(before.Props, after.Props, "dependencies")
+			if before.File != after.File {
+				job.Properties = append([]PropertyChange{{Action: ChangeAction.Update, Key: "file", Old: before.File, New: after.File}}, job.Properties...)
+			}
+			if len(job.AddedDependencies)+len(job.RemovedDependencies)+len(job.Properties) > 0 {
+				diff.Jobs = append(diff.Jobs, job)
+			}
+
+		}
+
+	}
+
+	// properties files, by file
+	files := map[string][2]*PropertiesFile{}
+	for _, file := range old.Properties {
+		pair := files[file.File]
+		pair[0] = file
+		files[file.File] = pair
+	}
+	for _, file := range new.Properties {
+		pair := files[file.File]
+		pair[1] = file
+		files[file.File] = pair
+	}
+
+	names = names[:0]
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		switch pair := files[name]; {
+		case pair[0] == nil:
+			diff.Properties = append(diff.Properties, PropertiesDiff{Action: ChangeAction.Create, File: name})
+		case pair[1] == nil:
+			diff.Properties = append(diff.Properties, PropertiesDiff{Action: ChangeAction.Remove, File: name})
+		default:
+			if changes := diffProperties(pair[0].Props, pair[1].Props); len(changes) > 0 {
+				diff.Properties = append(diff.Properties, PropertiesDiff{Action: ChangeAction.Update, File: name, Properties: changes})
+			}
+		}
+	}
+
+	return diff
+
+}
+
+// the elements only in new, then only in old, sorted
+func diffSets(old, new []string) ([]string, []string) {
+
+	in := func(list []string) map[string]bool {
+		set := map[string]bool{}
+		for _, s := range list {
+			set[s] = true
+		}
+		return set
+	}
+
+	before, after := in(old), in(new)
+
+	var added, removed []string
+	for s := range after {
+		if !before[s] {
+			added = append(added, s)
+		}
+	}
+	for s := range before {
+		if !after[s] {
+			removed = append(removed, s)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	return added, removed
+
+}
+
+// compare the values of properties, the last definition of a key wins, keys sorted
+func diffProperties(old, new []PropertyEntry, ignored ...string) []PropertyChange {
+
+	values := func(props []PropertyEntry) map[string]string {
+		m := map[string]string{}
+		for _, p := range props {
+			m[p.Key] = p.Value
+		}
+		for _, key := range ignored {
+			delete(m, key)
+		}
+		return m
+	}
+
+	before, after := values(old), values(new)
+
+	var keys []string
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []PropertyChange
+	for _, key := range keys {
+		was, existed := before[key]
+		is, exists := after[key]
+		switch {
+		case !existed:
+			changes = append(changes, PropertyChange{Action: ChangeAction.Create, Key: key, New: is})
+		case !exists:
+			changes = append(changes, PropertyChange{Action: ChangeAction.Remove, Key: key, Old: was})
+		case was != is:
+			changes = append(changes, PropertyChange{Action: ChangeAction.Update, Key: key, Old: was, New: is})
+		}
+	}
+
+	return changes
+
+}
+
+// read the flow model of project files, Flow 2.0 projects are converted
+func readFlowModel(files []File) (*LocalProject, error) {
+
+	for _, file := range files {
+
+		if path.Ext(file.Name) != ".project" {
+			continue
+		}
+
+		flow2, err := ReadFlow2Project(files)
+		if err != nil {
+			return nil, err
+		}
+
+		if files, err = ConvertFromFlow2(flow2); err != nil {
+			return nil, err
+		}
+
+		break
+
+	}
+
+	return ReadProject(files)
+
+}
+
+// the flow model of a version of a deployed project, an empty project when it has no archive
+func (this *Client) fetchFlowModel(project string, version int) (*LocalProject, error) {
+
+	var archive bytes.Buffer
+
+	err := this.DownloadProject(context.Background(), project, version, &archive)
+	if err == ArchiveNotFound && version == 0 {
+		return &LocalProject{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := readZip(archive.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return readFlowModel(files)
+
+}
+
+// DiffProject compares a project directory or zip archive with a version of the deployed
+// project, the latest when version is 0, to review what uploading it would change. A project
+// without any upload compares as empty.
+func (this *Client) DiffProject(local string, project string, version int) (*ProjectDiff, error) {
+
+	files, err := readProjectFiles(local)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := readFlowModel(files)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := this.fetchFlowModel(project, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffProjects(before, after), nil
+
+}
+
+// DiffProjectVersions compares two versions of a deployed project, from old to new.
+func (this *Client) DiffProjectVersions(project string, old, new int) (*ProjectDiff, error) {
+
+	before, err := this.fetchFlowModel(project, old)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := this.fetchFlowModel(project, new)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffProjects(before, after), nil
+
+}
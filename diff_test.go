@@ -0,0 +1,105 @@
+package azkaban_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wanglun/azkaban"
+	"github.com/wanglun/azkaban/azkabantest"
+)
+
+func TestDiffProject(t *testing.T) {
+
+	server := azkabantest.NewServer()
+	defer server.Close()
+
+	client, err := server.Client()
+	assert.Nil(t, err)
+
+	_, err = client.CreateProject("diffed", "diffed project")
+	assert.Nil(t, err)
+
+	// a copy of the test flow, changed below
+	source := t.TempDir()
+	for _, name := range []string{"foo.job", "bar.job", "flow.properties"} {
+		content, err := ioutil.ReadFile(filepath.Join("testdata", "testflow", name))
+		assert.Nil(t, err)
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(source, name), content, 0644))
+	}
+
+	// before the first upload everything is new
+	diff, err := client.DiffProject(source, "diffed", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"bar"}, diff.AddedFlows)
+	assert.Len(t, diff.Jobs, 2)
+	assert.Equal(t, "+ flow bar\n+ job bar (bar.job)\n+ job foo (foo.job)\n+ properties flow.properties\n", diff.String())
+
+	upload := func() {
+		var archive bytes.Buffer
+		assert.Nil(t, azkaban.PackageDir(&archive, source, azkaban.PackageOptions{}))
+		_, err := client.UploadProject(context.Background(), "diffed", &archive, "diffed.zip", nil)
+		assert.Nil(t, err)
+	}
+
+	upload()
+
+	diff, err = client.DiffProject(source, "diffed", 0)
+	assert.Nil(t, err)
+	assert.True(t, diff.Empty())
+	assert.Equal(t, "no changes\n", diff.String())
+
+	// a new job at the end of the flow, a changed command and a changed property
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(source, "baz.job"), []byte("type=command\ncommand=echo baz\ndependencies=bar\n"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(source, "foo.job"), []byte("type=command\ncommand=echo changed\nretries=2\n"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(source, "flow.properties"), []byte("test.p1=20\ntest.p2=p2\n"), 0644))
+
+	diff, err = client.DiffProject(source, "diffed", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"baz"}, diff.AddedFlows)
+	assert.Equal(t, []string{"bar"}, diff.RemovedFlows)
+	assert.Equal(t, `+ flow baz
+- flow bar
++ job baz (baz.job)
+~ job foo (foo.job)
+    ~ command=echo "hello foo, p1:" ${test.p1} -> echo changed
+    + retries=2
+~ properties flow.properties
+    ~ test.p1=10 -> 20
+`, diff.String())
+
+	upload()
+
+	// versions of the deployed project compare the same way
+	diff, err = client.DiffProjectVersions("diffed", 1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"baz"}, diff.AddedFlows)
+	assert.Equal(t, "foo", diff.Jobs[1].Name)
+	assert.Equal(t, []azkaban.PropertyChange{
+		{Action: azkaban.ChangeAction.Update, Key: "command", Old: `echo "hello foo, p1:" ${test.p1}`, New: "echo changed"},
+		{Action: azkaban.ChangeAction.Create, Key: "retries", New: "2"},
+	}, diff.Jobs[1].Properties)
+
+	// zip archives are compared as well, a moved job file is a change of the job
+	diff, err = client.DiffProject(filepath.Join("testdata", "testflow.zip"), "diffed", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"bar"}, diff.AddedFlows)
+	assert.Equal(t, []string{"baz"}, diff.RemovedFlows)
+	assert.Equal(t, azkaban.ChangeAction.Update, diff.Jobs[0].Action)
+	assert.Equal(t, azkaban.PropertyChange{Action: azkaban.ChangeAction.Update, Key: "file", Old: "bar.job", New: "testflow/bar.job"}, diff.Jobs[0].Properties[0])
+	assert.Equal(t, azkaban.JobDiff{Action: azkaban.ChangeAction.Remove, Name: "baz", File: "baz.job"}, diff.Jobs[1])
+
+	// a dependency removed
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(source, "baz.job"), []byte("type=command\ncommand=echo baz\ndependencies=foo\n"), 0644))
+	diff, err = client.DiffProject(source, "diffed", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"foo"}, diff.Jobs[0].AddedDependencies)
+	assert.Equal(t, []string{"bar"}, diff.Jobs[0].RemovedDependencies)
+
+	_, err = client.DiffProject(source, "diffed", 3)
+	assert.Equal(t, azkaban.ArchiveNotFound, err)
+
+}
@@ -0,0 +1,262 @@
+package azkaban
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Executor is an executor server of a multi-executor Azkaban 3 cluster.
+type Executor struct {
+	ID     int    `json:"id"`
+	Host   string `json:"host"`
+	Port   int    `json:"port"`
+	Active bool   `json:"active"`
+
+	// Stats are the statistics of the executor, known once its status was fetched
+	Stats *ExecutorStats `json:"stats,omitempty"`
+}
+
+// ExecutorStats are the statistics an executor reports to the web server, which dispatches the
+// executions by them.
+type ExecutorStats struct {
+	RemainingMemoryPercent float64 `json:"remainingMemoryPercent"`
+	RemainingMemoryInMB    int64   `json:"remainingMemoryInMB"`
+	RemainingFlowCapacity  int     `json:"remainingFlowCapacity"`
+	NumberOfAssignedFlows  int     `json:"numberOfAssignedFlows"`
+	LastDispatchedTime     int64   `json:"lastDispatchedTime"`
+	CpuUsage               float64 `json:"cpuUsage"`
+}
+
+// ExecutorCheck is the health of an executor, Error is why it could not be reached.
+type ExecutorCheck struct {
+	Executor Executor `json:"executor"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Healthy tells whether the executor answered.
+func (this ExecutorCheck) Healthy() bool {
+	return this.Error == ""
+}
+
+// the url of an executor server, executors are not served over https
+func (this Executor) url(path string, values url.Values) string {
+	return fmt.Sprintf("http://%s:%d%s?%s", this.Host, this.Port, path, values.Encode())
+}
+
+// This API call fetches the executors of the cluster, as the web server last loaded them.
+func (this *Client) FetchExecutors() ([]Executor, error) {
+
+	// init return
+	var response struct {
+		Executors []Executor `json:"executors"`
+	}
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", "fetchExecutors")
+	values.Add("session.id", this.Session)
+
+	// request api
+	err := this.action(http.MethodGet, "/executor", values, &response)
+
+	return response.Executors, err
+
+}
+
+// This API call makes the web server load the executors again, after executors were added,
+// activated or deactivated. It requires an admin.
+func (this *Client) ReloadExecutors() error {
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", "reloadExecutors")
+	values.Add("session.id", this.Session)
+
+	// an error or a success message is returned
+	var response map[string]interface{}
+
+	// request api
+	return this.action(http.MethodPost, "/executor", values, &response)
+
+}
+
+// call an executor server directly, it answers with an error or the response
+func (this *Client) executorAction(ctx context.Context, executor Executor, path string, values url.Values, data interface{}) error {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, executor.url(path, values), nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := this.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("executor %d: bad status: %s", executor.ID, res.Status)
+	}
+
+	content, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	var detail Detail
+	if err = json.Unmarshal(content, &detail); err != nil {
+		return fmt.Errorf("executor %d: %v", executor.ID, err)
+	}
+	if detail.Error != "" {
+		return fmt.Errorf("executor %d: %s", executor.ID, detail.Error)
+	}
+
+	return json.Unmarshal(content, data)
+
+}
+
+// ActivateExecutor makes an executor take executions again, once the web server reloads the executors.
+func (this *Client) ActivateExecutor(executor Executor) error {
+	return this.setExecutorActive(context.Background(), executor, "activate")
+}
+
+// DeactivateExecutor stops dispatching executions to an executor, once the web server reloads
+// the executors. The executions it runs are left running.
+func (this *Client) DeactivateExecutor(executor Executor) error {
+	return this.setExecutorActive(context.Background(), executor, "deactivate")
+}
+
+func (this *Client) setExecutorActive(ctx context.Context, executor Executor, action string) error {
+
+	// set query string
+	values := url.Values{}
+	values.Add("action", action)
+
+	// an error or a success status is returned
+	var detail Detail
+
+	// request executor
+	return this.executorAction(ctx, executor, "/executor", values, &detail)
+
+}
+
+// FetchExecutorStatus asks an executor whether it is active and for its statistics, it returns the
+// executor updated with them.
+func (this *Client) FetchExecutorStatus(executor Executor) (*Executor, error) {
+	return this.fetchExecutorStatus(context.Background(), executor)
+}
+
+func (this *Client) fetchExecutorStatus(ctx context.Context, executor Executor) (*Executor, error) {
+
+	// init return
+	var status struct {
+		Active json.RawMessage `json:"isActive"`
+	}
+
+	// set query string
+	values := url.Values{}
+	values.Add("action", "getStatus")
+
+	// request executor
+	if err := this.executorAction(ctx, executor, "/executor", values, &status); err != nil {
+		return nil, err
+	}
+
+	// sent as a string or a boolean
+	active := string(status.Active)
+	json.Unmarshal(status.Active, &active)
+
+	var err error
+	if executor.Active, err = strconv.ParseBool(active); err != nil {
+		return nil, fmt.Errorf("executor %d: invalid status %s", executor.ID, status.Active)
+	}
+
+	var stats ExecutorStats
+	if err = this.executorAction(ctx, executor, "/serverStatistics", url.Values{}, &stats); err != nil {
+		return nil, err
+	}
+	executor.Stats = &stats
+
+	return &executor, nil
+
+}
+
+// CheckExecutors fetches the status of every executor of the cluster, the executors which do
+// not answer are reported unhealthy.
+func (this *Client) CheckExecutors() ([]ExecutorCheck, error) {
+
+	executors, err := this.FetchExecutors()
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make([]ExecutorCheck, len(executors))
+
+	for i, executor := range executors {
+		checks[i].Executor = executor
+		if status, err := this.FetchExecutorStatus(executor); err != nil {
+			checks[i].Error = err.Error()
+		} else {
+			checks[i].Executor = *status
+		}
+	}
+
+	return checks, nil
+
+}
+
+// DrainExecutor deactivates an executor, makes the web server reload the executors so that it
+// dispatches no more executions to it, and waits until the executions it runs finished or ctx
+// is done, polling every interval. An executor which does not answer a poll, as when it is busy
+// or restarting, is polled again.
+func (this *Client) DrainExecutor(ctx context.Context, executor Executor, interval time.Duration) error {
+
+	if err := this.setExecutorActive(ctx, executor, "deactivate"); err != nil {
+		return err
+	}
+
+	if err := this.ReloadExecutors(); err != nil {
+		return err
+	}
+
+	for {
+
+		status, err := this.fetchExecutorStatus(ctx, executor)
+		if err == nil && status.Stats.NumberOfAssignedFlows == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+	}
+
+}
+
+// ExecuteFlowOnExecutor executes a flow as ExecuteFlow does, on the executor of id executorId
+// rather than on the one the web server picks. Azkaban pins executions only for admins.
+func (this *Client) ExecuteFlowOnExecutor(project, flow string, executorId int, concurrentOption concurrentOption, flowOverrides map[string]string) (*Execute, error) {
+
+	if executorId <= 0 {
+		return nil, errors.New("invalid executor id")
+	}
+
+	overrides := map[string]string{}
+	for k, v := range flowOverrides {
+		overrides[k] = v
+	}
+	overrides["useExecutor"] = strconv.Itoa(executorId)
+
+	return this.ExecuteFlow(project, flow, concurrentOption, overrides)
+
+}
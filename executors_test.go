@@ -0,0 +1,180 @@
+package azkaban_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wanglun/azkaban"
+	"github.com/wanglun/azkaban/azkabantest"
+)
+
+func TestExecutors(t *testing.T) {
+
+	server := azkabantest.NewServer()
+	defer server.Close()
+
+	// a clock the test moves, jobs take an hour
+	var (
+		mu  sync.Mutex
+		now = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+	server.Now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	server.JobDuration = time.Hour
+
+	first, second := server.AddExecutor(), server.AddExecutor()
+
+	client, err := server.Client()
+	assert.Nil(t, err)
+
+	_, err = client.CreateProject(PROJECT_NAME, PROJECT_DESC)
+	assert.Nil(t, err)
+	assert.Nil(t, client.UploadProjectZip(PROJECT_NAME, FLOW_ZIP_PATH))
+
+	executors, err := client.FetchExecutors()
+	assert.Nil(t, err)
+	assert.Equal(t, []azkaban.Executor{first, second}, executors)
+	assert.True(t, executors[1].Active)
+
+	// an execution pinned to the second executor
+	_, err = client.ExecuteFlowOnExecutor(PROJECT_NAME, "bar", second.ID, azkaban.ConcurrentOptionIgnore, nil)
+	assert.Nil(t, err)
+
+	checks, err := client.CheckExecutors()
+	assert.Nil(t, err)
+	assert.Len(t, checks, 2)
+	assert.True(t, checks[0].Healthy())
+	assert.Equal(t, 0, checks[0].Executor.Stats.NumberOfAssignedFlows)
+	assert.Equal(t, 1, checks[1].Executor.Stats.NumberOfAssignedFlows)
+
+	// deactivated, then seen so by the web server once reloaded
+	assert.Nil(t, client.DeactivateExecutor(first))
+	status, err := client.FetchExecutorStatus(first)
+	assert.Nil(t, err)
+	assert.False(t, status.Active)
+
+	executors, err = client.FetchExecutors()
+	assert.Nil(t, err)
+	assert.True(t, executors[0].Active)
+
+	assert.Nil(t, client.ReloadExecutors())
+	executors, err = client.FetchExecutors()
+	assert.Nil(t, err)
+	assert.False(t, executors[0].Active)
+
+	assert.Nil(t, client.ActivateExecutor(first))
+	assert.Nil(t, client.ReloadExecutors())
+
+	// draining waits for the execution
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, client.DrainExecutor(ctx, second, time.Millisecond))
+
+	mu.Lock()
+	now = now.Add(3 * time.Hour)
+	mu.Unlock()
+
+	assert.Nil(t, client.DrainExecutor(context.Background(), second, time.Millisecond))
+	executors, err = client.FetchExecutors()
+	assert.Nil(t, err)
+	assert.False(t, executors[1].Active)
+
+	// unknown executors
+	_, err = client.ExecuteFlowOnExecutor(PROJECT_NAME, "bar", 999, azkaban.ConcurrentOptionIgnore, nil)
+	assert.NotNil(t, err)
+
+	// an executor which does not answer is unhealthy
+	_, err = client.FetchExecutorStatus(azkaban.Executor{ID: 999, Host: "127.0.0.1", Port: 1})
+	assert.NotNil(t, err)
+
+}
+
+// failStatistics fails the first requests for the statistics of an executor
+type failStatistics struct {
+	mu    sync.Mutex
+	fails int
+}
+
+func (this *failStatistics) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	this.mu.Lock()
+	fail := req.URL.Path == "/serverStatistics" && this.fails > 0
+	if fail {
+		this.fails--
+	}
+	this.mu.Unlock()
+
+	if fail {
+		return nil, errors.New("connection refused")
+	}
+	return http.DefaultTransport.RoundTrip(req)
+
+}
+
+func TestDrainExecutorRetries(t *testing.T) {
+
+	server := azkabantest.NewServer()
+	defer server.Close()
+
+	executor := server.AddExecutor()
+
+	client, err := server.Client()
+	assert.Nil(t, err)
+
+	// polls failing for a while do not stop the drain
+	transport := &failStatistics{fails: 3}
+	client.HTTPClient = &http.Client{Transport: transport}
+	assert.Nil(t, client.DrainExecutor(context.Background(), executor, time.Millisecond))
+	assert.Equal(t, 0, transport.fails)
+
+	// an executor never answering is polled until ctx is done
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	transport.fails = 1 << 20
+	assert.Equal(t, context.DeadlineExceeded, client.DrainExecutor(ctx, executor, time.Millisecond))
+
+}
+
+func TestDrainExecutorHanging(t *testing.T) {
+
+	// an executor which never answers, the request ends with its context
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer hanging.Close()
+
+	host, port, err := net.SplitHostPort(hanging.Listener.Addr().String())
+	assert.Nil(t, err)
+	executor := azkaban.Executor{ID: 1, Host: host}
+	executor.Port, err = strconv.Atoi(port)
+	assert.Nil(t, err)
+
+	client := azkaban.New(hanging.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.DrainExecutor(ctx, executor, time.Millisecond)
+	}()
+
+	select {
+	case err = <-done:
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("drain did not stop with its context")
+	}
+
+}
@@ -2,11 +2,16 @@ package azkaban
 
 import (
 	"archive/zip"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
+	"time"
 )
 
 type File struct {
@@ -87,12 +92,6 @@ func ZipFiles(name string, list ...string) error {
 		return err
 	}
 
-	// create a new zip archive
-	zipWriter := zip.NewWriter(zipFile)
-
-	// close writer on defer
-	defer zipWriter.Close()
-
 	// read the files from disk
 	files, err := ReadFiles(list...)
 
@@ -101,37 +100,106 @@ func ZipFiles(name string, list ...string) error {
 		return err
 	}
 
-	// archive files
+	// archive files, files with the same name in different directories are refused
+	return writeZip(zipFile, files)
+
+}
+
+func DeleteFiles(files ...string) error {
+
 	for _, file := range files {
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+	}
 
-		// create entry into zip
-		f, err := zipWriter.Create(file.Name)
+	return nil
 
-		// error creating entry
+}
+
+// modification time of every zip entry, the earliest time zip supports, so the archive only
+// depends on the names and contents of the files
+var zipEpoch = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// write files into a zip archive, sorted by name with fixed timestamps and permissions,
+// identical files always give a byte-identical archive
+func writeZip(w io.Writer, files []File) error {
+
+	sorted := append([]File{}, files...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	// create a new zip archive
+	zipWriter := zip.NewWriter(w)
+
+	for i, file := range sorted {
+
+		if i > 0 && sorted[i-1].Name == file.Name {
+			return fmt.Errorf("duplicate file %s in archive", file.Name)
+		}
+
+		header := &zip.FileHeader{
+			Name:     file.Name,
+			Method:   zip.Deflate,
+			Modified: zipEpoch,
+		}
+		header.SetMode(0644)
+
+		// create entry into zip
+		f, err := zipWriter.CreateHeader(header)
 		if err != nil {
 			return err
 		}
 
 		// write content
-		_, err = f.Write([]byte(file.Body))
-
-		// error writing zip
-		if err != nil {
+		if _, err = f.Write(file.Body); err != nil {
 			return err
 		}
+
 	}
 
-	return nil
+	return zipWriter.Close()
+
 }
 
-func DeleteFiles(files ...string) error {
+// writes a file aside and renames it over path, so that readers never see a partial file and an
+// interruption leaves the previous content
+func writeFileAtomic(path string, content []byte, perm os.FileMode) error {
 
-	for _, file := range files {
-		if err := os.Remove(file); err != nil {
-			return err
-		}
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
 	}
+	defer os.Remove(f.Name())
 
-	return nil
+	if err = f.Chmod(perm); err == nil {
+		_, err = f.Write(content)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+
+}
+
+// reads a json file into v, it returns false when the file does not exist
+func readJSONFile(path string, v interface{}) (bool, error) {
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err = json.Unmarshal(content, v); err != nil {
+		return false, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return true, nil
 
 }
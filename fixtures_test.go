@@ -0,0 +1,547 @@
+package azkaban_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wanglun/azkaban"
+	"github.com/wanglun/azkaban/azkabantest"
+)
+
+const FIXTURES_DIR = "./testdata/fixtures"
+const AZKABAN_CORPUS = "AZKABAN_CORPUS"
+
+// the corpus recorded from azkabantest.Server rather than from the server of a profile
+const FAKE_CORPUS = "synthetic"
+
+var record = flag.Bool("record", false, "record the AZKABAN_CORPUS fixtures from the server of the azkaban profile, or from azkabantest.Server for the synthetic corpus")
+
+// state passed from a case to the following ones
+type fixtureState struct {
+	user, password string
+	execution      int64
+	pinned         int64
+	schedule       int
+	executor       azkaban.Executor
+}
+
+// the cases run in order against a single server, each with its own fixture
+var fixtureCases = []struct {
+	name string
+	run  func(t *testing.T, client *azkaban.Client, state *fixtureState)
+}{
+	{"Authenticate", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		assert.Nil(t, client.Authenticate(state.user, state.password))
+		assert.NotEmpty(t, client.Session)
+	}},
+	{"Login", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		credentials := azkaban.StaticCredentials{Username: state.user, Password: state.password}
+		cache := &azkaban.SessionCache{Path: filepath.Join(t.TempDir(), "sessions.json")}
+		assert.Nil(t, client.Login(credentials, "", cache))
+		session := client.Session
+		assert.NotEmpty(t, session)
+
+		// the cached session is reused without a request
+		client.Session = ""
+		assert.Nil(t, client.Login(credentials, state.user, cache))
+		assert.Equal(t, session, client.Session)
+	}},
+	{"CreateProject", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		object, err := client.CreateProject(PROJECT_NAME, PROJECT_DESC)
+		assert.Nil(t, err)
+		assert.Equal(t, azkaban.StatusType.Success, object.Status)
+	}},
+	{"UploadProjectZip", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		assert.Nil(t, client.UploadProjectZip(PROJECT_NAME, FLOW_ZIP_PATH))
+	}},
+	{"GetProject", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		project, err := client.GetProject(PROJECT_NAME)
+		assert.Nil(t, err)
+		assert.Equal(t, PROJECT_NAME, project.Name)
+		assert.NotZero(t, project.ID)
+		_, err = client.GetProject("missing_project")
+		assert.Equal(t, azkaban.ProjectNotFound, err)
+	}},
+	{"FetchProjects", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		projects, err := client.FetchProjects()
+		assert.Nil(t, err)
+		var names []string
+		for _, project := range projects {
+			names = append(names, project.Name)
+		}
+		assert.Contains(t, names, PROJECT_NAME)
+	}},
+	{"FetchFlows", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		flows, err := client.FetchFlows(PROJECT_NAME)
+		assert.Nil(t, err)
+		assert.Equal(t, []azkaban.Flow{{IdFlow: "bar"}}, flows.Flows)
+	}},
+	{"FetchJobs", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		jobs, err := client.FetchJobs(PROJECT_NAME, "bar")
+		assert.Nil(t, err)
+		order, err := azkaban.NewGraph(jobs).TopologicalSort()
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"foo", "bar"}, order)
+	}},
+	{"FetchJobInfo", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		info, err := client.FetchJobInfo(PROJECT_NAME, "bar", "foo")
+		assert.Nil(t, err)
+		assert.Equal(t, "command", info.Type)
+		assert.Equal(t, `echo "hello foo, p1:" ${test.p1}`, info.Properties["command"])
+	}},
+	{"SetJobOverride", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		assert.Nil(t, client.SetJobOverride(PROJECT_NAME, "bar", "foo", map[string]string{"test.p1": "20"}))
+		info, err := client.FetchJobInfo(PROJECT_NAME, "bar", "foo")
+		assert.Nil(t, err)
+		assert.Equal(t, "20", info.Effective()["test.p1"])
+	}},
+	{"FetchFlowInfo", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		_, err := client.FetchFlowInfo(PROJECT_NAME, "bar")
+		assert.Nil(t, err)
+	}},
+	{"ExecuteFlow", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		execute, err := client.ExecuteFlow(PROJECT_NAME, "bar", azkaban.ConcurrentOptionIgnore, map[string]string{"test.p2": "p2_overrided"})
+		assert.Nil(t, err)
+		assert.NotZero(t, execute.IdExecution)
+		state.execution = execute.IdExecution
+	}},
+	{"FetchRunningExecutions", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		running, err := client.FetchRunningExecutions(PROJECT_NAME, "bar")
+		assert.Nil(t, err)
+		assert.Contains(t, running.IdsExecution, strconv.FormatInt(state.execution, 10))
+	}},
+	{"FetchExecutionFlow", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		flow, err := client.FetchExecutionFlow(state.execution)
+		assert.Nil(t, err)
+		assert.Equal(t, state.execution, flow.IdExecution)
+		assert.Contains(t, flow.Statuses(), "foo")
+	}},
+	{"FetchExecutionJobLogs", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		_, err := client.FetchExecutionJobLogs(state.execution, "foo", 0, 1000)
+		assert.Nil(t, err)
+	}},
+	{"FetchExecutionOptions", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		options, err := client.FetchExecutionOptions(state.execution)
+		assert.Nil(t, err)
+		assert.Equal(t, "p2_overrided", options.FlowParameters["test.p2"])
+	}},
+	{"FetchExecutions", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		executions, err := client.FetchExecutions(PROJECT_NAME, "bar", 0, 10)
+		assert.Nil(t, err)
+		if assert.Len(t, executions.Execution, 1) {
+			assert.Equal(t, int(state.execution), executions.Execution[0].IdExecution)
+			assert.False(t, executions.Execution[0].SubmitAt.IsZero())
+		}
+	}},
+	{"IterateExecutions", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		var ids []int
+		it := client.IterateExecutions(PROJECT_NAME, "bar", time.Time{}, 1)
+		for it.Next() {
+			ids = append(ids, it.Execution().IdExecution)
+		}
+		assert.Nil(t, it.Err())
+		assert.Equal(t, []int{int(state.execution)}, ids)
+	}},
+	{"FindExecutions", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		executions, err := client.FindExecutions(azkaban.ExecutionFilter{Project: PROJECT_NAME})
+		assert.Nil(t, err)
+		assert.Len(t, executions, 1)
+	}},
+	{"SearchExecutions", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		var ids []int
+		search := client.SearchExecutions(azkaban.ExecutionFilter{Project: PROJECT_NAME, Flow: "bar", Status: []string{azkaban.ExecutionStatus.Running}, PageSize: 1})
+		for search.Next() {
+			ids = append(ids, search.Execution().IdExecution)
+		}
+		assert.Nil(t, search.Err())
+		assert.Equal(t, []int{int(state.execution)}, ids)
+	}},
+	{"FetchFlowProperties", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		props, err := client.FetchFlowProperties(PROJECT_NAME, "bar")
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{"test.p1": "10", "test.p2": "p2"}, props.Parameters)
+		assert.Equal(t, []string{"testflow/flow.properties"}, props.PropertiesFiles)
+	}},
+	{"FetchProjectLogs", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		events, err := client.FetchProjectLogs(PROJECT_NAME, 0, 10)
+		assert.Nil(t, err)
+		if assert.NotEmpty(t, events) {
+			assert.Equal(t, azkaban.EventType.Created, events[len(events)-1].Type)
+		}
+	}},
+	{"ProjectLogs", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		var types []string
+		it := client.ProjectLogs(PROJECT_NAME, 1)
+		for it.Next() {
+			types = append(types, it.Event().Type)
+		}
+		assert.Nil(t, it.Err())
+		assert.Contains(t, types, azkaban.EventType.Uploaded)
+	}},
+	{"DownloadProject", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		var archive bytes.Buffer
+		assert.Nil(t, client.DownloadProject(context.Background(), PROJECT_NAME, 0, &archive))
+		assert.Equal(t, "PK", archive.String()[:2])
+	}},
+	{"AddPermission", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		assert.Nil(t, client.AddPermission(PROJECT_NAME, azkaban.Permission{Name: "deploy", Permissions: []string{"READ"}}))
+	}},
+	{"ChangePermission", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		assert.Nil(t, client.ChangePermission(PROJECT_NAME, azkaban.Permission{Name: "deploy", Permissions: []string{"EXECUTE", "READ"}}))
+	}},
+	{"FetchPermissions", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		permissions, err := client.FetchPermissions(PROJECT_NAME)
+		assert.Nil(t, err)
+		assert.Contains(t, permissions, azkaban.Permission{Name: "deploy", Permissions: []string{"EXECUTE", "READ"}})
+	}},
+	{"RemovePermission", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		assert.Nil(t, client.RemovePermission(PROJECT_NAME, "deploy", false))
+		permissions, err := client.FetchPermissions(PROJECT_NAME)
+		assert.Nil(t, err)
+		for _, permission := range permissions {
+			assert.NotEqual(t, "deploy", permission.Name)
+		}
+	}},
+	{"AddProxyUser", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		assert.Nil(t, client.AddProxyUser(PROJECT_NAME, "etl"))
+	}},
+	{"FetchProxyUsers", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		users, err := client.FetchProxyUsers(PROJECT_NAME)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"etl"}, users)
+	}},
+	{"RemoveProxyUser", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		assert.Nil(t, client.RemoveProxyUser(PROJECT_NAME, "etl"))
+		users, err := client.FetchProxyUsers(PROJECT_NAME)
+		assert.Nil(t, err)
+		assert.Empty(t, users)
+	}},
+	{"CreateCommandJob", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		assert.Nil(t, client.CreateCommandJob(PROJECT_NAME, "baz", "echo baz"))
+		flows, err := client.FetchFlows(PROJECT_NAME)
+		assert.Nil(t, err)
+		assert.Len(t, flows.Flows, 2)
+	}},
+	{"ScheduleFlow", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		project, err := client.GetProject(PROJECT_NAME)
+		assert.Nil(t, err)
+		_, err = client.ScheduleFlow(project, "bar", time.Date(2030, 1, 1, 10, 30, 0, 0, time.UTC), "on", "1d")
+		assert.Nil(t, err)
+	}},
+	{"UnscheduleFlow", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		project, err := client.GetProject(PROJECT_NAME)
+		assert.Nil(t, err)
+		_, err = client.UnscheduleFlow(project, "bar", time.Date(2030, 1, 1, 10, 30, 0, 0, time.UTC), "off", "")
+		assert.Nil(t, err)
+	}},
+	{"ScheduleCronFlow", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		id, err := client.ScheduleCronFlow(PROJECT_NAME, "bar", "0 30 2 ? * *")
+		assert.Nil(t, err)
+		assert.NotZero(t, id)
+		state.schedule = id
+	}},
+	{"FetchSchedule", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		schedule, err := client.FetchSchedule(PROJECT_NAME, "bar")
+		assert.Nil(t, err)
+		assert.Equal(t, state.schedule, schedule.ID)
+		assert.Equal(t, "0 30 2 ? * *", schedule.Cron)
+	}},
+	{"FetchSchedules", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		schedules, err := client.FetchSchedules(PROJECT_NAME)
+		assert.Nil(t, err)
+		if assert.Len(t, schedules, 1) {
+			assert.Equal(t, state.schedule, schedules[0].ID)
+		}
+	}},
+	{"SetSLA", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		sla := azkaban.SLA{Emails: []string{"oncall@example.com"}, Settings: []azkaban.SLASetting{{Rule: azkaban.SLARule.Finish, Duration: time.Hour, Email: true}}}
+		assert.Nil(t, client.SetSLA(state.schedule, sla))
+	}},
+	{"FetchSLA", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		sla, err := client.FetchSLA(state.schedule)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"oncall@example.com"}, sla.Emails)
+		assert.Equal(t, []azkaban.SLASetting{{Rule: azkaban.SLARule.Finish, Duration: time.Hour, Email: true}}, sla.Settings)
+	}},
+	{"RemoveSchedule", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		assert.Nil(t, client.RemoveSchedule(state.schedule))
+		schedules, err := client.FetchSchedules(PROJECT_NAME)
+		assert.Nil(t, err)
+		assert.Empty(t, schedules)
+	}},
+	{"FetchExecutors", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		executors, err := client.FetchExecutors()
+		assert.Nil(t, err)
+		if assert.NotEmpty(t, executors) {
+			assert.True(t, executors[0].Active)
+			state.executor = executors[0]
+		}
+	}},
+	{"FetchExecutorStatus", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		status, err := client.FetchExecutorStatus(state.executor)
+		assert.Nil(t, err)
+		assert.True(t, status.Active)
+	}},
+	{"CheckExecutors", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		checks, err := client.CheckExecutors()
+		assert.Nil(t, err)
+		if assert.NotEmpty(t, checks) {
+			assert.True(t, checks[0].Healthy())
+		}
+	}},
+	{"DeactivateExecutor", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		assert.Nil(t, client.DeactivateExecutor(state.executor))
+		status, err := client.FetchExecutorStatus(state.executor)
+		assert.Nil(t, err)
+		assert.False(t, status.Active)
+	}},
+	{"ActivateExecutor", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		assert.Nil(t, client.ActivateExecutor(state.executor))
+		status, err := client.FetchExecutorStatus(state.executor)
+		assert.Nil(t, err)
+		assert.True(t, status.Active)
+	}},
+	{"ReloadExecutors", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		assert.Nil(t, client.ReloadExecutors())
+	}},
+	{"ExecuteFlowOnExecutor", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		execute, err := client.ExecuteFlowOnExecutor(PROJECT_NAME, "bar", state.executor.ID, azkaban.ConcurrentOptionIgnore, nil)
+		assert.Nil(t, err)
+		assert.NotZero(t, execute.IdExecution)
+		state.pinned = execute.IdExecution
+	}},
+	{"CancelExecution", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		assert.Nil(t, client.CancelExecution(state.pinned))
+	}},
+	{"WaitExecution", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		flow, err := client.WaitExecution(context.Background(), state.pinned, time.Millisecond)
+		assert.Nil(t, err)
+		assert.Equal(t, azkaban.ExecutionStatus.Killed, flow.Status)
+	}},
+	{"CancelFlow", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		assert.Nil(t, client.CancelFlow(PROJECT_NAME, "bar"))
+		flow, err := client.FetchExecutionFlow(state.execution)
+		assert.Nil(t, err)
+		assert.Equal(t, azkaban.ExecutionStatus.Killed, flow.Status)
+	}},
+	{"DrainExecutor", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		assert.Nil(t, client.DrainExecutor(context.Background(), state.executor, time.Millisecond))
+		assert.Nil(t, client.ActivateExecutor(state.executor))
+		assert.Nil(t, client.ReloadExecutors())
+	}},
+	{"UploadProject", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		archive, err := ioutil.ReadFile(FLOW_ZIP_PATH)
+		assert.Nil(t, err)
+		var sent int64
+		upload, err := client.UploadProject(context.Background(), PROJECT_NAME, bytes.NewReader(archive), "testflow.zip", func(n int64) { sent = n })
+		assert.Nil(t, err)
+		assert.Equal(t, "3", upload.Version)
+		assert.Equal(t, int64(len(archive)), sent)
+	}},
+	{"DiffProjectVersions", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		diff, err := client.DiffProjectVersions(PROJECT_NAME, 2, 3)
+		assert.Nil(t, err)
+		if assert.Len(t, diff.Jobs, 1) {
+			assert.Equal(t, "baz", diff.Jobs[0].Name)
+		}
+	}},
+	{"DiffProject", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		diff, err := client.DiffProject(FLOW_ZIP_PATH, PROJECT_NAME, 0)
+		assert.Nil(t, err)
+		assert.True(t, diff.Empty())
+	}},
+	{"Apply", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		plan, err := client.Apply(&azkaban.ProjectSpec{Name: PROJECT_NAME, Source: FLOW_ZIP_PATH, ProxyUsers: []string{"etl"}}, true)
+		assert.Nil(t, err)
+		assert.Len(t, plan.Changes, 1)
+	}},
+	{"DeleteProject", func(t *testing.T, client *azkaban.Client, state *fixtureState) {
+		object, err := client.DeleteProject(PROJECT_NAME)
+		assert.Nil(t, err)
+		assert.Equal(t, azkaban.StatusType.Success, object.Status)
+	}},
+}
+
+// TestFixtures replays the recorded responses of every corpus, a directory of FIXTURES_DIR. With
+// -record, the cases run against a server and the fixtures of the AZKABAN_CORPUS corpus are written
+// again, secrets redacted.
+func TestFixtures(t *testing.T) {
+	if *record {
+		recordFixtures(t)
+		return
+	}
+
+	entries, err := ioutil.ReadDir(FIXTURES_DIR)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		corpus := entry.Name()
+		t.Run(corpus, func(t *testing.T) {
+			client := azkaban.New("http://azkaban.test")
+			state := &fixtureState{user: azkabantest.DefaultUser, password: azkabantest.DefaultPassword}
+
+			for _, c := range fixtureCases {
+				fixture, err := azkabantest.LoadFixture(azkabantest.FixturePath(FIXTURES_DIR, corpus, c.name))
+				if !assert.Nil(t, err) {
+					return
+				}
+
+				replayer := azkabantest.NewReplayer(fixture)
+				client.HTTPClient = &http.Client{Transport: replayer}
+
+				if !t.Run(c.name, func(t *testing.T) { c.run(t, client, state) }) {
+					return
+				}
+				assert.Empty(t, replayer.Unused(), c.name)
+			}
+		})
+	}
+}
+
+func recordFixtures(t *testing.T) {
+	corpus := os.Getenv(AZKABAN_CORPUS)
+	if corpus == "" {
+		t.Fatal("recording needs AZKABAN_CORPUS")
+	}
+
+	if corpus == FAKE_CORPUS {
+		// executions keep running until they are cancelled, and the clock ticks a millisecond per
+		// reading so that recording again gives the same fixtures
+		server := azkabantest.NewServer()
+		executor := server.AddExecutor()
+		server.JobDuration = time.Hour
+		clock := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+		server.Now = func() time.Time {
+			clock = clock.Add(time.Millisecond)
+			return clock
+		}
+		defer server.Close()
+
+		recorder := azkabantest.NewRecorder(&executorPort{port: executor.Port})
+		client := azkaban.New(server.URL)
+		client.HTTPClient = &http.Client{Transport: recorder}
+
+		recordCases(t, client, recorder, corpus, &fixtureState{user: azkabantest.DefaultUser, password: azkabantest.DefaultPassword})
+		return
+	}
+
+	config, err := azkaban.LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile, err := config.Profile("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	password, err := profile.Secret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// start from a clean server
+	client, err := profile.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.Authenticate(profile.Username, password); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.GetProject(PROJECT_NAME); err == nil {
+		client.DeleteProject(PROJECT_NAME)
+	}
+
+	recorder := azkabantest.NewRecorder(client.HTTPClient.Transport)
+	client, _ = profile.NewClient()
+	client.HTTPClient.Transport = recorder
+
+	recordCases(t, client, recorder, corpus, &fixtureState{user: profile.Username, password: password})
+}
+
+func recordCases(t *testing.T, client *azkaban.Client, recorder *azkabantest.Recorder, corpus string, state *fixtureState) {
+	for _, c := range fixtureCases {
+		if !t.Run(c.name, func(t *testing.T) { c.run(t, client, state) }) {
+			return
+		}
+		assert.Nil(t, recorder.Save(azkabantest.FixturePath(FIXTURES_DIR, corpus, c.name)))
+	}
+}
+
+// the port of the executor, random for azkabantest.Server, is recorded as FIXTURE_EXECUTOR_PORT
+// so that recording again gives the same fixtures
+const FIXTURE_EXECUTOR_PORT = 12321
+
+type executorPort struct {
+	port int
+}
+
+func (this *executorPort) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	if req.URL.Port() == strconv.Itoa(FIXTURE_EXECUTOR_PORT) {
+		req = req.Clone(req.Context())
+		req.URL.Host = net.JoinHostPort(req.URL.Hostname(), strconv.Itoa(this.port))
+		req.Host = req.URL.Host
+	}
+
+	res, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil || !strings.Contains(res.Header.Get("Content-Type"), "json") {
+		return res, err
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if decoder.Decode(&value) == nil {
+		this.replace(value)
+		body, _ = json.Marshal(value)
+	}
+
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	res.ContentLength = int64(len(body))
+	res.Header.Del("Content-Length")
+
+	return res, nil
+
+}
+
+// replace the port of the executor in a json value
+func (this *executorPort) replace(value interface{}) {
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for k, v := range value {
+			if n, ok := v.(json.Number); ok && k == "port" && n.String() == strconv.Itoa(this.port) {
+				value[k] = FIXTURE_EXECUTOR_PORT
+				continue
+			}
+			this.replace(v)
+		}
+	case []interface{}:
+		for _, v := range value {
+			this.replace(v)
+		}
+	}
+
+}
@@ -1,6 +1,8 @@
 package azkaban
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,7 +10,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -27,6 +29,29 @@ type Node struct {
 	ID   string   `json:"id"`
 	Type string   `json:"type"`
 	In   []string `json:"in"`
+
+	// embedded flows carry the id of the flow they run and its nodes
+	EmbeddedFlowID string `json:"embeddedFlowId,omitempty"`
+	FlowID         string `json:"flowId,omitempty"`
+	Flow           string `json:"flow,omitempty"`
+	Nodes          []Node `json:"nodes,omitempty"`
+
+	// files the node was loaded from, relative to the project root
+	JobSource  string `json:"jobSource,omitempty"`
+	PropSource string `json:"propSource,omitempty"`
+}
+
+// EmbeddedFlow returns the id of the flow an embedded flow node runs, or "" for a job.
+func (this *Node) EmbeddedFlow() string {
+	switch {
+	case this.EmbeddedFlowID != "":
+		return this.EmbeddedFlowID
+	case this.FlowID != "":
+		return this.FlowID
+	case len(this.Nodes) > 0:
+		return this.Flow
+	}
+	return ""
 }
 
 type Jobs struct {
@@ -39,6 +64,7 @@ type Execution struct {
 	IdExecution int       `json:"execId"`
 	IdProject   int       `json:"projectId"`
 	IdFlow      string    `json:"flowId"`
+	Project     string    `json:"project,omitempty"`
 	User        string    `json:"submitUser"`
 	SubmitTime  int64     `json:"submitTime"`
 	StartTime   int64     `json:"startTime"`
@@ -70,6 +96,100 @@ type Execute struct {
 	Flow        string `json:"flow"`
 }
 
+var ExecutionStatus = struct {
+	Ready, Preparing, Running, Paused, Succeeded, Killing, Killed, Failed, FailedFinishing, Skipped, Disabled, Queued, Cancelled string
+}{"READY", "PREPARING", "RUNNING", "PAUSED", "SUCCEEDED", "KILLING", "KILLED", "FAILED", "FAILED_FINISHING", "SKIPPED", "DISABLED", "QUEUED", "CANCELLED"}
+
+// ExecutionFlow is the state of an execution and of each of its nodes.
+type ExecutionFlow struct {
+	IdExecution int64           `json:"execid"`
+	Project     string          `json:"project"`
+	IdProject   int             `json:"projectId"`
+	IdFlow      string          `json:"flowId"`
+	Flow        string          `json:"flow"`
+	User        string          `json:"submitUser"`
+	Status      string          `json:"status"`
+	Attempt     int             `json:"attempt"`
+	SubmitTime  int64           `json:"submitTime"`
+	StartTime   int64           `json:"startTime"`
+	EndTime     int64           `json:"endTime"`
+	UpdateTime  int64           `json:"updateTime"`
+	SubmitAt    time.Time       `json:"submitAt"`
+	StartedAt   time.Time       `json:"startedAt"`
+	FinishedAt  time.Time       `json:"finishedAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+	Nodes       []ExecutionNode `json:"nodes"`
+}
+
+// ExecutionNode is the state of a job, or of an embedded flow and its nodes, in an execution.
+type ExecutionNode struct {
+	ID         string          `json:"id"`
+	NestedID   string          `json:"nestedId"`
+	Type       string          `json:"type"`
+	Status     string          `json:"status"`
+	In         []string        `json:"in"`
+	Attempt    int             `json:"attempt"`
+	StartTime  int64           `json:"startTime"`
+	EndTime    int64           `json:"endTime"`
+	UpdateTime int64           `json:"updateTime"`
+	StartedAt  time.Time       `json:"startedAt"`
+	FinishedAt time.Time       `json:"finishedAt"`
+	UpdatedAt  time.Time       `json:"updatedAt"`
+	Flow       string          `json:"flow,omitempty"`
+	Nodes      []ExecutionNode `json:"nodes,omitempty"`
+}
+
+// Duration returns how long the node ran, or 0 when it has not finished.
+func (this *ExecutionNode) Duration() time.Duration {
+	if this.StartTime <= 0 || this.EndTime < this.StartTime {
+		return 0
+	}
+	return time.Duration(this.EndTime-this.StartTime) * time.Millisecond
+}
+
+// Durations returns how long each finished node of the execution ran, nodes of embedded flows
+// are keyed by their nested id, the ids of the embedded flow nodes and of the job joined by ':'.
+func (this *ExecutionFlow) Durations() map[string]time.Duration {
+
+	durations := map[string]time.Duration{}
+
+	var walk func(prefix string, nodes []ExecutionNode)
+	walk = func(prefix string, nodes []ExecutionNode) {
+		for i := range nodes {
+			if d := nodes[i].Duration(); d > 0 {
+				durations[prefix+nodes[i].ID] = d
+			}
+			walk(prefix+nodes[i].ID+":", nodes[i].Nodes)
+		}
+	}
+
+	walk("", this.Nodes)
+
+	return durations
+
+}
+
+// AverageDurations averages the durations of the nodes over several executions.
+func AverageDurations(flows ...*ExecutionFlow) map[string]time.Duration {
+
+	total := map[string]time.Duration{}
+	count := map[string]int{}
+
+	for _, flow := range flows {
+		for id, d := range flow.Durations() {
+			total[id] += d
+			count[id]++
+		}
+	}
+
+	for id := range total {
+		total[id] /= time.Duration(count[id])
+	}
+
+	return total
+
+}
+
 type Logs struct {
 	Data   string `json:"data"`
 	Length int    `json:"length"`
@@ -79,6 +199,26 @@ type Logs struct {
 // used to avoid recursion in UnmarshalJSON below
 type execution Execution
 
+// override json.Unmarshal for Running, azkaban 3 sends the ids as numbers and 2.5 as strings
+func (this *Running) UnmarshalJSON(b []byte) error {
+
+	var raw struct {
+		IdsExecution []json.Number `json:"execIds"`
+	}
+
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	this.IdsExecution = make([]string, len(raw.IdsExecution))
+	for i, id := range raw.IdsExecution {
+		this.IdsExecution[i] = id.String()
+	}
+
+	return nil
+
+}
+
 // ExecuteFlow concurrentOption
 type concurrentOption string
 
@@ -87,20 +227,100 @@ const ConcurrentOptionIgnore = concurrentOption("ignore")
 const ConcurrentOptionPipeline = concurrentOption("pipeline")
 const ConcurrentOptionSkip = concurrentOption("skip")
 
-// override json.Unmarshal for Execution
+// override json.Unmarshal for Execution, azkaban sends times in milliseconds and -1 for
+// executions that have not started or finished yet, those times are left zero
 func (e *Execution) UnmarshalJSON(b []byte) (err error) {
 
 	x := execution{}
 
 	if err = json.Unmarshal(b, &x); err == nil {
 		*e = Execution(x)
-		e.SubmitAt = time.Unix(e.SubmitTime/1000, 0)
-		e.StartedAt = time.Unix(e.StartTime/1000, 0)
-		e.FinishedAt = time.Unix(e.EndTime/1000, 0)
-		return
+		e.SubmitAt = timeFromMillis(e.SubmitTime)
+		e.StartedAt = timeFromMillis(e.StartTime)
+		e.FinishedAt = timeFromMillis(e.EndTime)
 	}
 
-	return nil
+	return err
+}
+
+// Duration returns how long the execution ran, up to now when it is still running,
+// or 0 when it has not started.
+func (this *Execution) Duration() time.Duration {
+
+	if this.StartedAt.IsZero() {
+		return 0
+	}
+
+	if this.FinishedAt.IsZero() {
+		return time.Since(this.StartedAt)
+	}
+
+	return this.FinishedAt.Sub(this.StartedAt)
+
+}
+
+// set the times of an execution in the server time zone
+func (this *Client) localizeExecution(e *Execution) {
+	e.SubmitAt = this.localize(e.SubmitAt)
+	e.StartedAt = this.localize(e.StartedAt)
+	e.FinishedAt = this.localize(e.FinishedAt)
+}
+
+// used to avoid recursion in UnmarshalJSON below
+type executionFlow ExecutionFlow
+type executionNode ExecutionNode
+
+// override json.Unmarshal for ExecutionFlow, times are converted as for Execution
+func (this *ExecutionFlow) UnmarshalJSON(b []byte) (err error) {
+
+	x := executionFlow{}
+
+	if err = json.Unmarshal(b, &x); err == nil {
+		*this = ExecutionFlow(x)
+		this.SubmitAt = timeFromMillis(this.SubmitTime)
+		this.StartedAt = timeFromMillis(this.StartTime)
+		this.FinishedAt = timeFromMillis(this.EndTime)
+		this.UpdatedAt = timeFromMillis(this.UpdateTime)
+	}
+
+	return err
+}
+
+// override json.Unmarshal for ExecutionNode, times are converted as for Execution
+func (this *ExecutionNode) UnmarshalJSON(b []byte) (err error) {
+
+	x := executionNode{}
+
+	if err = json.Unmarshal(b, &x); err == nil {
+		*this = ExecutionNode(x)
+		this.StartedAt = timeFromMillis(this.StartTime)
+		this.FinishedAt = timeFromMillis(this.EndTime)
+		this.UpdatedAt = timeFromMillis(this.UpdateTime)
+	}
+
+	return err
+}
+
+// set the times of an execution flow and of its nodes in the server time zone
+func (this *Client) localizeExecutionFlow(f *ExecutionFlow) {
+
+	f.SubmitAt = this.localize(f.SubmitAt)
+	f.StartedAt = this.localize(f.StartedAt)
+	f.FinishedAt = this.localize(f.FinishedAt)
+	f.UpdatedAt = this.localize(f.UpdatedAt)
+
+	var walk func(nodes []ExecutionNode)
+	walk = func(nodes []ExecutionNode) {
+		for i := range nodes {
+			nodes[i].StartedAt = this.localize(nodes[i].StartedAt)
+			nodes[i].FinishedAt = this.localize(nodes[i].FinishedAt)
+			nodes[i].UpdatedAt = this.localize(nodes[i].UpdatedAt)
+			walk(nodes[i].Nodes)
+		}
+	}
+
+	walk(f.Nodes)
+
 }
 
 // create a new Decode for Execution
@@ -109,41 +329,109 @@ func Decode(r io.Reader) (exe *Execution, err error) {
 	return
 }
 
+// CreateCommandJob adds a command job to an existing project, or replaces the commands of the job
+// when the project already has it. The current archive is downloaded, the job merged in and the
+// archive uploaded again, all in memory. Other properties of a replaced job, like its
+// dependencies, are kept.
 func (this *Client) CreateCommandJob(project, job string, commands ...string) error {
 
-	// get default temp dir
-	temp := "/tmp/"
-
-	// windows?
-	if strings.Contains(os.Getenv("OS"), "Windows") {
-		temp = "C:\\Windows\\Temp\\"
+	// check if project exists
+	if _, err := this.GetProject(project); err != nil {
+		return err
 	}
 
-	// mount file path
-	jobname := fmt.Sprintf("%s%s.job", temp, job)
-	zipname := fmt.Sprintf("%s%s.zip", temp, job)
+	// download the current archive, a new project has none
+	var archive bytes.Buffer
+	var files []File
 
-	// write job file
-	if err := WriteFile(jobname); err != nil {
+	err := this.DownloadProject(context.Background(), project, 0, &archive)
+	if err == nil {
+		files, err = readZip(archive.Bytes())
+	}
+	if err != nil && err != ArchiveNotFound {
 		return err
 	}
 
-	// zip job file
-	if err := ZipFiles(zipname, jobname); err != nil {
+	// merge the job into the archive
+	if files, err = mergeCommandJob(files, job, commands); err != nil {
 		return err
 	}
 
-	// upload zip file to project
-	if err := this.UploadProjectZip(project, zipname); err != nil {
+	archive.Reset()
+	if err = writeZip(&archive, files); err != nil {
 		return err
 	}
 
-	// delete temp files
-	if err := DeleteFiles(jobname, zipname); err != nil {
-		return err
+	// upload the merged archive
+	_, err = this.UploadProject(context.Background(), project, &archive, project+".zip", nil)
+
+	return err
+
+}
+
+func mergeCommandJob(files []File, job string, commands []string) ([]File, error) {
+
+	definition := CommandJob{Commands: commands}
+
+	props := definition.Properties()
+	props["type"] = definition.Type()
+
+	// replace the job where it is, keeping the properties that are not about its commands
+	for i, file := range files {
+
+		if path.Base(file.Name) != job+".job" {
+			continue
+		}
+
+		// an unreadable job would lose its properties
+		existing, err := ReadProperties(bytes.NewReader(file.Body))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file.Name, err)
+		}
+
+		for _, prop := range existing {
+			if prop.Key != "type" && prop.Key != "command" && !strings.HasPrefix(prop.Key, "command.") {
+				props[prop.Key] = prop.Value
+			}
+		}
+
+		files[i].Body = formatProperties(props)
+
+		return files, nil
+
 	}
 
-	return nil
+	// a new job goes in the directory all the files are in, so it inherits their properties
+	name := job + ".job"
+	if dir := commonDir(files); dir != "" {
+		name = dir + "/" + name
+	}
+
+	return append(files, File{Name: name, Body: formatProperties(props)}), nil
+
+}
+
+// the top directory of every file, or "" when the files are not in a single directory
+func commonDir(files []File) string {
+
+	dir := ""
+
+	for i, file := range files {
+
+		top := ""
+		if parts := strings.SplitN(file.Name, "/", 2); len(parts) == 2 {
+			top = parts[0]
+		}
+
+		if i == 0 {
+			dir = top
+		} else if top != dir {
+			return ""
+		}
+
+	}
+
+	return dir
 
 }
 
@@ -214,25 +502,60 @@ func (this *Client) FetchExecutions(project, flow string, start, length int) (*E
 
 	// project exists
 	if err == nil {
+		return this.fetchExecutions(project, flow, start, length)
+	}
 
-		// set form parameters
-		values := url.Values{}
-		values.Add("ajax", "fetchFlowExecutions")
-		values.Add("session.id", this.Session)
-		values.Add("project", project)
-		values.Add("flow", flow)
-		values.Add("start", strconv.Itoa(start))
-		values.Add("length", strconv.Itoa(length))
+	return &executions, err
 
-		// try to get project flows
-		err = this.action(http.MethodGet, "/manager", values, &executions)
+}
+
+// FetchExecutions of a project known to exist
+func (this *Client) fetchExecutions(project, flow string, start, length int) (*Executions, error) {
 
+	// init return
+	var executions Executions
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", "fetchFlowExecutions")
+	values.Add("session.id", this.Session)
+	values.Add("project", project)
+	values.Add("flow", flow)
+	values.Add("start", strconv.Itoa(start))
+	values.Add("length", strconv.Itoa(length))
+
+	// try to get project flows
+	err := this.action(http.MethodGet, "/manager", values, &executions)
+
+	for i := range executions.Execution {
+		this.localizeExecution(&executions.Execution[i])
 	}
 
 	return &executions, err
 
 }
 
+// Given an execution id, this API call fetches the state of the execution and of each of its nodes.
+func (this *Client) FetchExecutionFlow(executionId int64) (*ExecutionFlow, error) {
+
+	// init return
+	var flow ExecutionFlow
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", "fetchexecflow")
+	values.Add("session.id", this.Session)
+	values.Add("execid", strconv.FormatInt(executionId, 10))
+
+	// try to get execution flow
+	err := this.action(http.MethodGet, "/executor", values, &flow)
+
+	this.localizeExecutionFlow(&flow)
+
+	return &flow, err
+
+}
+
 // Given a project name and a flow id, this API call fetches only executions that are currently running.
 func (this *Client) FetchRunningExecutions(project, flow string) (*Running, error) {
 
@@ -282,9 +605,22 @@ func (this *Client) ExecuteFlow(project, flow string, concurrentOption concurren
 		if concurrentOption != ConcurrentOptionDefault {
 			values.Add("concurrentOption", string(concurrentOption))
 		}
+		for k, v := range this.Defaults.FlowOverrides {
+			if _, ok := flowOverrides[k]; !ok {
+				values.Add(fmt.Sprintf("flowOverride[%s]", k), v)
+			}
+		}
 		for k, v := range flowOverrides {
 			values.Add(fmt.Sprintf("flowOverride[%s]", k), v)
 		}
+		if len(this.Defaults.FailureEmails) > 0 {
+			values.Add("failureEmailsOverride", "true")
+			values.Add("failureEmails", strings.Join(this.Defaults.FailureEmails, ","))
+		}
+		if len(this.Defaults.SuccessEmails) > 0 {
+			values.Add("successEmailsOverride", "true")
+			values.Add("successEmails", strings.Join(this.Defaults.SuccessEmails, ","))
+		}
 
 		// try to get project flows
 		err = this.action(http.MethodGet, "/executor", values, &execute)
@@ -330,6 +666,62 @@ func (this *Client) CancelFlow(project, flow string) error {
 
 }
 
+// Given an execution id, this API call cancels the execution. If it is not running, an error is returned.
+func (this *Client) CancelExecution(executionId int64) error {
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", "cancelFlow")
+	values.Add("session.id", this.Session)
+	values.Add("execid", strconv.FormatInt(executionId, 10))
+
+	// an empty struct will return if succeeds
+	var response map[string]string
+
+	// request api
+	return this.action(http.MethodGet, "/executor", values, &response)
+
+}
+
+// statuses an execution does not leave
+var finalStatuses = map[string]bool{
+	ExecutionStatus.Succeeded: true,
+	ExecutionStatus.Failed:    true,
+	ExecutionStatus.Killed:    true,
+	ExecutionStatus.Cancelled: true,
+	ExecutionStatus.Skipped:   true,
+}
+
+// FinalStatus reports whether an execution or a node in this status will not change anymore.
+func FinalStatus(status string) bool {
+	return finalStatuses[status]
+}
+
+// Finished reports whether the execution reached a final status.
+func (this *ExecutionFlow) Finished() bool {
+	return FinalStatus(this.Status)
+}
+
+// WaitExecution polls an execution every interval until it finishes or ctx is done, and returns its last state.
+func (this *Client) WaitExecution(ctx context.Context, executionId int64, interval time.Duration) (*ExecutionFlow, error) {
+
+	for {
+
+		flow, err := this.FetchExecutionFlow(executionId)
+		if err != nil || flow.Finished() {
+			return flow, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return flow, ctx.Err()
+		case <-time.After(interval):
+		}
+
+	}
+
+}
+
 // This API call schedules a flow.
 func (this *Client) ScheduleFlow(project *Project, flow string, schedule time.Time, repeat, period string) (*Detail, error) {
 
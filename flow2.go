@@ -0,0 +1,519 @@
+package azkaban
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Flow2Version is the azkaban-flow-version of Flow 2.0 projects.
+const Flow2Version = "2.0"
+
+// Flow2ProjectFile is the content of the .project file that marks a Flow 2.0 project.
+type Flow2ProjectFile struct {
+	FlowVersion string `yaml:"azkaban-flow-version"`
+}
+
+// Flow2 is a Flow 2.0 .flow file, the flow is named after the file.
+type Flow2 struct {
+	Name   string            `yaml:"-"`
+	Config map[string]string `yaml:"config,omitempty"`
+	Nodes  []*Flow2Node      `yaml:"nodes"`
+}
+
+// Flow2Node is a job or, when its type is flow, an embedded flow.
+type Flow2Node struct {
+	Name      string            `yaml:"name"`
+	Type      string            `yaml:"type"`
+	DependsOn []string          `yaml:"dependsOn,omitempty"`
+	Condition string            `yaml:"condition,omitempty"`
+	Config    map[string]string `yaml:"config,omitempty"`
+	Nodes     []*Flow2Node      `yaml:"nodes,omitempty"`
+}
+
+// Flow2Project is a Flow 2.0 project, its name is the name of the .project file.
+type Flow2Project struct {
+	Name  string
+	Flows []*Flow2
+}
+
+// UnmarshalFlow2 parses the content of a .flow file.
+func UnmarshalFlow2(name string, b []byte) (*Flow2, error) {
+
+	flow := &Flow2{}
+
+	if err := yaml.Unmarshal(b, flow); err != nil {
+		return nil, fmt.Errorf("%s.flow: %v", name, err)
+	}
+
+	flow.Name = name
+
+	return flow, nil
+
+}
+
+// Marshal renders the content of the .flow file.
+func (this *Flow2) Marshal() ([]byte, error) {
+
+	var buff bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buff)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(this); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+
+}
+
+// Validate checks the flow the way azkaban does on upload, every problem found is reported.
+func (this *Flow2) Validate() error {
+
+	var problems []string
+
+	if this.Name == "" {
+		problems = append(problems, "flow has no name")
+	}
+
+	if len(this.Nodes) == 0 {
+		problems = append(problems, fmt.Sprintf("flow %s has no nodes", this.Name))
+	}
+
+	problems = append(problems, validateFlow2Nodes(this.Name, this.Nodes)...)
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+
+	return nil
+
+}
+
+func validateFlow2Nodes(scope string, nodes []*Flow2Node) []string {
+
+	var problems []string
+
+	names := make([]string, 0, len(nodes))
+	byName := map[string]*Flow2Node{}
+
+	for _, node := range nodes {
+
+		switch {
+		case node.Name == "":
+			problems = append(problems, fmt.Sprintf("%s: node without a name", scope))
+			continue
+		case byName[node.Name] != nil:
+			problems = append(problems, fmt.Sprintf("%s: duplicate node %s", scope, node.Name))
+			continue
+		}
+
+		names = append(names, node.Name)
+		byName[node.Name] = node
+
+		if node.Type == "" {
+			problems = append(problems, fmt.Sprintf("%s: node %s has no type", scope, node.Name))
+		}
+
+		// only embedded flows have nodes
+		if node.Type == JobType.Flow {
+			if len(node.Nodes) == 0 {
+				problems = append(problems, fmt.Sprintf("%s: embedded flow %s has no nodes", scope, node.Name))
+			}
+			problems = append(problems, validateFlow2Nodes(scope+":"+node.Name, node.Nodes)...)
+		} else if len(node.Nodes) > 0 {
+			problems = append(problems, fmt.Sprintf("%s: node %s of type %s cannot have nodes", scope, node.Name, node.Type))
+		}
+
+	}
+
+	for _, node := range nodes {
+		for _, dep := range node.DependsOn {
+			if byName[dep] == nil {
+				problems = append(problems, fmt.Sprintf("%s: node %s depends on unknown node %s", scope, node.Name, dep))
+			}
+		}
+	}
+
+	// unknown dependencies are reported above
+	cycle := findCycle(names, func(name string) []string {
+		if node := byName[name]; node != nil {
+			return node.DependsOn
+		}
+		return nil
+	})
+
+	if cycle != nil {
+		problems = append(problems, fmt.Sprintf("%s: dependency cycle %s", scope, strings.Join(cycle, " -> ")))
+	}
+
+	return problems
+
+}
+
+// Validate checks every flow of the project.
+func (this *Flow2Project) Validate() error {
+
+	var problems []string
+
+	if this.Name == "" {
+		problems = append(problems, "project has no name")
+	}
+
+	seen := map[string]bool{}
+
+	for _, flow := range this.Flows {
+		if seen[flow.Name] {
+			problems = append(problems, fmt.Sprintf("duplicate flow %s", flow.Name))
+		}
+		seen[flow.Name] = true
+		if err := flow.Validate(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+
+	return nil
+
+}
+
+// Files renders the .project and .flow files of the project.
+func (this *Flow2Project) Files() ([]File, error) {
+
+	if err := this.Validate(); err != nil {
+		return nil, err
+	}
+
+	version, err := yaml.Marshal(Flow2ProjectFile{FlowVersion: Flow2Version})
+	if err != nil {
+		return nil, err
+	}
+
+	files := []File{{Name: this.Name + ".project", Body: version}}
+
+	for _, flow := range this.Flows {
+
+		body, err := flow.Marshal()
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, File{Name: flow.Name + ".flow", Body: body})
+
+	}
+
+	return files, nil
+
+}
+
+// WriteZip renders the project into a zip archive, ready for UploadProject.
+func (this *Flow2Project) WriteZip(w io.Writer) error {
+
+	files, err := this.Files()
+	if err != nil {
+		return err
+	}
+
+	return writeZip(w, files)
+
+}
+
+// LoadFlow2Project reads a Flow 2.0 project from a directory or a .zip archive.
+func LoadFlow2Project(name string) (*Flow2Project, error) {
+
+	files, err := readProjectFiles(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadFlow2Project(files)
+
+}
+
+// ReadFlow2Project parses the .project and .flow files of a project, other files are ignored.
+func ReadFlow2Project(files []File) (*Flow2Project, error) {
+
+	project := &Flow2Project{}
+
+	for _, file := range files {
+
+		base := path.Base(file.Name)
+		ext := path.Ext(base)
+
+		switch ext {
+
+		case ".project":
+
+			var version Flow2ProjectFile
+			if err := yaml.Unmarshal(file.Body, &version); err != nil {
+				return nil, fmt.Errorf("%s: %v", file.Name, err)
+			}
+
+			if version.FlowVersion != Flow2Version {
+				return nil, fmt.Errorf("%s: unsupported azkaban-flow-version %q", file.Name, version.FlowVersion)
+			}
+
+			project.Name = strings.TrimSuffix(base, ext)
+
+		case ".flow":
+
+			flow, err := UnmarshalFlow2(strings.TrimSuffix(base, ext), file.Body)
+			if err != nil {
+				return nil, err
+			}
+
+			project.Flows = append(project.Flows, flow)
+
+		}
+
+	}
+
+	if project.Name == "" {
+		return nil, errors.New("no .project file found, not a Flow 2.0 project")
+	}
+
+	sort.Slice(project.Flows, func(i, j int) bool { return project.Flows[i].Name < project.Flows[j].Name })
+
+	return project, nil
+
+}
+
+// ConvertToFlow2 converts a legacy project into Flow 2.0, with a .flow file for every flow.
+// Embedded flows are inlined, properties files at the root of the project become the flow
+// config and properties files in sub directories are merged into the config of their jobs.
+func ConvertToFlow2(project *LocalProject, name string) (*Flow2Project, error) {
+
+	converted := &Flow2Project{Name: name}
+
+	// properties at the project root apply to the whole flow
+	config := map[string]string{}
+	for _, file := range project.Properties {
+		if path.Dir(file.File) == "." {
+			for _, prop := range file.Props {
+				config[prop.Key] = prop.Value
+			}
+		}
+	}
+
+	for _, flow := range project.Flows() {
+
+		nodes, err := convertToFlow2Nodes(project, flow, []string{flow})
+		if err != nil {
+			return nil, err
+		}
+
+		converted.Flows = append(converted.Flows, &Flow2{
+			Name:   flow,
+			Config: copyConfig(config),
+			Nodes:  nodes,
+		})
+
+	}
+
+	return converted, nil
+
+}
+
+func convertToFlow2Nodes(project *LocalProject, flow string, embedding []string) ([]*Flow2Node, error) {
+
+	var nodes []*Flow2Node
+
+	for _, job := range project.FlowJobs(flow) {
+
+		node := &Flow2Node{
+			Name:      job.Name,
+			Type:      job.Type(),
+			DependsOn: job.Dependencies(),
+			Config:    map[string]string{},
+		}
+
+		for _, prop := range job.Props {
+			switch prop.Key {
+			case "type", "dependencies":
+			case "condition":
+				node.Condition = prop.Value
+			default:
+				node.Config[prop.Key] = prop.Value
+			}
+		}
+
+		// inherit properties of sub directories, the nearest directory wins
+		for _, file := range project.Properties {
+			dir := path.Dir(file.File)
+			if dir == "." || !(job.Dir() == dir || strings.HasPrefix(job.Dir(), dir+"/")) {
+				continue
+			}
+			for _, prop := range file.Props {
+				if _, ok := node.Config[prop.Key]; !ok {
+					node.Config[prop.Key] = prop.Value
+				}
+			}
+		}
+
+		// inline embedded flows
+		if node.Type == JobType.Flow {
+
+			embedded := node.Config["flow.name"]
+			delete(node.Config, "flow.name")
+
+			for _, name := range embedding {
+				if name == embedded {
+					return nil, fmt.Errorf("flow %s embeds itself through %s", embedded, strings.Join(embedding, " -> "))
+				}
+			}
+
+			var err error
+			if node.Nodes, err = convertToFlow2Nodes(project, embedded, append(embedding, embedded)); err != nil {
+				return nil, err
+			}
+
+		}
+
+		if len(node.Config) == 0 {
+			node.Config = nil
+		}
+
+		nodes = append(nodes, node)
+
+	}
+
+	return nodes, nil
+
+}
+
+func copyConfig(config map[string]string) map[string]string {
+	if len(config) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(config))
+	for k, v := range config {
+		copied[k] = v
+	}
+	return copied
+}
+
+// ConvertFromFlow2 converts a Flow 2.0 project into legacy project files.
+// Every flow gets its own directory holding its jobs and a flow.properties with its config.
+// Azkaban names legacy flows after their last job, so when no node carries the flow name a
+// noop job closing the flow is added, embedded flows are closed the same way by a job
+// suffixed with _end. Job names must be unique across the whole legacy project.
+func ConvertFromFlow2(project *Flow2Project) ([]File, error) {
+
+	if err := project.Validate(); err != nil {
+		return nil, err
+	}
+
+	var files []File
+
+	for _, flow := range project.Flows {
+
+		if len(flow.Config) > 0 {
+			files = append(files, File{Name: flow.Name + "/flow.properties", Body: formatProperties(flow.Config)})
+		}
+
+		jobs, err := convertFromFlow2Nodes(flow.Name, flow.Name, flow.Nodes)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, jobs...)
+
+	}
+
+	// job names are global, detect clashes between directories
+	seen := map[string]string{}
+	for _, file := range files {
+		if path.Ext(file.Name) != ".job" {
+			continue
+		}
+		base := path.Base(file.Name)
+		if other, ok := seen[base]; ok {
+			return nil, fmt.Errorf("job %s is defined in %s and %s", strings.TrimSuffix(base, ".job"), other, file.Name)
+		}
+		seen[base] = file.Name
+	}
+
+	return files, nil
+
+}
+
+func convertFromFlow2Nodes(dir, last string, nodes []*Flow2Node) ([]File, error) {
+
+	var files []File
+
+	depended := map[string]bool{}
+	closed := false
+
+	for _, node := range nodes {
+
+		props := copyConfig(node.Config)
+		if props == nil {
+			props = map[string]string{}
+		}
+
+		props["type"] = node.Type
+		if len(node.DependsOn) > 0 {
+			props["dependencies"] = strings.Join(node.DependsOn, ",")
+		}
+		if node.Condition != "" {
+			props["condition"] = node.Condition
+		}
+
+		if node.Type == JobType.Flow {
+
+			// the embedded flow is named after its closing job
+			end := node.Name + "_end"
+			props["flow.name"] = end
+
+			embedded, err := convertFromFlow2Nodes(dir+"/"+node.Name, end, node.Nodes)
+			if err != nil {
+				return nil, err
+			}
+
+			files = append(files, embedded...)
+
+		}
+
+		for _, dep := range node.DependsOn {
+			depended[dep] = true
+		}
+
+		if node.Name == last {
+			closed = true
+		}
+
+		files = append(files, File{Name: dir + "/" + node.Name + ".job", Body: formatProperties(props)})
+
+	}
+
+	if !closed {
+
+		var leaves []string
+		for _, node := range nodes {
+			if !depended[node.Name] {
+				leaves = append(leaves, node.Name)
+			}
+		}
+
+		props := map[string]string{"type": JobType.Noop, "dependencies": strings.Join(leaves, ",")}
+		files = append(files, File{Name: dir + "/" + last + ".job", Body: formatProperties(props)})
+
+	}
+
+	return files, nil
+
+}
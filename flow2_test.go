@@ -0,0 +1,88 @@
+package azkaban
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const FLOW_DIR_PATH = "./testdata/testflow"
+const FLOW_ZIP_PATH = "./testdata/testflow.zip"
+
+func TestFlow2Convert(t *testing.T) {
+	legacy, err := LoadProject(FLOW_DIR_PATH)
+	assert.Nil(t, err)
+
+	project, err := ConvertToFlow2(legacy, "testflow")
+	assert.Nil(t, err)
+	assert.Nil(t, project.Validate())
+	assert.Len(t, project.Flows, 1)
+
+	flow := project.Flows[0]
+	assert.Equal(t, "bar", flow.Name)
+	assert.Equal(t, map[string]string{"test.p1": "10", "test.p2": "p2"}, flow.Config)
+	assert.Len(t, flow.Nodes, 2)
+	assert.Equal(t, []string{"foo"}, flow.Nodes[1].DependsOn)
+
+	// zip and read back
+	var buff bytes.Buffer
+	assert.Nil(t, project.WriteZip(&buff))
+	files, err := readZip(buff.Bytes())
+	assert.Nil(t, err)
+	read, err := ReadFlow2Project(files)
+	assert.Nil(t, err)
+	assert.Equal(t, project, read)
+
+	// and back to the legacy format
+	files, err = ConvertFromFlow2(read)
+	assert.Nil(t, err)
+	back, err := ReadProject(files)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"bar"}, back.Flows())
+	assert.Equal(t, "foo", back.Job("bar").Get("dependencies"))
+	assert.Equal(t, `echo "hello foo, p1:" ${test.p1}`, back.Job("foo").Get("command"))
+}
+
+func TestFlow2Validate(t *testing.T) {
+	flow, err := UnmarshalFlow2("daily", []byte(`
+config:
+  retries: 3
+nodes:
+  - name: a
+    type: command
+    dependsOn: [b]
+  - name: b
+    type: command
+    dependsOn: [a, c]
+  - name: sub
+    type: flow
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, "3", flow.Config["retries"])
+
+	err = flow.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "depends on unknown node c")
+		assert.Contains(t, err.Error(), "dependency cycle a -> b -> a")
+		assert.Contains(t, err.Error(), "embedded flow sub has no nodes")
+	}
+}
+
+func TestFlow2ValidateUnknownDependency(t *testing.T) {
+	flow, err := UnmarshalFlow2("daily", []byte(`
+nodes:
+  - name: a
+    type: command
+    dependsOn: [c]
+`))
+	assert.Nil(t, err)
+
+	err = flow.Validate()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "depends on unknown node c")
+	}
+
+	_, err = ConvertFromFlow2(&Flow2Project{Name: "daily", Flows: []*Flow2{flow}})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,93 @@
+package azkaban
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutionUnmarshal(t *testing.T) {
+	var e Execution
+	err := json.Unmarshal([]byte(`{"execId":7,"submitTime":1529740141123,"startTime":1529740142456,"endTime":-1,"status":"RUNNING"}`), &e)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1529740141123), e.SubmitAt.UnixNano()/int64(time.Millisecond))
+	assert.Equal(t, 456*time.Millisecond, time.Duration(e.StartedAt.Nanosecond()))
+	assert.True(t, e.FinishedAt.IsZero())
+	assert.True(t, e.Duration() > 0)
+
+	e.FinishedAt = e.StartedAt.Add(1500 * time.Millisecond)
+	assert.Equal(t, 1500*time.Millisecond, e.Duration())
+
+	err = json.Unmarshal([]byte(`{"execId":"seven"}`), &e)
+	assert.NotNil(t, err)
+
+	var pending Execution
+	assert.Nil(t, json.Unmarshal([]byte(`{"execId":8,"submitTime":1529740141123,"startTime":-1,"endTime":-1}`), &pending))
+	assert.True(t, pending.StartedAt.IsZero())
+	assert.Equal(t, time.Duration(0), pending.Duration())
+}
+
+func TestMergeCommandJob(t *testing.T) {
+	files := []File{
+		{Name: "etl/flow.properties", Body: []byte("user.to.proxy=etl\n")},
+		{Name: "etl/load.job", Body: []byte("type=command\ncommand=echo old\ncommand.1=echo older\ndependencies=extract\nretries=2\n")},
+	}
+
+	// a replaced job keeps its other properties
+	files, err := mergeCommandJob(files, "load", []string{"echo load"})
+	assert.Nil(t, err)
+	assert.Len(t, files, 2)
+	assert.Equal(t, "type=command\ncommand=echo load\ndependencies=extract\nretries=2\n", string(files[1].Body))
+
+	// a new job goes in the directory of the project
+	files, err = mergeCommandJob(files, "report", []string{"echo report", "echo done"})
+	assert.Nil(t, err)
+	if assert.Len(t, files, 3) {
+		assert.Equal(t, "etl/report.job", files[2].Name)
+		assert.Equal(t, "type=command\ncommand=echo report\ncommand.1=echo done\n", string(files[2].Body))
+	}
+
+	// an unreadable job is not replaced
+	_, err = mergeCommandJob([]File{{Name: "bad.job", Body: []byte(`command=\u12`)}}, "bad", []string{"echo bad"})
+	assert.EqualError(t, err, `bad.job: line 1: malformed \uxxxx escape in "\\u12"`)
+}
+
+func TestExecutionFlowUnmarshal(t *testing.T) {
+	var f ExecutionFlow
+	err := json.Unmarshal([]byte(`{"execid":7,"submitTime":1529740141123,"startTime":1529740142456,"endTime":-1,"updateTime":1529740143000,
+		"nodes":[{"id":"foo","startTime":1529740142456,"endTime":1529740143000,"nodes":[{"id":"bar","startTime":-1,"endTime":-1}]}]}`), &f)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1529740141123), f.SubmitAt.UnixNano()/int64(time.Millisecond))
+	assert.Equal(t, int64(1529740142456), f.StartedAt.UnixNano()/int64(time.Millisecond))
+	assert.True(t, f.FinishedAt.IsZero())
+	assert.Equal(t, int64(1529740143000), f.UpdatedAt.UnixNano()/int64(time.Millisecond))
+
+	node := f.Nodes[0]
+	assert.Equal(t, 544*time.Millisecond, node.FinishedAt.Sub(node.StartedAt))
+	assert.True(t, node.Nodes[0].StartedAt.IsZero())
+
+	shanghai := time.FixedZone("CST", 8*3600)
+	client := &Client{Location: shanghai}
+	client.localizeExecutionFlow(&f)
+	assert.Equal(t, shanghai, f.SubmitAt.Location())
+	assert.Equal(t, shanghai, f.Nodes[0].StartedAt.Location())
+	assert.True(t, f.FinishedAt.IsZero())
+	assert.True(t, f.Nodes[0].Nodes[0].StartedAt.IsZero())
+}
+
+func TestRunningUnmarshal(t *testing.T) {
+	// numbers from azkaban 3, strings from azkaban 2.5
+	var r Running
+	assert.Nil(t, json.Unmarshal([]byte(`{"execIds":[12,13]}`), &r))
+	assert.Equal(t, []string{"12", "13"}, r.IdsExecution)
+
+	assert.Nil(t, json.Unmarshal([]byte(`{"execIds":["14"]}`), &r))
+	assert.Equal(t, []string{"14"}, r.IdsExecution)
+
+	assert.Nil(t, json.Unmarshal([]byte(`{}`), &r))
+	assert.Empty(t, r.IdsExecution)
+
+	assert.NotNil(t, json.Unmarshal([]byte(`{"execIds":["fourteen"]}`), &r))
+}
@@ -0,0 +1,167 @@
+package azkaban
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+type FlowInfo struct {
+	SuccessEmails []string `json:"successEmails"`
+	FailureEmails []string `json:"failureEmails"`
+}
+
+type ExecutionOptions struct {
+	FlowParameters     map[string]string `json:"flowParam"`
+	SuccessEmails      []string          `json:"successEmails"`
+	FailureEmails      []string          `json:"failureEmails"`
+	NotifyFailureFirst bool              `json:"notifyFailureFirst"`
+	NotifyFailureLast  bool              `json:"notifyFailureLast"`
+	FailureAction      string            `json:"failureAction"`
+	ConcurrentOptions  string            `json:"concurrentOptions"`
+	PipelineLevel      int               `json:"pipelineLevel"`
+}
+
+// FlowProperties gathers the metadata of a flow: its notification settings, the parameters its
+// properties files define, the properties files its jobs inherit and the flows it embeds.
+type FlowProperties struct {
+	Project         string
+	Flow            string
+	SuccessEmails   []string
+	FailureEmails   []string
+	Parameters      map[string]string
+	PropertiesFiles []string
+	EmbeddedFlows   []string
+}
+
+// Given a project and a flow, this API call fetches the notification settings of the flow.
+func (this *Client) FetchFlowInfo(project, flow string) (*FlowInfo, error) {
+
+	// init return
+	var info FlowInfo
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", "flowInfo")
+	values.Add("session.id", this.Session)
+	values.Add("project", project)
+	values.Add("flow", flow)
+
+	// try to get flow info
+	err := this.action(http.MethodGet, "/executor", values, &info)
+
+	return &info, err
+
+}
+
+// Given an execution id, this API call fetches the options the flow was executed with,
+// including the flow parameters.
+func (this *Client) FetchExecutionOptions(executionId int64) (*ExecutionOptions, error) {
+
+	// init return
+	var options ExecutionOptions
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", "flowInfo")
+	values.Add("session.id", this.Session)
+	values.Add("execid", strconv.FormatInt(executionId, 10))
+
+	// try to get execution options
+	err := this.action(http.MethodGet, "/executor", values, &options)
+
+	return &options, err
+
+}
+
+// FetchFlowProperties fetches the metadata of a flow. The parameters are the properties defined by
+// the properties files the flow's job inherits, the nearest directory winning, read from the latest
+// archive of the project. Neither the runtime overrides of executions nor the properties of the
+// job files are included.
+//
+// Azkaban does not serve the properties files alone, so the whole archive is downloaded on every
+// call. Use FetchFlowPropertiesFrom to read the files of several flows from a single download.
+func (this *Client) FetchFlowProperties(project, flow string) (*FlowProperties, error) {
+
+	// properties files of the latest archive, a project without any upload has none
+	model, err := this.fetchFlowModel(project, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return this.FetchFlowPropertiesFrom(model, project, flow)
+
+}
+
+// FetchFlowPropertiesFrom fetches the metadata of a flow as FetchFlowProperties does, reading the
+// properties files from model, such as the project the archive was packaged from, rather than
+// downloading the archive.
+func (this *Client) FetchFlowPropertiesFrom(model *LocalProject, project, flow string) (*FlowProperties, error) {
+
+	jobs, err := this.FetchJobs(project, flow)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := this.FetchFlowInfo(project, flow)
+	if err != nil {
+		return nil, err
+	}
+
+	props := &FlowProperties{
+		Project:       project,
+		Flow:          flow,
+		SuccessEmails: info.SuccessEmails,
+		FailureEmails: info.FailureEmails,
+		Parameters:    map[string]string{},
+	}
+
+	// walk the graph and the graphs of embedded flows
+	files := map[string]bool{}
+	embedded := map[string]bool{}
+
+	var walk func(nodes []Node)
+	walk = func(nodes []Node) {
+		for i := range nodes {
+			if nodes[i].PropSource != "" {
+				files[nodes[i].PropSource] = true
+			}
+			if id := nodes[i].EmbeddedFlow(); id != "" {
+				embedded[id] = true
+			}
+			walk(nodes[i].Nodes)
+		}
+	}
+
+	walk(jobs.Nodes)
+
+	for _, job := range model.FlowJobs(flow) {
+		for _, file := range model.inheritedProperties(job) {
+			files[file.File] = true
+		}
+	}
+
+	if job := model.Job(flow); job != nil {
+		for _, file := range model.inheritedProperties(job) {
+			for _, prop := range file.Props {
+				props.Parameters[prop.Key] = prop.Value
+			}
+		}
+	}
+
+	props.PropertiesFiles = sortedKeys(files)
+	props.EmbeddedFlows = sortedKeys(embedded)
+
+	return props, nil
+
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
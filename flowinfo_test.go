@@ -0,0 +1,74 @@
+package azkaban_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wanglun/azkaban"
+	"github.com/wanglun/azkaban/azkabantest"
+)
+
+func TestFetchFlowProperties(t *testing.T) {
+
+	server := azkabantest.NewServer()
+	defer server.Close()
+
+	client, err := server.Client()
+	assert.Nil(t, err)
+
+	_, err = client.CreateProject(PROJECT_NAME, PROJECT_DESC)
+	assert.Nil(t, err)
+
+	var archive bytes.Buffer
+	w := zip.NewWriter(&archive)
+	for name, body := range map[string]string{
+		"root.properties":        "env=prod\ntable=root\n",
+		"etl/etl.properties":     "table=events\n",
+		"etl/load.job":           "type=command\ncommand=echo ${table}\ntable=job\n",
+		"etl/daily.job":          "type=noop\ndependencies=load\n",
+		"other/other.properties": "other=true\n",
+		"other/solo.job":         "type=noop\n",
+	} {
+		f, err := w.Create(name)
+		assert.Nil(t, err)
+		f.Write([]byte(body))
+	}
+	assert.Nil(t, w.Close())
+
+	uploaded := archive.Bytes()
+	_, err = client.UploadProject(context.Background(), PROJECT_NAME, bytes.NewReader(uploaded), "etl.zip", nil)
+	assert.Nil(t, err)
+
+	// the files of the flow's directory and of its parents, the nearest winning
+	props, err := client.FetchFlowProperties(PROJECT_NAME, "daily")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "table": "events"}, props.Parameters)
+	assert.Equal(t, []string{"etl/etl.properties", "root.properties"}, props.PropertiesFiles)
+
+	// runtime overrides are not parameters of the flow
+	_, err = client.ExecuteFlow(PROJECT_NAME, "daily", azkaban.ConcurrentOptionIgnore, map[string]string{"table": "override"})
+	assert.Nil(t, err)
+	props, err = client.FetchFlowProperties(PROJECT_NAME, "daily")
+	assert.Nil(t, err)
+	assert.Equal(t, "events", props.Parameters["table"])
+
+	// the same properties from the project the archive was packaged from, without a download
+	name := filepath.Join(t.TempDir(), "etl.zip")
+	assert.Nil(t, ioutil.WriteFile(name, uploaded, 0644))
+	model, err := azkaban.LoadProject(name)
+	assert.Nil(t, err)
+
+	requests := &countRequests{}
+	client.HTTPClient = &http.Client{Transport: requests}
+	from, err := client.FetchFlowPropertiesFrom(model, PROJECT_NAME, "daily")
+	assert.Nil(t, err)
+	assert.Equal(t, props, from)
+	assert.Equal(t, []string{"fetchprojectflows", "fetchflowgraph", "flowInfo"}, requests.ajax)
+
+}
@@ -0,0 +1,292 @@
+package azkaban
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Graph is the dependency graph of a flow, built from the nodes returned by FetchJobs.
+// Node ids are those of the flow's own level, embedded flows have their own graph, see SubGraph.
+type Graph struct {
+	Flow string
+
+	// nested id prefix of the nodes, "" for a top level flow
+	prefix string
+
+	ids      []string
+	nodes    map[string]*Node
+	parents  map[string][]string
+	children map[string][]string
+}
+
+// NewGraph builds the graph of a flow.
+func NewGraph(jobs *Jobs) *Graph {
+	return newGraph(jobs.Flow, "", jobs.Nodes)
+}
+
+func newGraph(flow, prefix string, nodes []Node) *Graph {
+
+	g := &Graph{
+		Flow:     flow,
+		prefix:   prefix,
+		nodes:    map[string]*Node{},
+		parents:  map[string][]string{},
+		children: map[string][]string{},
+	}
+
+	for i := range nodes {
+		node := &nodes[i]
+		if _, ok := g.nodes[node.ID]; !ok {
+			g.ids = append(g.ids, node.ID)
+		}
+		g.nodes[node.ID] = node
+	}
+
+	// edges to unknown nodes are dropped
+	for _, id := range g.ids {
+		for _, parent := range g.nodes[id].In {
+			if _, ok := g.nodes[parent]; ok {
+				g.parents[id] = append(g.parents[id], parent)
+				g.children[parent] = append(g.children[parent], id)
+			}
+		}
+	}
+
+	return g
+
+}
+
+// Nodes returns the ids of the nodes, in the order azkaban returned them.
+func (this *Graph) Nodes() []string {
+	return append([]string{}, this.ids...)
+}
+
+// Node returns a node, or nil.
+func (this *Graph) Node(id string) *Node {
+	return this.nodes[id]
+}
+
+// NestedID returns the id azkaban uses for the node in executions, prefixed by the embedded flow nodes.
+func (this *Graph) NestedID(id string) string {
+	return this.prefix + id
+}
+
+// Parents returns the nodes a node depends on.
+func (this *Graph) Parents(id string) []string {
+	return append([]string{}, this.parents[id]...)
+}
+
+// Children returns the nodes depending on a node.
+func (this *Graph) Children(id string) []string {
+	return append([]string{}, this.children[id]...)
+}
+
+// Roots returns the nodes without dependencies, they start the flow.
+func (this *Graph) Roots() []string {
+	var roots []string
+	for _, id := range this.ids {
+		if len(this.parents[id]) == 0 {
+			roots = append(roots, id)
+		}
+	}
+	return roots
+}
+
+// Leaves returns the nodes nobody depends on, they end the flow.
+func (this *Graph) Leaves() []string {
+	var leaves []string
+	for _, id := range this.ids {
+		if len(this.children[id]) == 0 {
+			leaves = append(leaves, id)
+		}
+	}
+	return leaves
+}
+
+// Ancestors returns every node a node depends on, directly or not, sorted.
+func (this *Graph) Ancestors(id string) []string {
+	return this.reach(id, this.parents)
+}
+
+// Descendants returns every node depending on a node, directly or not, sorted.
+func (this *Graph) Descendants(id string) []string {
+	return this.reach(id, this.children)
+}
+
+func (this *Graph) reach(id string, edges map[string][]string) []string {
+
+	seen := map[string]bool{}
+	stack := append([]string{}, edges[id]...)
+
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !seen[n] {
+			seen[n] = true
+			stack = append(stack, edges[n]...)
+		}
+	}
+
+	return sortedKeys(seen)
+
+}
+
+// Cycles returns the dependency cycles of the graph, each as the sorted ids of its nodes.
+func (this *Graph) Cycles() [][]string {
+
+	var cycles [][]string
+
+	deps := func(id string) []string { return this.parents[id] }
+
+	for _, component := range stronglyConnected(this.ids, deps) {
+
+		cyclic := len(component) > 1
+		for _, parent := range this.parents[component[0]] {
+			cyclic = cyclic || parent == component[0]
+		}
+
+		if cyclic {
+			cycles = append(cycles, component)
+		}
+
+	}
+
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+
+	return cycles
+
+}
+
+// CycleError is returned by the algorithms that need an acyclic graph.
+type CycleError struct {
+	Cycles [][]string
+}
+
+func (this *CycleError) Error() string {
+	var cycles []string
+	for _, cycle := range this.Cycles {
+		cycles = append(cycles, strings.Join(cycle, ", "))
+	}
+	return fmt.Sprintf("dependency cycle between %s", strings.Join(cycles, "; "))
+}
+
+// TopologicalSort returns the nodes in an order where every node comes after its dependencies,
+// keeping azkaban's order between independent nodes.
+func (this *Graph) TopologicalSort() ([]string, error) {
+
+	pending := map[string]int{}
+	for _, id := range this.ids {
+		pending[id] = len(this.parents[id])
+	}
+
+	var (
+		order []string
+		ready = this.Roots()
+	)
+
+	for len(ready) > 0 {
+
+		id := ready[0]
+		ready = ready[1:]
+		order = append(order, id)
+
+		for _, child := range this.children[id] {
+			if pending[child]--; pending[child] == 0 {
+				ready = append(ready, child)
+			}
+		}
+
+	}
+
+	if len(order) < len(this.ids) {
+		return nil, &CycleError{Cycles: this.Cycles()}
+	}
+
+	return order, nil
+
+}
+
+// Levels assigns each node its level, 0 for roots and otherwise one more than its deepest
+// dependency, the nodes of a level can run in parallel.
+func (this *Graph) Levels() (map[string]int, error) {
+
+	order, err := this.TopologicalSort()
+	if err != nil {
+		return nil, err
+	}
+
+	levels := map[string]int{}
+
+	for _, id := range order {
+		levels[id] = 0
+		for _, parent := range this.parents[id] {
+			if levels[parent]+1 > levels[id] {
+				levels[id] = levels[parent] + 1
+			}
+		}
+	}
+
+	return levels, nil
+
+}
+
+// CriticalPath returns the longest chain of dependent nodes weighted by duration, which bounds
+// the duration of the flow, and its total duration. Durations are keyed by nested id, as
+// returned by ExecutionFlow.Durations or AverageDurations, missing nodes count as 0.
+func (this *Graph) CriticalPath(durations map[string]time.Duration) ([]string, time.Duration, error) {
+
+	order, err := this.TopologicalSort()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		finish = map[string]time.Duration{}
+		via    = map[string]string{}
+		last   string
+	)
+
+	for _, id := range order {
+
+		start := time.Duration(0)
+		for _, parent := range this.parents[id] {
+			if finish[parent] > start || via[id] == "" {
+				start = finish[parent]
+				via[id] = parent
+			}
+		}
+
+		finish[id] = start + durations[this.NestedID(id)]
+
+		if last == "" || finish[id] > finish[last] {
+			last = id
+		}
+
+	}
+
+	if last == "" {
+		return nil, 0, nil
+	}
+
+	path := []string{last}
+	for id := last; via[id] != ""; id = via[id] {
+		path = append([]string{via[id]}, path...)
+	}
+
+	return path, finish[last], nil
+
+}
+
+// SubGraph returns the graph of an embedded flow node, or nil when the node is a job.
+func (this *Graph) SubGraph(id string) *Graph {
+
+	node := this.nodes[id]
+	if node == nil || node.EmbeddedFlow() == "" {
+		return nil
+	}
+
+	return newGraph(node.EmbeddedFlow(), this.NestedID(id)+":", node.Nodes)
+
+}
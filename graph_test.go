@@ -0,0 +1,74 @@
+package azkaban
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraph(t *testing.T) {
+	//   a -> b -> d
+	//   a -> c -> d, c embeds e -> f
+	jobs := &Jobs{Flow: "d", Nodes: []Node{
+		{ID: "a", Type: "command"},
+		{ID: "b", Type: "command", In: []string{"a"}},
+		{ID: "c", Type: "flow", In: []string{"a"}, FlowID: "f", Nodes: []Node{
+			{ID: "e", Type: "command"},
+			{ID: "f", Type: "noop", In: []string{"e"}},
+		}},
+		{ID: "d", Type: "noop", In: []string{"b", "c"}},
+	}}
+
+	g := NewGraph(jobs)
+	assert.Equal(t, []string{"a"}, g.Roots())
+	assert.Equal(t, []string{"d"}, g.Leaves())
+	assert.Equal(t, []string{"a", "b", "c"}, g.Ancestors("d"))
+	assert.Equal(t, []string{"b", "c", "d"}, g.Descendants("a"))
+	assert.Empty(t, g.Cycles())
+
+	order, err := g.TopologicalSort()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, order)
+
+	levels, err := g.Levels()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"a": 0, "b": 1, "c": 1, "d": 2}, levels)
+
+	execution := &ExecutionFlow{Nodes: []ExecutionNode{
+		{ID: "a", StartTime: 0, EndTime: 0},
+		{ID: "b", StartTime: 1000, EndTime: 3000},
+		{ID: "c", StartTime: 1000, EndTime: 6000, Nodes: []ExecutionNode{
+			{ID: "e", StartTime: 1000, EndTime: 5000},
+			{ID: "f", StartTime: 5000, EndTime: 6000},
+		}},
+		{ID: "d", StartTime: 6000, EndTime: 6500},
+	}}
+	durations := execution.Durations()
+	assert.Equal(t, 4*time.Second, durations["c:e"])
+
+	path, total, err := g.CriticalPath(durations)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "c", "d"}, path)
+	assert.Equal(t, 5500*time.Millisecond, total)
+
+	sub := g.SubGraph("c")
+	assert.Equal(t, "f", sub.Flow)
+	assert.Equal(t, "c:e", sub.NestedID("e"))
+	path, total, err = sub.CriticalPath(durations)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"e", "f"}, path)
+	assert.Equal(t, 5*time.Second, total)
+	assert.Nil(t, g.SubGraph("a"))
+
+	// cycles
+	g = NewGraph(&Jobs{Nodes: []Node{
+		{ID: "x", In: []string{"z"}},
+		{ID: "y", In: []string{"x"}},
+		{ID: "z", In: []string{"y"}},
+		{ID: "w", In: []string{"w"}},
+	}})
+	assert.Equal(t, [][]string{{"w"}, {"x", "y", "z"}}, g.Cycles())
+	_, err = g.TopologicalSort()
+	assert.EqualError(t, err, "dependency cycle between w; x, y, z")
+}
@@ -0,0 +1,335 @@
+package azkaban
+
+import (
+	"time"
+)
+
+// ExecutionFilter selects executions across projects, the zero value selects everything.
+// Project, Flow and User match the execution's exactly.
+type ExecutionFilter struct {
+	Project string
+	Flow    string
+	User    string
+	// Status lists the accepted statuses, any status when empty.
+	Status []string
+	// Begin and End bound the submit time, unbounded when zero.
+	Begin time.Time
+	End   time.Time
+	// PageSize is the number of executions fetched per request, 100 when 0.
+	PageSize int
+}
+
+func (this *ExecutionFilter) matches(e *Execution) bool {
+
+	if this.User != "" && e.User != this.User {
+		return false
+	}
+
+	if !this.End.IsZero() && e.SubmitAt.After(this.End) {
+		return false
+	}
+
+	if !this.Begin.IsZero() && e.SubmitAt.Before(this.Begin) {
+		return false
+	}
+
+	if len(this.Status) == 0 {
+		return true
+	}
+
+	for _, status := range this.Status {
+		if status == e.Status {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// ExecutionSearch walks the executions selected by a filter, newest first, fetching pages on demand.
+// Executions submitted while it pages push older ones to later pages, they are seen only once.
+//
+//	search := client.SearchExecutions(ExecutionFilter{Status: []string{ExecutionStatus.Failed}, Begin: yesterday})
+//	for search.Next() {
+//		execution := search.Execution()
+//	}
+//	if err := search.Err(); err != nil {
+//	}
+type ExecutionSearch struct {
+	client    *Client
+	filter    ExecutionFilter
+	listed    bool
+	flows     []*ExecutionIterator
+	heads     []*Execution
+	execution Execution
+	err       error
+}
+
+// SearchExecutions searches the executions of every flow of every project the user can read.
+// Azkaban has no json search api, so projects and flows are listed and the executions of each
+// flow are paged until the beginning of the time range, the newest of all flows coming first.
+func (this *Client) SearchExecutions(filter ExecutionFilter) *ExecutionSearch {
+
+	if filter.PageSize <= 0 {
+		filter.PageSize = 100
+	}
+
+	return &ExecutionSearch{client: this, filter: filter}
+
+}
+
+// FindExecutions collects every execution selected by a filter.
+func (this *Client) FindExecutions(filter ExecutionFilter) ([]Execution, error) {
+
+	var executions []Execution
+
+	search := this.SearchExecutions(filter)
+	for search.Next() {
+		executions = append(executions, search.Execution())
+	}
+
+	return executions, search.Err()
+
+}
+
+// list the flows to search, an iterator each
+func (this *ExecutionSearch) list() error {
+
+	projects, err := this.client.FetchProjects()
+	if err != nil {
+		return err
+	}
+
+	for _, project := range projects {
+
+		if this.filter.Project != "" && project.Name != this.filter.Project {
+			continue
+		}
+
+		flows, err := this.client.FetchFlows(project.Name)
+		if err != nil {
+			return err
+		}
+
+		for _, flow := range flows.Flows {
+			if this.filter.Flow == "" || flow.IdFlow == this.filter.Flow {
+				this.flows = append(this.flows, this.client.iterateExecutions(project.Name, flow.IdFlow, this.filter.Begin, this.filter.PageSize))
+			}
+		}
+
+	}
+
+	this.heads = make([]*Execution, len(this.flows))
+
+	return nil
+
+}
+
+// the newest of the next executions of the flows, nil when they are all over
+func (this *ExecutionSearch) next() (*Execution, error) {
+
+	newest := -1
+
+	for i, flow := range this.flows {
+
+		if this.heads[i] == nil && flow != nil {
+
+			if flow.Next() {
+				execution := flow.Execution()
+				this.heads[i] = &execution
+			} else if err := flow.Err(); err != nil {
+				return nil, err
+			} else {
+				this.flows[i] = nil
+				continue
+			}
+
+		}
+
+		if this.heads[i] != nil && (newest < 0 || newer(this.heads[i], this.heads[newest])) {
+			newest = i
+		}
+
+	}
+
+	if newest < 0 {
+		return nil, nil
+	}
+
+	execution := this.heads[newest]
+	this.heads[newest] = nil
+
+	return execution, nil
+
+}
+
+// the order of a search, by submit time then by id
+func newer(a, b *Execution) bool {
+	if a.SubmitAt.Equal(b.SubmitAt) {
+		return a.IdExecution > b.IdExecution
+	}
+	return a.SubmitAt.After(b.SubmitAt)
+}
+
+// Next advances to the next execution, it returns false when the search is over or an error occurred.
+func (this *ExecutionSearch) Next() bool {
+
+	if this.err != nil {
+		return false
+	}
+
+	if !this.listed {
+		this.listed = true
+		if this.err = this.list(); this.err != nil {
+			return false
+		}
+	}
+
+	for {
+
+		execution, err := this.next()
+		if err != nil {
+			this.err = err
+			return false
+		}
+
+		if execution == nil {
+			return false
+		}
+
+		if this.filter.matches(execution) {
+			this.execution = *execution
+			return true
+		}
+
+	}
+
+}
+
+// Execution returns the current execution.
+func (this *ExecutionSearch) Execution() Execution {
+	return this.execution
+}
+
+// Err returns the error that stopped the search, if any.
+func (this *ExecutionSearch) Err() error {
+	return this.err
+}
+
+// ExecutionIterator walks the executions of a flow, newest first, fetching pages on demand.
+// Executions submitted while it pages push older ones to later pages, they are seen only once.
+//
+//	it := client.IterateExecutions("project", "flow", time.Now().AddDate(0, 0, -7), 100)
+//	for it.Next() {
+//		execution := it.Execution()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type ExecutionIterator struct {
+	client    *Client
+	project   string
+	flow      string
+	since     time.Time
+	size      int
+	offset    int
+	page      []Execution
+	seen      map[int]bool
+	done      bool
+	execution Execution
+	err       error
+}
+
+// IterateExecutions returns an iterator over the executions of a flow submitted since the given
+// time, or all of them when since is zero, fetching pageSize executions per request. The project
+// is looked up once, Err tells when it does not exist.
+func (this *Client) IterateExecutions(project, flow string, since time.Time, pageSize int) *ExecutionIterator {
+
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+
+	it := this.iterateExecutions(project, flow, since, pageSize)
+	_, it.err = this.GetProject(project)
+
+	return it
+
+}
+
+// an iterator over the executions of a flow of a project known to exist
+func (this *Client) iterateExecutions(project, flow string, since time.Time, pageSize int) *ExecutionIterator {
+
+	return &ExecutionIterator{
+		client:  this,
+		project: project,
+		flow:    flow,
+		since:   since,
+		size:    pageSize,
+		seen:    map[int]bool{},
+	}
+
+}
+
+// Next advances to the next execution, it returns false when there are no more executions
+// since the cutoff or an error occurred.
+func (this *ExecutionIterator) Next() bool {
+
+	for this.err == nil {
+
+		if len(this.page) == 0 {
+
+			if this.done {
+				return false
+			}
+
+			executions, err := this.client.fetchExecutions(this.project, this.flow, this.offset, this.size)
+			if err != nil {
+				this.err = err
+				return false
+			}
+
+			this.page = executions.Execution
+			this.offset += len(this.page)
+			this.done = len(this.page) < this.size || this.offset >= executions.Total
+
+			if len(this.page) == 0 {
+				return false
+			}
+
+		}
+
+		execution := this.page[0]
+		this.page = this.page[1:]
+
+		// shifted by executions submitted since the previous page
+		if this.seen[execution.IdExecution] {
+			continue
+		}
+		this.seen[execution.IdExecution] = true
+
+		// executions are sorted by submit time, the rest is older
+		if !this.since.IsZero() && execution.SubmitAt.Before(this.since) {
+			this.page, this.done = nil, true
+			return false
+		}
+
+		execution.Project = this.project
+		this.execution = execution
+
+		return true
+
+	}
+
+	return false
+
+}
+
+// Execution returns the current execution.
+func (this *ExecutionIterator) Execution() Execution {
+	return this.execution
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (this *ExecutionIterator) Err() error {
+	return this.err
+}
@@ -0,0 +1,164 @@
+package azkaban_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wanglun/azkaban"
+	"github.com/wanglun/azkaban/azkabantest"
+)
+
+// a server with two projects, whose names contain one another, and a clock moved by the tests
+func newHistoryServer(t *testing.T) (*azkabantest.Server, *azkaban.Client, *time.Time) {
+
+	server := azkabantest.NewServer()
+
+	clock := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	server.Now = func() time.Time { return clock }
+
+	client, err := server.Client()
+	assert.Nil(t, err)
+
+	for _, project := range []string{PROJECT_NAME, PROJECT_NAME + "_copy"} {
+		_, err = client.CreateProject(project, PROJECT_DESC)
+		assert.Nil(t, err)
+		assert.Nil(t, client.UploadProjectZip(project, FLOW_ZIP_PATH))
+	}
+
+	return server, client, &clock
+
+}
+
+// execute a flow an hour after the previous execution
+func executeAt(t *testing.T, client *azkaban.Client, clock *time.Time, project string) int {
+
+	*clock = clock.Add(time.Hour)
+
+	execute, err := client.ExecuteFlow(project, "bar", azkaban.ConcurrentOptionIgnore, nil)
+	assert.Nil(t, err)
+
+	return int(execute.IdExecution)
+
+}
+
+func TestSearchExecutions(t *testing.T) {
+
+	server, client, clock := newHistoryServer(t)
+	defer server.Close()
+
+	first := executeAt(t, client, clock, PROJECT_NAME)
+	copied := executeAt(t, client, clock, PROJECT_NAME+"_copy")
+	server.FailJob(PROJECT_NAME, "foo")
+	failed := executeAt(t, client, clock, PROJECT_NAME)
+	*clock = clock.Add(time.Hour)
+
+	find := func(filter azkaban.ExecutionFilter) []int {
+		executions, err := client.FindExecutions(filter)
+		assert.Nil(t, err)
+		var ids []int
+		for _, execution := range executions {
+			ids = append(ids, execution.IdExecution)
+		}
+		return ids
+	}
+
+	// newest first, the project name is matched exactly
+	assert.Equal(t, []int{failed, copied, first}, find(azkaban.ExecutionFilter{}))
+	assert.Equal(t, []int{failed, first}, find(azkaban.ExecutionFilter{Project: PROJECT_NAME, PageSize: 1}))
+	assert.Equal(t, []int{copied}, find(azkaban.ExecutionFilter{Project: PROJECT_NAME + "_copy", Flow: "bar"}))
+	assert.Empty(t, find(azkaban.ExecutionFilter{Flow: "ba"}))
+	assert.Equal(t, []int{failed, copied, first}, find(azkaban.ExecutionFilter{User: azkabantest.DefaultUser}))
+	assert.Empty(t, find(azkaban.ExecutionFilter{User: "azk"}))
+
+	// one status or several
+	assert.Equal(t, []int{failed}, find(azkaban.ExecutionFilter{Status: []string{azkaban.ExecutionStatus.Failed}}))
+	assert.Equal(t, []int{failed, copied, first}, find(azkaban.ExecutionFilter{Status: []string{azkaban.ExecutionStatus.Failed, azkaban.ExecutionStatus.Succeeded}}))
+
+	begin := time.Date(2030, 1, 1, 1, 30, 0, 0, time.UTC)
+	assert.Equal(t, []int{failed, copied}, find(azkaban.ExecutionFilter{Begin: begin}))
+	assert.Equal(t, []int{copied}, find(azkaban.ExecutionFilter{Begin: begin, End: begin.Add(time.Hour)}))
+
+	// executions submitted while paging are not seen twice
+	search := client.SearchExecutions(azkaban.ExecutionFilter{Project: PROJECT_NAME, PageSize: 1})
+	assert.True(t, search.Next())
+	assert.Equal(t, failed, search.Execution().IdExecution)
+	executeAt(t, client, clock, PROJECT_NAME)
+	assert.True(t, search.Next())
+	assert.Equal(t, first, search.Execution().IdExecution)
+	assert.False(t, search.Next())
+	assert.Nil(t, search.Err())
+
+}
+
+func TestIterateExecutions(t *testing.T) {
+
+	server, client, clock := newHistoryServer(t)
+	defer server.Close()
+
+	var ids []int
+	for i := 0; i < 3; i++ {
+		ids = append([]int{executeAt(t, client, clock, PROJECT_NAME)}, ids...)
+	}
+
+	var seen []int
+	it := client.IterateExecutions(PROJECT_NAME, "bar", time.Time{}, 2)
+	for it.Next() {
+		seen = append(seen, it.Execution().IdExecution)
+		assert.Equal(t, PROJECT_NAME, it.Execution().Project)
+
+		// shifts the second page by one
+		if len(seen) == 1 {
+			executeAt(t, client, clock, PROJECT_NAME)
+		}
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, ids, seen)
+
+	// stops at the first execution before since
+	seen = nil
+	it = client.IterateExecutions(PROJECT_NAME, "bar", time.Date(2030, 1, 1, 1, 30, 0, 0, time.UTC), 1)
+	for it.Next() {
+		seen = append(seen, it.Execution().IdExecution)
+	}
+	assert.Nil(t, it.Err())
+	assert.Len(t, seen, 3)
+	assert.Equal(t, ids[:2], seen[1:])
+
+	// the project is looked up once, not for each of the four pages
+	requests := &countRequests{}
+	client.HTTPClient = &http.Client{Transport: requests}
+	it = client.IterateExecutions(PROJECT_NAME, "bar", time.Time{}, 1)
+	for it.Next() {
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, 1, requests.count("fetchprojectflows"))
+	assert.Equal(t, 4, requests.count("fetchFlowExecutions"))
+
+	// an unknown project
+	it = client.IterateExecutions("unknown", "bar", time.Time{}, 1)
+	assert.False(t, it.Next())
+	assert.NotNil(t, it.Err())
+
+}
+
+// counts the requests by ajax action
+type countRequests struct {
+	ajax []string
+}
+
+func (this *countRequests) RoundTrip(req *http.Request) (*http.Response, error) {
+	this.ajax = append(this.ajax, req.URL.Query().Get("ajax"))
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (this *countRequests) count(ajax string) int {
+	n := 0
+	for _, a := range this.ajax {
+		if a == ajax {
+			n++
+		}
+	}
+	return n
+}
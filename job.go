@@ -0,0 +1,89 @@
+package azkaban
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type JobInfo struct {
+	Name       string            `json:"jobName"`
+	Type       string            `json:"jobType"`
+	Properties map[string]string `json:"generalParams"`
+	Overrides  map[string]string `json:"overrideParams"`
+}
+
+// Effective returns the properties of the job file with the overrides applied.
+func (this *JobInfo) Effective() map[string]string {
+
+	props := make(map[string]string, len(this.Properties)+len(this.Overrides))
+
+	for k, v := range this.Properties {
+		props[k] = v
+	}
+
+	for k, v := range this.Overrides {
+		props[k] = v
+	}
+
+	return props
+
+}
+
+// Given a project, a flow and a job, this API call fetches the properties of the job file and the
+// properties overriding them, as set from the web UI or by SetJobOverride.
+func (this *Client) FetchJobInfo(project, flow, job string) (*JobInfo, error) {
+
+	// init return
+	var info JobInfo
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", "fetchJobInfo")
+	values.Add("session.id", this.Session)
+	values.Add("project", project)
+	values.Add("flowName", flow)
+	values.Add("jobName", job)
+
+	// try to get job info
+	err := this.action(http.MethodGet, "/manager", values, &info)
+
+	// project does not exist
+	if err == EmptyResponse {
+		err = ProjectNotFound
+	}
+
+	return &info, err
+
+}
+
+// This API call sets the override properties of a job, taking effect from the next execution
+// without uploading the project again. The overrides replace the ones previously set, so fetch
+// them with FetchJobInfo first to change a single property.
+func (this *Client) SetJobOverride(project, flow, job string, overrides map[string]string) error {
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", "setJobOverrideProperty")
+	values.Add("session.id", this.Session)
+	values.Add("project", project)
+	values.Add("flowName", flow)
+	values.Add("jobName", job)
+	for k, v := range overrides {
+		values.Add(fmt.Sprintf("jobOverride[%s]", k), v)
+	}
+
+	// an empty struct will return if succeeds
+	var response map[string]interface{}
+
+	// request api
+	err := this.action(http.MethodPost, "/manager", values, &response)
+
+	// azkaban answers with an empty body
+	if err == EmptyResponse {
+		err = nil
+	}
+
+	return err
+
+}
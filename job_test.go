@@ -0,0 +1,70 @@
+package azkaban_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wanglun/azkaban"
+)
+
+func TestFetchJobInfo(t *testing.T) {
+
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.Form
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"jobName": "load",
+			"jobType": "command",
+			"generalParams": {"command": "echo ${table}", "table": "events", "retries": "1"},
+			"overrideParams": {"table": "backfill"}
+		}`))
+	}))
+	defer server.Close()
+
+	client := azkaban.New(server.URL)
+
+	info, err := client.FetchJobInfo(PROJECT_NAME, "daily", "load")
+	assert.Nil(t, err)
+	assert.Equal(t, "fetchJobInfo", form.Get("ajax"))
+	assert.Equal(t, "daily", form.Get("flowName"))
+	assert.Equal(t, "load", form.Get("jobName"))
+
+	// the properties of the job file and the overrides are kept apart
+	assert.Equal(t, "load", info.Name)
+	assert.Equal(t, "command", info.Type)
+	assert.Equal(t, map[string]string{"command": "echo ${table}", "table": "events", "retries": "1"}, info.Properties)
+	assert.Equal(t, map[string]string{"table": "backfill"}, info.Overrides)
+	assert.Equal(t, map[string]string{"command": "echo ${table}", "table": "backfill", "retries": "1"}, info.Effective())
+
+}
+
+func TestSetJobOverride(t *testing.T) {
+
+	var method string
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		method, form = r.Method, r.PostForm
+	}))
+	defer server.Close()
+
+	client := azkaban.New(server.URL)
+
+	assert.Nil(t, client.SetJobOverride(PROJECT_NAME, "daily", "load", map[string]string{"table": "backfill", "retries": "3"}))
+	assert.Equal(t, http.MethodPost, method)
+	assert.Equal(t, "setJobOverrideProperty", form.Get("ajax"))
+	assert.Equal(t, PROJECT_NAME, form.Get("project"))
+	assert.Equal(t, "daily", form.Get("flowName"))
+	assert.Equal(t, "load", form.Get("jobName"))
+
+	// each override is a jobOverride[key] field
+	assert.Equal(t, []string{"backfill"}, form["jobOverride[table]"])
+	assert.Equal(t, []string{"3"}, form["jobOverride[retries]"])
+	assert.NotContains(t, form, "table")
+
+}
@@ -0,0 +1,392 @@
+package azkaban
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PropertyEntry is a single key/value pair of a job or properties file.
+type PropertyEntry struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// PropertiesFile is a .properties file of a project, inherited by the jobs in its directory and below.
+type PropertiesFile struct {
+	File  string
+	Props []PropertyEntry
+}
+
+// LocalJob is a .job file of a project.
+type LocalJob struct {
+	Name  string
+	File  string
+	Props []PropertyEntry
+}
+
+// LocalProject is a project in the legacy format, read from a directory or a zip archive.
+// File names are relative to the project root and always use forward slashes.
+type LocalProject struct {
+	Jobs       []*LocalJob
+	Properties []*PropertiesFile
+}
+
+// Get returns the value of a property of the job, the last definition wins.
+func (this *LocalJob) Get(key string) string {
+	value, _ := lookupProperty(this.Props, key)
+	return value
+}
+
+// Type returns the job type.
+func (this *LocalJob) Type() string {
+	return this.Get("type")
+}
+
+// Dependencies returns the jobs this job depends on.
+func (this *LocalJob) Dependencies() []string {
+	return splitList(this.Get("dependencies"))
+}
+
+// Dir returns the directory of the job file.
+func (this *LocalJob) Dir() string {
+	return path.Dir(this.File)
+}
+
+func lookupProperty(props []PropertyEntry, key string) (string, bool) {
+	for i := len(props) - 1; i >= 0; i-- {
+		if props[i].Key == key {
+			return props[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// split a comma separated list as azkaban does
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Job returns the job with the given name, or nil.
+func (this *LocalProject) Job(name string) *LocalJob {
+	for _, job := range this.Jobs {
+		if job.Name == name {
+			return job
+		}
+	}
+	return nil
+}
+
+// Flows returns the names of the flows of the project. Azkaban makes a flow of every job
+// no other job depends on, and names the flow after it.
+func (this *LocalProject) Flows() []string {
+
+	depended := map[string]bool{}
+	for _, job := range this.Jobs {
+		for _, dep := range job.Dependencies() {
+			depended[dep] = true
+		}
+	}
+
+	var flows []string
+	for _, job := range this.Jobs {
+		if !depended[job.Name] {
+			flows = append(flows, job.Name)
+		}
+	}
+
+	sort.Strings(flows)
+
+	return flows
+
+}
+
+// FlowJobs returns the jobs of a flow, the flow job and everything it depends on, directly or not.
+func (this *LocalProject) FlowJobs(flow string) []*LocalJob {
+
+	var (
+		jobs []*LocalJob
+		seen = map[string]bool{}
+		walk func(name string)
+	)
+
+	walk = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		if job := this.Job(name); job != nil {
+			for _, dep := range job.Dependencies() {
+				walk(dep)
+			}
+			jobs = append(jobs, job)
+		}
+	}
+
+	walk(flow)
+
+	return jobs
+
+}
+
+// LoadProject reads a legacy project from a directory or a .zip archive.
+func LoadProject(name string) (*LocalProject, error) {
+
+	files, err := readProjectFiles(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadProject(files)
+
+}
+
+// ReadProject parses the .job and .properties files of a project, other files are ignored.
+func ReadProject(files []File) (*LocalProject, error) {
+
+	project := &LocalProject{}
+
+	for _, file := range files {
+
+		ext := path.Ext(file.Name)
+		if ext != ".job" && ext != ".properties" {
+			continue
+		}
+
+		props, err := ReadProperties(bytes.NewReader(file.Body))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file.Name, err)
+		}
+
+		if ext == ".job" {
+			project.Jobs = append(project.Jobs, &LocalJob{
+				Name:  strings.TrimSuffix(path.Base(file.Name), ext),
+				File:  file.Name,
+				Props: props,
+			})
+		} else {
+			project.Properties = append(project.Properties, &PropertiesFile{
+				File:  file.Name,
+				Props: props,
+			})
+		}
+
+	}
+
+	return project, nil
+
+}
+
+// read the files of a project directory or zip archive
+func readProjectFiles(name string) ([]File, error) {
+
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return readDir(name)
+	}
+
+	body, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return readZip(body)
+
+}
+
+// read every regular file below root, names relative to root
+func readDir(root string) ([]File, error) {
+
+	var files []File
+
+	err := filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+
+		body, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, File{Name: filepath.ToSlash(rel), Body: body})
+
+		return nil
+
+	})
+
+	return files, err
+
+}
+
+// read every file of a zip archive
+func readZip(body []byte) ([]File, error) {
+
+	reader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+
+	for _, entry := range reader.File {
+
+		// skip directories and metadata added by macOS
+		if entry.FileInfo().IsDir() || strings.HasPrefix(entry.Name, "__MACOSX/") {
+			continue
+		}
+
+		rc, err := entry.Open()
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, File{Name: entry.Name, Body: content})
+
+	}
+
+	return files, nil
+
+}
+
+// find a dependency cycle among names, returned as the path of names closing on its first element
+func findCycle(names []string, deps func(string) []string) []string {
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	var (
+		state = map[string]int{}
+		stack []string
+		visit func(name string) []string
+	)
+
+	visit = func(name string) []string {
+
+		state[name] = visiting
+		stack = append(stack, name)
+
+		for _, dep := range deps(name) {
+			switch state[dep] {
+			case visiting:
+				for i, n := range stack {
+					if n == dep {
+						return append(append([]string{}, stack[i:]...), dep)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+
+		return nil
+
+	}
+
+	for _, name := range names {
+		if state[name] == unvisited {
+			if cycle := visit(name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+
+}
+
+// group names into strongly connected components with Tarjan's algorithm, components of more
+// than one name, or of a name depending on itself, are dependency cycles
+func stronglyConnected(names []string, deps func(string) []string) [][]string {
+
+	var (
+		index      = map[string]int{}
+		low        = map[string]int{}
+		onStack    = map[string]bool{}
+		stack      []string
+		components [][]string
+		visit      func(name string)
+	)
+
+	visit = func(name string) {
+
+		index[name] = len(index)
+		low[name] = index[name]
+		stack = append(stack, name)
+		onStack[name] = true
+
+		for _, dep := range deps(name) {
+			if _, ok := index[dep]; !ok {
+				visit(dep)
+				if low[dep] < low[name] {
+					low[name] = low[dep]
+				}
+			} else if onStack[dep] && index[dep] < low[name] {
+				low[name] = index[dep]
+			}
+		}
+
+		// name is the root of a component
+		if low[name] == index[name] {
+			var component []string
+			for {
+				n := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[n] = false
+				component = append(component, n)
+				if n == name {
+					break
+				}
+			}
+			sort.Strings(component)
+			components = append(components, component)
+		}
+
+	}
+
+	for _, name := range names {
+		if _, ok := index[name]; !ok {
+			visit(name)
+		}
+	}
+
+	return components
+
+}
@@ -0,0 +1,620 @@
+package azkaban
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the steps of the migration of a project, in order
+var MigrationStep = struct{ Project, Archives, Permissions, ProxyUsers, Schedules string }{"project", "archives", "permissions", "proxy users", "schedules"}
+
+// MigrateOptions tells Migrate what to copy.
+type MigrateOptions struct {
+	// Projects are the projects migrated, every project of the source when empty
+	Projects []string
+
+	// AllVersions uploads every version of the archive still on the source, oldest first,
+	// rather than the latest one
+	AllVersions bool
+
+	// Description of the projects created on the target, "Migrated from <source>" when empty,
+	// azkaban does not report the description of existing projects
+	Description string
+
+	// StateFile keeps the progress of the migration, an interrupted migration run again with
+	// the same state file resumes where it stopped
+	StateFile string
+}
+
+// MigrationReport is the outcome of Migrate, it is also the state resumed from.
+type MigrationReport struct {
+	Source   string              `json:"source"`
+	Target   string              `json:"target"`
+	Projects []*ProjectMigration `json:"projects"`
+}
+
+// ProjectMigration is what was copied of a project, and what could not be.
+type ProjectMigration struct {
+	Name string `json:"name"`
+
+	// Steps are the completed steps
+	Steps []string `json:"steps,omitempty"`
+
+	// Versions are the versions of the source uploaded to the target, or when only the latest
+	// archive is migrated, the version the target gave it
+	Versions    []int    `json:"versions,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	ProxyUsers  []string `json:"proxyUsers,omitempty"`
+
+	// Schedules are the flows scheduled on the target, with their cron expression
+	Schedules []string `json:"schedules,omitempty"`
+
+	// Problems are what could not be migrated, Error is why the migration of the project stopped
+	Problems []string `json:"problems,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Done tells whether every step of the migration of the project is completed.
+func (this *ProjectMigration) Done() bool {
+	return this.completed(MigrationStep.Schedules)
+}
+
+func (this *ProjectMigration) completed(step string) bool {
+	for _, s := range this.Steps {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
+
+// problems are found again when a step is resumed, they are kept once
+func (this *ProjectMigration) problem(format string, args ...interface{}) {
+	problem := fmt.Sprintf(format, args...)
+	for _, p := range this.Problems {
+		if p == problem {
+			return
+		}
+	}
+	this.Problems = append(this.Problems, problem)
+}
+
+// String summarizes the migration, one line per project followed by its problems.
+func (this *MigrationReport) String() string {
+
+	var b strings.Builder
+
+	for _, p := range this.Projects {
+
+		switch {
+		case p.Error != "":
+			fmt.Fprintf(&b, "project %s: failed: %s\n", p.Name, p.Error)
+		default:
+			fmt.Fprintf(&b, "project %s: %d versions, %d permissions, %d proxy users, %d schedules\n", p.Name, len(p.Versions), len(p.Permissions), len(p.ProxyUsers), len(p.Schedules))
+		}
+
+		for _, problem := range p.Problems {
+			fmt.Fprintf(&b, "    ! %s\n", problem)
+		}
+
+	}
+
+	return b.String()
+
+}
+
+func (this *MigrationReport) project(name string) *ProjectMigration {
+
+	for _, p := range this.Projects {
+		if p.Name == name {
+			return p
+		}
+	}
+
+	p := &ProjectMigration{Name: name}
+	this.Projects = append(this.Projects, p)
+
+	return p
+
+}
+
+// the state of an interrupted migration, a new report when there is none
+func loadMigrationReport(path string, source, target *Client) (*MigrationReport, error) {
+
+	report := &MigrationReport{Source: source.Endpoint, Target: target.Endpoint}
+	if path == "" {
+		return report, nil
+	}
+
+	found, err := readJSONFile(path, report)
+	if err != nil || !found {
+		return report, err
+	}
+
+	if report.Source != source.Endpoint || report.Target != target.Endpoint {
+		return nil, fmt.Errorf("%s is the state of a migration from %s to %s", path, report.Source, report.Target)
+	}
+
+	return report, nil
+
+}
+
+func (this *MigrationReport) save(path string) error {
+
+	if path == "" {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(this, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, content, 0644)
+
+}
+
+// Migrate copies projects from a server to another, such as from an Azkaban 2.5 cluster to a
+// 3.x one: their archives, the permissions of their users and groups, their proxy users, and
+// their schedules with their SLAs. Schedules with a period are converted to cron expressions.
+//
+// A project failing to migrate does not stop the others, the report tells why it failed and
+// what could not be migrated, and an error is returned once every project was tried. Projects
+// which already exist on the target are not migrated, unless a previous run of the same state
+// file created them.
+func Migrate(source, target *Client, options MigrateOptions) (*MigrationReport, error) {
+
+	report, err := loadMigrationReport(options.StateFile, source, target)
+	if err != nil {
+		return nil, err
+	}
+
+	names := options.Projects
+	if len(names) == 0 {
+		projects, err := source.FetchProjects()
+		if err != nil {
+			return report, err
+		}
+		for _, p := range projects {
+			names = append(names, p.Name)
+		}
+	}
+
+	if options.Description == "" {
+		options.Description = "Migrated from " + source.Endpoint
+	}
+
+	m := &migration{source: source, target: target, options: options, report: report}
+
+	failed := 0
+
+	for _, name := range names {
+
+		p := report.project(name)
+		if p.Done() {
+			continue
+		}
+
+		p.Error = ""
+		if err = m.project(p); err != nil {
+			p.Error = err.Error()
+			failed++
+		}
+
+		if err = report.save(options.StateFile); err != nil {
+			return report, err
+		}
+
+	}
+
+	if failed > 0 {
+		return report, fmt.Errorf("%d of %d projects failed to migrate", failed, len(names))
+	}
+
+	return report, nil
+
+}
+
+type migration struct {
+	source, target *Client
+	options        MigrateOptions
+	report         *MigrationReport
+}
+
+// the steps not completed yet, the state is saved after each one
+func (this *migration) project(p *ProjectMigration) error {
+
+	steps := []struct {
+		name string
+		run  func(p *ProjectMigration) error
+	}{
+		{MigrationStep.Project, this.create},
+		{MigrationStep.Archives, this.archives},
+		{MigrationStep.Permissions, this.permissions},
+		{MigrationStep.ProxyUsers, this.proxyUsers},
+		{MigrationStep.Schedules, this.schedules},
+	}
+
+	for _, step := range steps {
+
+		if p.completed(step.name) {
+			continue
+		}
+
+		if err := step.run(p); err != nil {
+			return fmt.Errorf("%s: %v", step.name, err)
+		}
+
+		p.Steps = append(p.Steps, step.name)
+
+		if err := this.report.save(this.options.StateFile); err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+
+}
+
+func (this *migration) create(p *ProjectMigration) error {
+
+	if _, err := this.source.GetProject(p.Name); err != nil {
+		return err
+	}
+
+	_, err := this.target.GetProject(p.Name)
+	if err == nil {
+		return fmt.Errorf("project %s already exists on %s", p.Name, this.target.Endpoint)
+	}
+	if err != ProjectNotFound {
+		return err
+	}
+
+	_, err = this.target.CreateProject(p.Name, this.options.Description)
+
+	return err
+
+}
+
+// the number of uploads of a project, from its audit log
+func (this *migration) uploads(project string) (int, error) {
+
+	n := 0
+
+	it := this.source.ProjectLogs(project, 100)
+	for it.Next() {
+		if it.Event().Type == EventType.Uploaded {
+			n++
+		}
+	}
+
+	return n, it.Err()
+
+}
+
+// uploads a version of the source to the target, the latest when version is 0, and returns the
+// version the target gave it
+func (this *migration) upload(p *ProjectMigration, version int) (string, error) {
+
+	var archive bytes.Buffer
+
+	err := this.source.DownloadProject(context.Background(), p.Name, version, &archive)
+	if err != nil {
+		return "", err
+	}
+
+	upload, err := this.target.UploadProject(context.Background(), p.Name, &archive, p.Name+".zip", nil)
+	if err != nil {
+		return "", err
+	}
+
+	return upload.Version, nil
+
+}
+
+// versions are uploaded in order, those removed from the source by the cleanup of old versions
+// are skipped
+func (this *migration) archives(p *ProjectMigration) error {
+
+	if !this.options.AllVersions {
+
+		uploaded, err := this.upload(p, 0)
+		if err == ArchiveNotFound {
+			p.problem("project %s has no archive", p.Name)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		version, err := strconv.Atoi(uploaded)
+		if err != nil {
+			return fmt.Errorf("invalid version %q of project %s on %s", uploaded, p.Name, this.target.Endpoint)
+		}
+
+		p.Versions = []int{version}
+
+		return nil
+
+	}
+
+	uploads, err := this.uploads(p.Name)
+	if err != nil {
+		return err
+	}
+
+	first := 1
+	if len(p.Versions) > 0 {
+		first = p.Versions[len(p.Versions)-1] + 1
+	}
+
+	for version := first; version <= uploads; version++ {
+
+		_, err = this.upload(p, version)
+		if err == ArchiveNotFound {
+			p.problem("version %d of project %s is no longer on the source", version, p.Name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		p.Versions = append(p.Versions, version)
+
+		if err = this.report.save(this.options.StateFile); err != nil {
+			return err
+		}
+
+	}
+
+	if len(p.Versions) == 0 {
+		p.problem("project %s has no archive", p.Name)
+	}
+
+	return nil
+
+}
+
+// the user migrating is an admin of the projects it creates, its permission is left as is
+func (this *migration) permissions(p *ProjectMigration) error {
+
+	permissions, err := this.source.FetchPermissions(p.Name)
+	if err != nil {
+		return err
+	}
+
+	current, err := this.target.FetchPermissions(p.Name)
+	if err != nil {
+		return err
+	}
+
+	granted := map[string]string{}
+	for _, permission := range current {
+		granted[permissionKey(permission.Name, permission.Group)] = strings.Join(permission.Permissions, ",")
+	}
+
+	p.Permissions = nil
+
+	for _, permission := range permissions {
+
+		key := permissionKey(permission.Name, permission.Group)
+
+		if !permission.Group && permission.Name == this.target.Username {
+			continue
+		}
+
+		var err error
+
+		was, ok := granted[key]
+		switch {
+		case !ok:
+			err = this.target.AddPermission(p.Name, permission)
+		case was != strings.Join(permission.Permissions, ","):
+			err = this.target.ChangePermission(p.Name, permission)
+		}
+
+		// users and groups may not exist on the target
+		if err != nil {
+			p.problem("permission of %s on project %s: %v", key, p.Name, err)
+			continue
+		}
+
+		p.Permissions = append(p.Permissions, key)
+
+	}
+
+	return nil
+
+}
+
+func (this *migration) proxyUsers(p *ProjectMigration) error {
+
+	users, err := this.source.FetchProxyUsers(p.Name)
+	if err != nil {
+		return err
+	}
+
+	current, err := this.target.FetchProxyUsers(p.Name)
+	if err != nil {
+		return err
+	}
+
+	added := map[string]bool{}
+	for _, user := range current {
+		added[user] = true
+	}
+
+	p.ProxyUsers = nil
+
+	for _, user := range users {
+
+		if !added[user] {
+			if err = this.target.AddProxyUser(p.Name, user); err != nil {
+				p.problem("proxy user %s of project %s: %v", user, p.Name, err)
+				continue
+			}
+		}
+
+		p.ProxyUsers = append(p.ProxyUsers, user)
+
+	}
+
+	return nil
+
+}
+
+func (this *migration) schedules(p *ProjectMigration) error {
+
+	schedules, err := this.source.FetchSchedules(p.Name)
+	if err != nil {
+		return err
+	}
+
+	p.Schedules = nil
+
+	for _, s := range schedules {
+
+		cron := s.Cron
+		if cron == "" {
+			if cron, err = this.cron(s); err != nil {
+				p.problem("schedule of flow %s.%s: %v", p.Name, s.Flow, err)
+				continue
+			}
+		}
+
+		id, err := this.target.ScheduleCronFlow(p.Name, s.Flow, cron)
+		if err != nil {
+			p.problem("schedule of flow %s.%s: %v", p.Name, s.Flow, err)
+			continue
+		}
+
+		p.Schedules = append(p.Schedules, s.Flow+": "+cron)
+
+		sla, err := this.source.FetchSLA(s.ID)
+		if err == nil && len(sla.Settings) > 0 {
+			err = this.target.SetSLA(id, *sla)
+		}
+		if err != nil {
+			p.problem("SLA of flow %s.%s: %v", p.Name, s.Flow, err)
+		}
+
+	}
+
+	return nil
+
+}
+
+// the cron expression of a schedule with a period, in the time zone of the target
+func (this *migration) cron(s Schedule) (string, error) {
+
+	location := func(c *Client) *time.Location {
+		if c.Location != nil {
+			return c.Location
+		}
+		return time.Local
+	}
+
+	first, err := time.ParseInLocation("2006-01-02 15:04:05", s.FirstSchedAt, location(this.source))
+	if err != nil {
+		return "", fmt.Errorf("invalid first schedule time %q", s.FirstSchedAt)
+	}
+
+	// a schedule which does not repeat is kept only until it runs
+	if s.Period == "" && first.Before(time.Now()) {
+		return "", fmt.Errorf("it ran once at %s and does not repeat", s.FirstSchedAt)
+	}
+
+	return PeriodCron(first.In(location(this.target)), s.Period)
+
+}
+
+// PeriodCron converts a schedule with a first time and a period, as azkaban 2.5 has, into a
+// Quartz cron expression firing at the same times. A period is a number followed by s, m, h, d,
+// w, M or y, and a schedule without a period runs once. Periods which do not divide the minute,
+// the hour, the day, the year and so on have no cron expression.
+func PeriodCron(first time.Time, period string) (string, error) {
+
+	second, minute, hour := first.Second(), first.Minute(), first.Hour()
+	day, month, year := first.Day(), int(first.Month()), first.Year()
+
+	if period == "" {
+		return fmt.Sprintf("%d %d %d %d %d ? %d", second, minute, hour, day, month, year), nil
+	}
+
+	n, err := strconv.Atoi(period[:len(period)-1])
+	if err != nil || n <= 0 {
+		return "", fmt.Errorf("invalid period %q", period)
+	}
+
+	// the start of a step, when n divides the range of the field
+	step := func(value, start, size int) (string, bool) {
+		if size%n != 0 {
+			return "", false
+		}
+		if n == 1 {
+			return "*", true
+		}
+		return fmt.Sprintf("%d/%d", (value-start)%n+start, n), true
+	}
+
+	var (
+		field string
+		ok    bool
+	)
+
+	switch period[len(period)-1] {
+
+	case 's':
+		if field, ok = step(second, 0, 60); ok {
+			return fmt.Sprintf("%s * * ? * *", field), nil
+		}
+
+	case 'm':
+		if field, ok = step(minute, 0, 60); ok {
+			return fmt.Sprintf("%d %s * ? * *", second, field), nil
+		}
+
+	case 'h':
+		if field, ok = step(hour, 0, 24); ok {
+			return fmt.Sprintf("%d %d %s ? * *", second, minute, field), nil
+		}
+
+	case 'd':
+		if n == 1 {
+			return fmt.Sprintf("%d %d %d ? * *", second, minute, hour), nil
+		}
+
+	case 'w':
+		if n == 1 {
+			return fmt.Sprintf("%d %d %d ? * %s", second, minute, hour, strings.ToUpper(first.Weekday().String()[:3])), nil
+		}
+
+	case 'M':
+		// azkaban moves the runs to the end of shorter months, cron skips them
+		if day > 28 {
+			return "", fmt.Errorf("every %s from the %dth of a month has no cron expression", period, day)
+		}
+		if field, ok = step(month, 1, 12); ok {
+			return fmt.Sprintf("%d %d %d %d %s ?", second, minute, hour, day, field), nil
+		}
+
+	case 'y':
+		if n == 1 {
+			return fmt.Sprintf("%d %d %d %d %d ?", second, minute, hour, day, month), nil
+		}
+		return fmt.Sprintf("%d %d %d %d %d ? %d/%d", second, minute, hour, day, month, year, n), nil
+
+	default:
+		return "", fmt.Errorf("invalid period %q", period)
+
+	}
+
+	return "", fmt.Errorf("every %s has no cron expression", period)
+
+}
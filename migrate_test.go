@@ -0,0 +1,176 @@
+package azkaban_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wanglun/azkaban"
+	"github.com/wanglun/azkaban/azkabantest"
+)
+
+func TestPeriodCron(t *testing.T) {
+
+	first := time.Date(2030, 1, 15, 2, 30, 0, 0, time.UTC)
+
+	for period, cron := range map[string]string{
+		"":    "0 30 2 15 1 ? 2030",
+		"15m": "0 0/15 * ? * *",
+		"1h":  "0 30 * ? * *",
+		"6h":  "0 30 2/6 ? * *",
+		"1d":  "0 30 2 ? * *",
+		"1w":  "0 30 2 ? * TUE",
+		"3M":  "0 30 2 15 1/3 ?",
+		"1y":  "0 30 2 15 1 ?",
+		"2y":  "0 30 2 15 1 ? 2030/2",
+	} {
+		expression, err := azkaban.PeriodCron(first, period)
+		assert.Nil(t, err, period)
+		assert.Equal(t, cron, expression, period)
+	}
+
+	for _, period := range []string{"90m", "5h", "2d", "2w", "5M", "1x", "d"} {
+		_, err := azkaban.PeriodCron(first, period)
+		assert.NotNil(t, err, period)
+	}
+
+	_, err := azkaban.PeriodCron(time.Date(2030, 1, 31, 2, 30, 0, 0, time.UTC), "1M")
+	assert.NotNil(t, err)
+
+}
+
+func TestMigrate(t *testing.T) {
+
+	source, target := azkabantest.NewServer(), azkabantest.NewServer()
+	defer source.Close()
+	defer target.Close()
+
+	from, err := source.Client()
+	assert.Nil(t, err)
+	to, err := target.Client()
+	assert.Nil(t, err)
+	from.Location, to.Location = time.UTC, time.UTC
+
+	// etl has two versions, a schedule with a period and an SLA, permissions and a proxy user
+	_, err = from.CreateProject("etl", "etl")
+	assert.Nil(t, err)
+	assert.Nil(t, from.UploadProjectZip("etl", FLOW_ZIP_PATH))
+	assert.Nil(t, from.UploadProjectZip("etl", FLOW_ZIP_PATH))
+	assert.Nil(t, from.AddPermission("etl", azkaban.Permission{Name: "deploy", Permissions: []string{"EXECUTE", "READ"}}))
+	assert.Nil(t, from.AddPermission("etl", azkaban.Permission{Name: "analysts", Group: true, Permissions: []string{"READ"}}))
+	assert.Nil(t, from.AddProxyUser("etl", "etl"))
+
+	project, err := from.GetProject("etl")
+	assert.Nil(t, err)
+	_, err = from.ScheduleFlow(project, "bar", time.Date(2030, 1, 1, 2, 30, 0, 0, time.UTC), "on", "1d")
+	assert.Nil(t, err)
+	schedule, err := from.FetchSchedule("etl", "bar")
+	assert.Nil(t, err)
+	sla := azkaban.SLA{Emails: []string{"oncall@example.com"}, Settings: []azkaban.SLASetting{{Rule: azkaban.SLARule.Finish, Duration: time.Hour, Kill: true}}}
+	assert.Nil(t, from.SetSLA(schedule.ID, sla))
+
+	// odd has a schedule without a cron expression, and already exists on the target
+	_, err = from.CreateProject("odd", "odd")
+	assert.Nil(t, err)
+	assert.Nil(t, from.UploadProjectZip("odd", FLOW_ZIP_PATH))
+	project, err = from.GetProject("odd")
+	assert.Nil(t, err)
+	_, err = from.ScheduleFlow(project, "bar", time.Date(2030, 1, 1, 2, 30, 0, 0, time.UTC), "on", "90m")
+	assert.Nil(t, err)
+
+	_, err = to.CreateProject("odd", "odd")
+	assert.Nil(t, err)
+
+	state := filepath.Join(t.TempDir(), "migration.json")
+
+	report, err := azkaban.Migrate(from, to, azkaban.MigrateOptions{AllVersions: true, StateFile: state})
+	assert.NotNil(t, err)
+	assert.Equal(t, "1 of 2 projects failed to migrate", err.Error())
+	assert.Len(t, report.Projects, 2)
+
+	etl := report.Projects[0]
+	assert.True(t, etl.Done())
+	assert.Equal(t, []int{1, 2}, etl.Versions)
+	assert.Equal(t, []string{"user deploy", "group analysts"}, etl.Permissions)
+	assert.Equal(t, []string{"etl"}, etl.ProxyUsers)
+	assert.Equal(t, []string{"bar: 0 30 2 ? * *"}, etl.Schedules)
+	assert.Empty(t, etl.Problems)
+
+	assert.False(t, report.Projects[1].Done())
+	assert.Contains(t, report.Projects[1].Error, "already exists")
+
+	// the target has both versions, the permissions, the proxy user, the schedule and its SLA
+	var archive bytes.Buffer
+	assert.Nil(t, to.DownloadProject(context.Background(), "etl", 2, &archive))
+
+	permissions, err := to.FetchPermissions("etl")
+	assert.Nil(t, err)
+	assert.Equal(t, []azkaban.Permission{
+		{Name: "azkaban", Permissions: []string{"ADMIN"}},
+		{Name: "deploy", Permissions: []string{"EXECUTE", "READ"}},
+		{Name: "analysts", Group: true, Permissions: []string{"READ"}},
+	}, permissions)
+
+	users, err := to.FetchProxyUsers("etl")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"etl"}, users)
+
+	schedule, err = to.FetchSchedule("etl", "bar")
+	assert.Nil(t, err)
+	assert.Equal(t, "0 30 2 ? * *", schedule.Cron)
+	migrated, err := to.FetchSLA(schedule.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, &sla, migrated)
+
+	// run again once odd is out of the way, etl is not migrated twice
+	_, err = to.DeleteProject("odd")
+	assert.Nil(t, err)
+
+	report, err = azkaban.Migrate(from, to, azkaban.MigrateOptions{AllVersions: true, StateFile: state})
+	assert.Nil(t, err)
+	assert.True(t, report.Projects[1].Done())
+	assert.Equal(t, []string{"schedule of flow odd.bar: every 90m has no cron expression"}, report.Projects[1].Problems)
+	assert.Equal(t, `project etl: 2 versions, 2 permissions, 1 proxy users, 1 schedules
+project odd: 1 versions, 0 permissions, 0 proxy users, 0 schedules
+    ! schedule of flow odd.bar: every 90m has no cron expression
+`, report.String())
+
+	assert.Equal(t, azkaban.ArchiveNotFound, to.DownloadProject(context.Background(), "etl", 3, &archive))
+
+	// the state belongs to these servers
+	_, err = azkaban.Migrate(to, from, azkaban.MigrateOptions{StateFile: state})
+	assert.NotNil(t, err)
+
+}
+
+func TestMigrateLatest(t *testing.T) {
+
+	source, target := azkabantest.NewServer(), azkabantest.NewServer()
+	defer source.Close()
+	defer target.Close()
+
+	from, err := source.Client()
+	assert.Nil(t, err)
+	to, err := target.Client()
+	assert.Nil(t, err)
+
+	// three uploads on the source, only the latest goes to the target
+	_, err = from.CreateProject("etl", "etl")
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, from.UploadProjectZip("etl", FLOW_ZIP_PATH))
+	}
+
+	report, err := azkaban.Migrate(from, to, azkaban.MigrateOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, report.Projects, 1)
+	assert.Equal(t, []int{1}, report.Projects[0].Versions)
+
+	var archive bytes.Buffer
+	assert.Nil(t, to.DownloadProject(context.Background(), "etl", 1, &archive))
+	assert.Equal(t, azkaban.ArchiveNotFound, to.DownloadProject(context.Background(), "etl", 2, &archive))
+
+}
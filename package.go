@@ -0,0 +1,120 @@
+package azkaban
+
+import (
+	"io"
+	"path"
+	"strings"
+)
+
+// PackageOptions selects the files of a directory that go into a project archive.
+// Patterns use path.Match syntax plus ** for any number of directories, and are matched
+// against slash separated paths relative to the directory. A pattern without a slash is
+// matched against the base name of every file and directory, as in .gitignore.
+type PackageOptions struct {
+	// Include lists the files to package, every file when empty.
+	Include []string
+	// Exclude lists files and directories to leave out, it wins over Include.
+	Exclude []string
+}
+
+// PackageDir zips the directory tree below root into w, keeping relative paths.
+// Entries are sorted and have fixed timestamps and permissions, so identical trees give
+// byte-identical archives whose hash can be compared to skip redundant uploads.
+func PackageDir(w io.Writer, root string, options PackageOptions) error {
+
+	files, err := readDir(root)
+	if err != nil {
+		return err
+	}
+
+	return PackageFiles(w, options.filter(files))
+
+}
+
+// PackageFiles zips files into w, with the same guarantees as PackageDir.
+func PackageFiles(w io.Writer, files []File) error {
+	return writeZip(w, files)
+}
+
+func (this PackageOptions) filter(files []File) []File {
+
+	var selected []File
+
+	for _, file := range files {
+		if this.selects(file.Name) {
+			selected = append(selected, file)
+		}
+	}
+
+	return selected
+
+}
+
+func (this PackageOptions) selects(name string) bool {
+
+	// an excluded directory excludes everything below it
+	for dir := name; dir != "."; dir = path.Dir(dir) {
+		for _, pattern := range this.Exclude {
+			if matchGlob(pattern, dir) {
+				return false
+			}
+		}
+	}
+
+	if len(this.Include) == 0 {
+		return true
+	}
+
+	for _, pattern := range this.Include {
+		if matchGlob(pattern, name) {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// match a slash separated path against a pattern, see PackageOptions
+func matchGlob(pattern, name string) bool {
+
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(name))
+		return matched
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+
+}
+
+func matchSegments(pattern, name []string) bool {
+
+	for len(pattern) > 0 {
+
+		if pattern[0] == "**" {
+			// ** matches zero or more directories
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+
+	}
+
+	return len(name) == 0
+
+}
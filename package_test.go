@@ -0,0 +1,58 @@
+package azkaban
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageDir(t *testing.T) {
+	root := t.TempDir()
+	for name, body := range map[string]string{
+		"flow.properties":   "p=1\n",
+		"a/run.job":         "type=noop\n",
+		"b/run.job":         "type=command\n",
+		"b/scripts/x.sh":    "echo x\n",
+		"target/out.jar":    "jar",
+		"b/target/tmp.job":  "type=noop\n",
+		"notes/readme.txt":  "notes",
+		"notes/.hidden.job": "type=noop\n",
+	} {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(file), 0755))
+		assert.Nil(t, ioutil.WriteFile(file, []byte(body), 0644))
+	}
+
+	options := PackageOptions{
+		Include: []string{"*.job", "*.properties", "**/scripts/**"},
+		Exclude: []string{"target", ".*"},
+	}
+
+	var first bytes.Buffer
+	assert.Nil(t, PackageDir(&first, root, options))
+
+	files, err := readZip(first.Bytes())
+	assert.Nil(t, err)
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	assert.Equal(t, []string{"a/run.job", "b/run.job", "b/scripts/x.sh", "flow.properties"}, names)
+
+	// touching files does not change the archive
+	later := time.Now().Add(time.Hour)
+	assert.Nil(t, os.Chtimes(filepath.Join(root, "a", "run.job"), later, later))
+
+	var second bytes.Buffer
+	assert.Nil(t, PackageDir(&second, root, options))
+	assert.Equal(t, first.Bytes(), second.Bytes())
+
+	// same base name in different directories cannot be flattened
+	err = ZipFiles(filepath.Join(root, "out.zip"), filepath.Join(root, "a", "run.job"), filepath.Join(root, "b", "run.job"))
+	assert.EqualError(t, err, "duplicate file run.job in archive")
+}
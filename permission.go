@@ -0,0 +1,153 @@
+package azkaban
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// permissions of a user or a group on a project
+var PermissionType = struct{ Admin, Read, Write, Execute, Schedule string }{"ADMIN", "READ", "WRITE", "EXECUTE", "SCHEDULE"}
+
+// Permission is what a user, or a group, may do on a project.
+type Permission struct {
+	Name        string   `json:"username"`
+	Group       bool     `json:"-"`
+	Permissions []string `json:"permission"`
+}
+
+// the form fields of a set of permissions, every permission is sent
+func permissionValues(values url.Values, permissions []string) {
+
+	granted := map[string]bool{}
+	for _, p := range permissions {
+		granted[strings.ToUpper(p)] = true
+	}
+
+	for _, p := range []string{PermissionType.Admin, PermissionType.Read, PermissionType.Write, PermissionType.Execute, PermissionType.Schedule} {
+		values.Add("permissions["+strings.ToLower(p)+"]", strconv.FormatBool(granted[p]))
+	}
+
+}
+
+// The ajax API fetching the permissions of the users, then of the groups, of a project.
+func (this *Client) FetchPermissions(project string) ([]Permission, error) {
+
+	// init return
+	var permissions []Permission
+
+	for _, ajax := range []string{"getPermissions", "getGroupPermissions"} {
+
+		var response struct {
+			Permissions []Permission `json:"permissions"`
+		}
+
+		// set form parameters
+		values := url.Values{}
+		values.Add("ajax", ajax)
+		values.Add("session.id", this.Session)
+		values.Add("project", project)
+
+		// request api
+		if err := this.action(http.MethodGet, "/manager", values, &response); err != nil {
+			return nil, err
+		}
+
+		for _, p := range response.Permissions {
+			p.Group = ajax == "getGroupPermissions"
+			sort.Strings(p.Permissions)
+			permissions = append(permissions, p)
+		}
+
+	}
+
+	return permissions, nil
+
+}
+
+// The ajax API granting permissions to a user or a group without any on a project.
+func (this *Client) AddPermission(project string, permission Permission) error {
+	return this.setPermission("addPermission", project, permission)
+}
+
+// The ajax API changing the permissions of a user or a group on a project, a permission without
+// any permission type is removed.
+func (this *Client) ChangePermission(project string, permission Permission) error {
+	return this.setPermission("changePermission", project, permission)
+}
+
+// RemovePermission removes the permissions of a user or a group on a project.
+func (this *Client) RemovePermission(project, name string, group bool) error {
+	return this.setPermission("changePermission", project, Permission{Name: name, Group: group})
+}
+
+func (this *Client) setPermission(ajax, project string, permission Permission) error {
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", ajax)
+	values.Add("session.id", this.Session)
+	values.Add("project", project)
+	values.Add("name", permission.Name)
+	values.Add("group", strconv.FormatBool(permission.Group))
+	permissionValues(values, permission.Permissions)
+
+	// an error or nothing is returned
+	var detail Detail
+
+	// request api
+	return this.action(http.MethodPost, "/manager", values, &detail)
+
+}
+
+// The ajax API fetching the proxy users of a project, the users its jobs may run as.
+func (this *Client) FetchProxyUsers(project string) ([]string, error) {
+
+	// init return
+	var response struct {
+		ProxyUsers []string `json:"proxyUsers"`
+	}
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", "getProxyUsers")
+	values.Add("session.id", this.Session)
+	values.Add("project", project)
+
+	// request api
+	err := this.action(http.MethodGet, "/manager", values, &response)
+
+	sort.Strings(response.ProxyUsers)
+
+	return response.ProxyUsers, err
+
+}
+
+// The ajax API adding a proxy user to a project.
+func (this *Client) AddProxyUser(project, name string) error {
+	return this.proxyUser("addProxyUser", project, name)
+}
+
+// The ajax API removing a proxy user of a project.
+func (this *Client) RemoveProxyUser(project, name string) error {
+	return this.proxyUser("removeProxyUser", project, name)
+}
+
+func (this *Client) proxyUser(ajax, project, name string) error {
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", ajax)
+	values.Add("session.id", this.Session)
+	values.Add("project", project)
+	values.Add("name", name)
+
+	// an error or nothing is returned
+	var detail Detail
+
+	// request api
+	return this.action(http.MethodPost, "/manager", values, &detail)
+
+}
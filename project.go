@@ -2,23 +2,24 @@ package azkaban
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
-	//"io/ioutil"
-	//"crypto/tls"
-	//"github.com/davecgh/go-spew/spew"
-	//"reflect"
-	//"encoding/json"
-	"crypto/tls"
-	"fmt"
-	"io"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var ProjectNotFound = errors.New("Project not found")
+var ArchiveNotFound = errors.New("Project archive not found")
 var ActionType = struct{ Create, Delete string }{"create", "delete"}
 var StatusType = struct{ Success, Error string }{"success", "error"}
 
@@ -27,6 +28,30 @@ type Project struct {
 	Name string `json:"project"`
 }
 
+type ProjectSummary struct {
+	ID          int       `json:"projectId"`
+	Name        string    `json:"projectName"`
+	CreatedBy   string    `json:"createdBy"`
+	CreatedTime int64     `json:"createdTime"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// used to avoid recursion in UnmarshalJSON below
+type projectSummary ProjectSummary
+
+// override json.Unmarshal for ProjectSummary, the creation time is sent in milliseconds
+func (this *ProjectSummary) UnmarshalJSON(b []byte) (err error) {
+
+	x := projectSummary{}
+
+	if err = json.Unmarshal(b, &x); err == nil {
+		*this = ProjectSummary(x)
+		this.CreatedAt = timeFromMillis(this.CreatedTime)
+	}
+
+	return err
+}
+
 type Object struct {
 	Project string `json:"project"`
 	Action  string `json:"-"`
@@ -45,6 +70,30 @@ func (this *Client) GetProject(name string) (*Project, error) {
 	return &flows.Project, err
 }
 
+// The ajax API for listing the projects the user can read.
+func (this *Client) FetchProjects() ([]ProjectSummary, error) {
+
+	// init return
+	var projects struct {
+		Projects []ProjectSummary `json:"projects"`
+	}
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", "fetchuserprojects")
+	values.Add("session.id", this.Session)
+
+	// try to get projects
+	err := this.action(http.MethodGet, "/index", values, &projects)
+
+	for i := range projects.Projects {
+		projects.Projects[i].CreatedAt = this.localize(projects.Projects[i].CreatedAt)
+	}
+
+	return projects.Projects, err
+
+}
+
 // The ajax API for creating a new project.
 func (this *Client) CreateProject(name, description string) (*Object, error) {
 
@@ -111,71 +160,424 @@ func (this *Client) DeleteProject(name string) (*Object, error) {
 
 }
 
+// The ajax API for uploading a project zip file from disk. Only a bad status fails the upload, as
+// it always did, unless azkaban answers with a validation error, which is returned as a go error.
+// An empty or non-json answer is not an error here, as it is for UploadProject.
 func (this *Client) UploadProjectZip(project, file string) error {
 
-	// Prepare a form that you will submit to azkaban
-	var buff bytes.Buffer
-	w := multipart.NewWriter(&buff)
-
-	// Add project file
+	// open project file
 	f, err := os.Open(file)
-	defer f.Close()
 	if err != nil {
 		return err
 	}
-	fw, err := w.CreateFormFile("file", filepath.Base(file))
+	defer f.Close()
+
+	content, err := this.upload(context.Background(), project, f, filepath.Base(file), nil)
 	if err != nil {
 		return err
 	}
-	if _, err = io.Copy(fw, f); err != nil {
-		return err
+
+	// return azkaban validation error as a go error
+	var upload Upload
+	if json.Unmarshal(content, &upload) == nil && upload.Error != "" {
+		return errors.New(upload.Error)
 	}
-	// Add the other fields
-	if fw, err = w.CreateFormField("ajax"); err != nil {
-		return err
+
+	return nil
+
+}
+
+// The API for downloading a project archive into w, the latest version when version is 0.
+// ArchiveNotFound is returned when the project has no such version, for instance before its first upload.
+func (this *Client) DownloadProject(ctx context.Context, project string, version int, w io.Writer) error {
+
+	// set query string
+	values := url.Values{}
+	values.Add("project", project)
+	values.Add("download", "true")
+	values.Add("session.id", this.Session)
+	if version > 0 {
+		values.Add("version", strconv.Itoa(version))
 	}
-	if _, err = fw.Write([]byte("upload")); err != nil {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, this.Endpoint+"/manager?"+values.Encode(), nil)
+	if err != nil {
 		return err
 	}
-	if fw, err = w.CreateFormField("project"); err != nil {
+
+	res, err := this.httpClient().Do(req)
+	if err != nil {
 		return err
 	}
-	if _, err = fw.Write([]byte(project)); err != nil {
-		return err
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("bad status: %s", res.Status)
 	}
-	if fw, err = w.CreateFormField("session.id"); err != nil {
-		return err
+
+	// azkaban redirects to the project page when there is nothing to download
+	if contentType := res.Header.Get("Content-Type"); !strings.Contains(contentType, "zip") && !strings.Contains(contentType, "octet-stream") {
+		return ArchiveNotFound
 	}
-	if _, err = fw.Write([]byte(this.Session)); err != nil {
-		return err
+
+	_, err = io.Copy(w, res.Body)
+
+	return err
+
+}
+
+// UploadProgress is called during an upload with the number of archive bytes sent so far.
+type UploadProgress func(sent int64)
+
+// counts the bytes read through it and reports them to an UploadProgress
+type progressReader struct {
+	reader   io.Reader
+	sent     int64
+	progress UploadProgress
+}
+
+func (this *progressReader) Read(b []byte) (int, error) {
+	n, err := this.reader.Read(b)
+	if n > 0 {
+		this.sent += int64(n)
+		this.progress(this.sent)
 	}
-	// Don't forget to close the multipart writer.
-	// If you don't close it, your request will be missing the terminating boundary.
-	w.Close()
+	return n, err
+}
+
+// The ajax API for uploading a project archive read from r, filename is the name azkaban will see.
+// The multipart body is streamed, so the archive is never held in memory, and progress, when not nil,
+// is called as the archive is sent. Validation errors reported by azkaban are returned as a go error.
+func (this *Client) UploadProject(ctx context.Context, project string, r io.Reader, filename string, progress UploadProgress) (*Upload, error) {
 
-	// Now that you have a form, you can submit it to your handler.
-	req, err := http.NewRequest(http.MethodPost, this.Endpoint+"/manager", &buff)
+	// init return
+	var upload Upload
+
+	content, err := this.upload(ctx, project, r, filename, progress)
 	if err != nil {
-		return err
+		return &upload, err
 	}
 
-	// Don't forget to set the content type, this will contain the boundary.
-	req.Header.Set("Content-Type", w.FormDataContentType())
+	if len(bytes.TrimSpace(content)) == 0 {
+		return &upload, EmptyResponse
+	}
+
+	if err = json.Unmarshal(content, &upload); err != nil {
+		return &upload, err
+	}
+
+	// return azkaban validation error as a go error
+	if upload.Error != "" {
+		err = errors.New(upload.Error)
+	}
+
+	return &upload, err
+
+}
 
-	// init http.Client without ssl verification
-	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+// send the upload form and return the answer of azkaban, r is no longer read once it returns
+func (this *Client) upload(ctx context.Context, project string, r io.Reader, filename string, progress UploadProgress) ([]byte, error) {
 
-	// Submit the request
-	res, err := client.Do(req)
+	if progress != nil {
+		r = &progressReader{reader: r, progress: progress}
+	}
+
+	// the form is written to the pipe while the request reads from it
+	pr, pw := io.Pipe()
+	w := multipart.NewWriter(pw)
+	done := make(chan struct{})
+
+	go func() {
+
+		defer close(done)
+
+		err := writeUploadForm(w, project, this.Session, r, filename)
+
+		// close the multipart writer, or the request will be missing the terminating boundary
+		if err == nil {
+			err = w.Close()
+		}
+
+		pw.CloseWithError(err)
+
+	}()
+
+	// the body may not have been read completely if the request failed or azkaban answered early,
+	// the writer is stopped and waited for
+	stop := func(err error) {
+		pr.CloseWithError(err)
+		<-done
+	}
+
+	// init request
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, this.Endpoint+"/manager", pr)
 	if err != nil {
-		return err
+		stop(err)
+		return nil, err
+	}
+
+	// the content type contains the boundary
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	// submit the request
+	res, err := this.httpClient().Do(req)
+	if err != nil {
+		stop(err)
+		return nil, err
 	}
+	defer res.Body.Close()
 
-	// Check the response
+	stop(nil)
+
+	// check the response
 	if res.StatusCode != http.StatusOK {
-		err = fmt.Errorf("bad status: %s", res.Status)
+		return nil, fmt.Errorf("bad status: %s", res.Status)
 	}
 
+	return ioutil.ReadAll(res.Body)
+
+}
+
+func writeUploadForm(w *multipart.Writer, project, session string, r io.Reader, filename string) error {
+
+	// add the other fields first, so azkaban knows the project before the file arrives
+	fields := [][2]string{{"ajax", "upload"}, {"project", project}, {"session.id", session}}
+	for _, field := range fields {
+		if err := w.WriteField(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+
+	// add project file
+	fw, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(fw, r)
+
 	return err
 
 }
+
+// used to avoid recursion in UnmarshalJSON below
+type upload Upload
+
+// override json.Unmarshal for Upload, azkaban versions send the version either as a string or as a number
+func (this *Upload) UnmarshalJSON(b []byte) error {
+
+	var raw struct {
+		upload
+		Version json.RawMessage `json:"version"`
+	}
+
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	*this = Upload(raw.upload)
+
+	if len(raw.Version) > 0 && string(raw.Version) != "null" {
+		if err := json.Unmarshal(raw.Version, &this.Version); err != nil {
+			this.Version = string(raw.Version)
+		}
+	}
+
+	return nil
+
+}
+
+var EventType = struct {
+	Error, Created, Deleted, UserPermission, GroupPermission, Description, Uploaded, Schedule, SLA, ProxyUser, Purge, PropertyOverride string
+}{"ERROR", "CREATED", "DELETED", "USER_PERMISSION", "GROUP_PERMISSION", "DESCRIPTION", "UPLOADED", "SCHEDULE", "SLA", "PROXY_USER", "PURGE", "PROPERTY_OVERRIDE"}
+
+// ProjectEvent is a single entry of the project audit log
+type ProjectEvent struct {
+	Time    time.Time `json:"time"`
+	User    string    `json:"user"`
+	Type    string    `json:"type"`
+	Message string    `json:"message"`
+}
+
+type ProjectLogs struct {
+	Project   string         `json:"project"`
+	IdProject int            `json:"projectId"`
+	Events    []ProjectEvent `json:"-"`
+}
+
+// used to avoid recursion in UnmarshalJSON below
+type projectLogs ProjectLogs
+
+// override json.Unmarshal for ProjectLogs, azkaban sends the events as rows of a table
+func (this *ProjectLogs) UnmarshalJSON(b []byte) error {
+
+	var raw struct {
+		projectLogs
+		Columns []string            `json:"columns"`
+		Data    [][]json.RawMessage `json:"logData"`
+	}
+
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	*this = ProjectLogs(raw.projectLogs)
+
+	// azkaban has always sent user, time, type and message in this order
+	columns := raw.Columns
+	if len(columns) == 0 {
+		columns = []string{"user", "time", "type", "message"}
+	}
+
+	for _, row := range raw.Data {
+
+		var event ProjectEvent
+
+		for i, cell := range row {
+
+			if i >= len(columns) {
+				break
+			}
+
+			var err error
+
+			switch columns[i] {
+			case "user":
+				err = json.Unmarshal(cell, &event.User)
+			case "type":
+				err = json.Unmarshal(cell, &event.Type)
+			case "message":
+				err = json.Unmarshal(cell, &event.Message)
+			case "time":
+				var ms int64
+				if err = json.Unmarshal(cell, &ms); err == nil {
+					event.Time = timeFromMillis(ms)
+				}
+			}
+
+			if err != nil {
+				return fmt.Errorf("project log column %q: %v", columns[i], err)
+			}
+
+		}
+
+		this.Events = append(this.Events, event)
+
+	}
+
+	return nil
+
+}
+
+// Given a project name, this API call fetches a page of its audit log, most recent events first.
+// The offset and length parameters are used to handle pagination.
+func (this *Client) FetchProjectLogs(project string, offset, length int) ([]ProjectEvent, error) {
+
+	// init return
+	var logs ProjectLogs
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", "fetchProjectLogEvents")
+	values.Add("session.id", this.Session)
+	values.Add("project", project)
+	values.Add("skip", strconv.Itoa(offset))
+	values.Add("size", strconv.Itoa(length))
+
+	// try to get project logs
+	err := this.action(http.MethodGet, "/manager", values, &logs)
+
+	// project does not exist
+	if err == EmptyResponse {
+		err = ProjectNotFound
+	}
+
+	for i := range logs.Events {
+		logs.Events[i].Time = this.localize(logs.Events[i].Time)
+	}
+
+	return logs.Events, err
+
+}
+
+// ProjectLogIterator walks the full audit log of a project, fetching pages on demand.
+//
+//	it := client.ProjectLogs("project", 100)
+//	for it.Next() {
+//		event := it.Event()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type ProjectLogIterator struct {
+	client  *Client
+	project string
+	size    int
+	offset  int
+	page    []ProjectEvent
+	event   ProjectEvent
+	done    bool
+	err     error
+}
+
+// ProjectLogs returns an iterator over the audit log of a project, fetching pageSize events per request.
+func (this *Client) ProjectLogs(project string, pageSize int) *ProjectLogIterator {
+
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+
+	return &ProjectLogIterator{
+		client:  this,
+		project: project,
+		size:    pageSize,
+	}
+
+}
+
+// Next advances to the next event, it returns false when the log is exhausted or an error occurred.
+func (this *ProjectLogIterator) Next() bool {
+
+	if this.err != nil {
+		return false
+	}
+
+	// fetch next page
+	if len(this.page) == 0 {
+
+		if this.done {
+			return false
+		}
+
+		this.page, this.err = this.client.FetchProjectLogs(this.project, this.offset, this.size)
+		if this.err != nil {
+			return false
+		}
+
+		this.offset += len(this.page)
+
+		// a short page means we reached the beginning of the history
+		if len(this.page) < this.size {
+			this.done = true
+		}
+
+		if len(this.page) == 0 {
+			return false
+		}
+
+	}
+
+	this.event, this.page = this.page[0], this.page[1:]
+
+	return true
+
+}
+
+// Event returns the current event.
+func (this *ProjectLogIterator) Event() ProjectEvent {
+	return this.event
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (this *ProjectLogIterator) Err() error {
+	return this.err
+}
@@ -0,0 +1,62 @@
+package azkaban_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/wanglun/azkaban"
+)
+
+// an endless archive, counting its reads
+type endlessReader struct {
+	reads int
+}
+
+func (r *endlessReader) Read(b []byte) (int, error) {
+	r.reads++
+	return len(b), nil
+}
+
+func TestUploadProjectAnswer(t *testing.T) {
+	var status int
+	var body string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client := azkaban.New(server.URL)
+
+	// azkaban answers before reading the archive, which is no longer read once the upload returned
+	status = http.StatusInternalServerError
+	archive := &endlessReader{}
+	_, err := client.UploadProject(context.Background(), PROJECT_NAME, archive, "endless.zip", nil)
+	assert.EqualError(t, err, "bad status: 500 Internal Server Error")
+	reads := archive.reads
+	_, err = client.UploadProject(context.Background(), PROJECT_NAME, bytes.NewReader([]byte("PK")), "small.zip", nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, reads, archive.reads)
+
+	// only UploadProject needs an answer
+	status, body = http.StatusOK, ""
+	_, err = client.UploadProject(context.Background(), PROJECT_NAME, bytes.NewReader([]byte("PK")), "small.zip", nil)
+	assert.Equal(t, azkaban.EmptyResponse, err)
+	assert.Nil(t, client.UploadProjectZip(PROJECT_NAME, FLOW_ZIP_PATH))
+
+	body = "<html></html>"
+	assert.Nil(t, client.UploadProjectZip(PROJECT_NAME, FLOW_ZIP_PATH))
+
+	body = `{"error":"Installation Failed. Project has no flows"}`
+	assert.EqualError(t, client.UploadProjectZip(PROJECT_NAME, FLOW_ZIP_PATH), "Installation Failed. Project has no flows")
+
+	body = `{"projectId":1,"version":"2"}`
+	upload, err := client.UploadProject(context.Background(), PROJECT_NAME, bytes.NewReader([]byte("PK")), "small.zip", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "2", upload.Version)
+}
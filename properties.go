@@ -0,0 +1,369 @@
+package azkaban
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ReadProperties parses a java .properties file: comments, key=value, key:value and key value
+// separators, line continuations and \t, \n, \r, \f and \uXXXX escapes. The line of each entry
+// is the line its key starts on.
+func ReadProperties(r io.Reader) ([]PropertyEntry, error) {
+
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// normalize line endings
+	text := strings.Replace(string(content), "\r\n", "\n", -1)
+	text = strings.Replace(text, "\r", "\n", -1)
+	lines := strings.Split(text, "\n")
+
+	var props []PropertyEntry
+
+	for i := 0; i < len(lines); i++ {
+
+		start := i + 1
+		natural := strings.TrimLeft(lines[i], " \t\f")
+
+		// comments and blank lines are only recognized at the start of a natural line
+		if natural == "" || natural[0] == '#' || natural[0] == '!' {
+			continue
+		}
+
+		// join continuation lines, an odd number of trailing backslashes escapes the line break
+		logical := natural
+		for continues(logical) && i+1 < len(lines) {
+			i++
+			logical = logical[:len(logical)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+		if continues(logical) {
+			logical = logical[:len(logical)-1]
+		}
+
+		key, value := splitProperty(logical)
+
+		if key, err = unescapeProperty(key); err == nil {
+			value, err = unescapeProperty(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", start, err)
+		}
+
+		props = append(props, PropertyEntry{Key: key, Value: value, Line: start})
+
+	}
+
+	return props, nil
+
+}
+
+func continues(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// split a logical line at the first unescaped separator
+func splitProperty(line string) (string, string) {
+
+	for i := 0; i < len(line); i++ {
+
+		switch line[i] {
+
+		case '\\':
+			i++
+
+		case '=', ':':
+			return line[:i], strings.TrimLeft(line[i+1:], " \t\f")
+
+		case ' ', '\t', '\f':
+			// whitespace may be followed by an = or : separator
+			value := strings.TrimLeft(line[i:], " \t\f")
+			if value != "" && (value[0] == '=' || value[0] == ':') {
+				value = strings.TrimLeft(value[1:], " \t\f")
+			}
+			return line[:i], value
+
+		}
+
+	}
+
+	return line, ""
+
+}
+
+func unescapeProperty(s string) (string, error) {
+
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var (
+		out   strings.Builder
+		units []uint16
+	)
+
+	// flush pending utf-16 code units, joining surrogate pairs
+	flush := func() {
+		if len(units) > 0 {
+			out.WriteString(string(utf16.Decode(units)))
+			units = units[:0]
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+
+		if s[i] != '\\' || i+1 == len(s) {
+			flush()
+			out.WriteByte(s[i])
+			continue
+		}
+
+		i++
+
+		if s[i] == 'u' {
+			if len(s)-(i+1) < 4 {
+				return "", fmt.Errorf("malformed \\uxxxx escape in %q", s)
+			}
+			code, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("malformed \\uxxxx escape in %q", s)
+			}
+			units = append(units, uint16(code))
+			i += 4
+			continue
+		}
+
+		flush()
+
+		switch s[i] {
+		case 't':
+			out.WriteByte('\t')
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 'f':
+			out.WriteByte('\f')
+		default:
+			out.WriteByte(s[i])
+		}
+
+	}
+
+	flush()
+
+	return out.String(), nil
+
+}
+
+// WriteProperties writes properties in the .properties format, escaping them so that java,
+// and azkaban which reads job files as ISO-8859-1, reads back exactly the same values.
+func WriteProperties(w io.Writer, props []PropertyEntry) error {
+
+	writer := bufio.NewWriter(w)
+
+	for _, prop := range props {
+		writer.WriteString(escapeProperty(prop.Key, true))
+		writer.WriteByte('=')
+		writer.WriteString(escapeProperty(prop.Value, false))
+		writer.WriteByte('\n')
+	}
+
+	return writer.Flush()
+
+}
+
+func escapeProperty(s string, key bool) string {
+
+	var out strings.Builder
+
+	for i, r := range s {
+
+		switch {
+		case r == '\\':
+			out.WriteString(`\\`)
+		case r == '\t':
+			out.WriteString(`\t`)
+		case r == '\n':
+			out.WriteString(`\n`)
+		case r == '\r':
+			out.WriteString(`\r`)
+		case r == '\f':
+			out.WriteString(`\f`)
+		case r == '=' || r == ':' || r == '#' || r == '!':
+			if key || i == 0 {
+				out.WriteByte('\\')
+			}
+			out.WriteRune(r)
+		case r == ' ':
+			// spaces separate keys, and leading spaces of values are trimmed
+			if key || i == 0 {
+				out.WriteByte('\\')
+			}
+			out.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			for _, unit := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&out, `\u%04X`, unit)
+			}
+		default:
+			out.WriteRune(r)
+		}
+
+	}
+
+	return out.String()
+
+}
+
+// ResolveJob computes the properties a job sees when it runs, as azkaban does: the job file
+// wins over the runtime overrides passed to ExecuteFlow, which win over the properties files
+// of the job's directory and of its parents, the nearest directory winning. ${param} references
+// are then substituted, references to the RuntimeParameters that are not known locally are
+// kept as they are.
+func (this *LocalProject) ResolveJob(name string, overrides map[string]string) (map[string]string, error) {
+
+	job := this.Job(name)
+	if job == nil {
+		return nil, fmt.Errorf("job %s not found", name)
+	}
+
+	props := map[string]string{}
+
+	for _, file := range this.inheritedProperties(job) {
+		for _, prop := range file.Props {
+			props[prop.Key] = prop.Value
+		}
+	}
+
+	for k, v := range overrides {
+		props[k] = v
+	}
+
+	for _, prop := range job.Props {
+		props[prop.Key] = prop.Value
+	}
+
+	props["azkaban.job.id"] = job.Name
+
+	return ResolveProperties(props)
+
+}
+
+// the properties files of the job's directory and of its parents, from the root to the job's directory
+func (this *LocalProject) inheritedProperties(job *LocalJob) []*PropertiesFile {
+
+	var files []*PropertiesFile
+	for _, file := range this.Properties {
+		dir := path.Dir(file.File)
+		if dir == "." || dir == job.Dir() || strings.HasPrefix(job.Dir(), dir+"/") {
+			files = append(files, file)
+		}
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return depth(files[i].File) < depth(files[j].File)
+	})
+
+	return files
+
+}
+
+// directory depth of a project file, 0 at the project root
+func depth(file string) int {
+	dir := path.Dir(file)
+	if dir == "." {
+		return 0
+	}
+	return strings.Count(dir, "/") + 1
+}
+
+// ResolveProperties substitutes ${param} references with the values of other properties,
+// recursively. Undefined parameters and circular references are reported as an error, except
+// for the RuntimeParameters which azkaban sets when the job runs and are kept as they are.
+func ResolveProperties(props map[string]string) (map[string]string, error) {
+
+	var (
+		resolved   = map[string]string{}
+		resolving  = map[string]bool{}
+		unresolved = map[string]bool{}
+		resolve    func(key string) (string, error)
+	)
+
+	resolve = func(key string) (string, error) {
+
+		if value, ok := resolved[key]; ok {
+			return value, nil
+		}
+
+		if resolving[key] {
+			return "", fmt.Errorf("circular reference to ${%s}", key)
+		}
+
+		resolving[key] = true
+		defer delete(resolving, key)
+
+		var err error
+
+		value := parameterReference.ReplaceAllStringFunc(props[key], func(match string) string {
+
+			param := match[2 : len(match)-1]
+
+			if _, ok := props[param]; !ok {
+				if !runtimeParameter(param) {
+					unresolved[param] = true
+				}
+				return match
+			}
+
+			substituted, e := resolve(param)
+			if e != nil && err == nil {
+				err = e
+			}
+
+			return substituted
+
+		})
+
+		if err != nil {
+			return "", err
+		}
+
+		resolved[key] = value
+
+		return value, nil
+
+	}
+
+	for key := range props {
+		if _, err := resolve(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(unresolved) > 0 {
+
+		var params []string
+		for param := range unresolved {
+			params = append(params, "${"+param+"}")
+		}
+		sort.Strings(params)
+
+		return nil, fmt.Errorf("could not find variable substitution for %s", strings.Join(params, ", "))
+
+	}
+
+	return resolved, nil
+
+}
@@ -0,0 +1,82 @@
+package azkaban
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadProperties(t *testing.T) {
+	props, err := ReadProperties(strings.NewReader(`# comment
+  ! another comment
+a=1
+b : 2
+c 3
+long = first \
+       second\
+third
+escaped\ key\=x = tab\tnew\nline
+unicode=café 😀
+trailing=back\\
+empty
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, []PropertyEntry{
+		{Key: "a", Value: "1", Line: 3},
+		{Key: "b", Value: "2", Line: 4},
+		{Key: "c", Value: "3", Line: 5},
+		{Key: "long", Value: "first secondthird", Line: 6},
+		{Key: "escaped key=x", Value: "tab\tnew\nline", Line: 9},
+		{Key: "unicode", Value: "café 😀", Line: 10},
+		{Key: "trailing", Value: `back\`, Line: 11},
+		{Key: "empty", Value: "", Line: 12},
+	}, props)
+
+	// write and read back
+	var buff bytes.Buffer
+	assert.Nil(t, WriteProperties(&buff, props))
+	assert.Contains(t, buff.String(), `unicode=caf\u00E9 \uD83D\uDE00`)
+	read, err := ReadProperties(&buff)
+	assert.Nil(t, err)
+	for i := range read {
+		read[i].Line = props[i].Line
+	}
+	assert.Equal(t, props, read)
+
+	_, err = ReadProperties(strings.NewReader(`bad=\u12`))
+	assert.NotNil(t, err)
+}
+
+func TestResolveJob(t *testing.T) {
+	project, err := LoadProject(FLOW_DIR_PATH)
+	assert.Nil(t, err)
+
+	props, err := project.ResolveJob("foo", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, `echo "hello foo, p1:" 10`, props["command"])
+
+	props, err = project.ResolveJob("bar", map[string]string{"test.p2": "p2_overrided"})
+	assert.Nil(t, err)
+	assert.Equal(t, `echo "hello bar, p2:" p2_overrided`, props["command"])
+
+	project, err = ReadProject([]File{
+		{Name: "flow.properties", Body: []byte("dir=/root\nname=root\n")},
+		{Name: "sub/sub.properties", Body: []byte("name=sub\npath=${dir}/${name}\n")},
+		{Name: "sub/job.job", Body: []byte("type=command\ncommand=ls ${path} ${azkaban.flow.execid}\n")},
+		{Name: "loop.job", Body: []byte("type=command\na=${b}\nb=${a}\n")},
+		{Name: "missing.job", Body: []byte("type=command\ncommand=${nope}\n")},
+	})
+	assert.Nil(t, err)
+
+	props, err = project.ResolveJob("job", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "ls /root/sub ${azkaban.flow.execid}", props["command"])
+
+	_, err = project.ResolveJob("loop", nil)
+	assert.NotNil(t, err)
+
+	_, err = project.ResolveJob("missing", nil)
+	assert.EqualError(t, err, "could not find variable substitution for ${nope}")
+}
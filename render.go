@@ -0,0 +1,318 @@
+package azkaban
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// colours of the nodes by execution status
+var statusColors = map[string]string{
+	ExecutionStatus.Ready:           "#ffffff",
+	ExecutionStatus.Queued:          "#ffffff",
+	ExecutionStatus.Preparing:       "#e0f0ff",
+	ExecutionStatus.Running:         "#7fbfff",
+	ExecutionStatus.Paused:          "#ffee88",
+	ExecutionStatus.Succeeded:       "#88dd88",
+	ExecutionStatus.Failed:          "#ff6f61",
+	ExecutionStatus.FailedFinishing: "#ffa59e",
+	ExecutionStatus.Killing:         "#ffb347",
+	ExecutionStatus.Killed:          "#ffb347",
+	ExecutionStatus.Cancelled:       "#ffb347",
+	ExecutionStatus.Skipped:         "#d3d3d3",
+	ExecutionStatus.Disabled:        "#d3d3d3",
+}
+
+// RenderOptions controls how a flow graph is rendered.
+type RenderOptions struct {
+	// Execution colours the nodes by their status in this execution, fetched with FetchExecutionFlow.
+	Execution *ExecutionFlow
+	// Collapse draws embedded flows as single nodes instead of expanding their nodes.
+	Collapse bool
+}
+
+// Statuses returns the status of each node of the execution, keyed by nested id.
+func (this *ExecutionFlow) Statuses() map[string]string {
+
+	statuses := map[string]string{}
+
+	var walk func(prefix string, nodes []ExecutionNode)
+	walk = func(prefix string, nodes []ExecutionNode) {
+		for i := range nodes {
+			statuses[prefix+nodes[i].ID] = nodes[i].Status
+			walk(prefix+nodes[i].ID+":", nodes[i].Nodes)
+		}
+	}
+
+	walk("", this.Nodes)
+
+	return statuses
+
+}
+
+// FlowGraph returns the graph of a flow of a local project in the shape FetchJobs returns,
+// so a project can be rendered or analysed before it is uploaded.
+func (this *LocalProject) FlowGraph(flow string) *Jobs {
+	return &Jobs{Flow: flow, Nodes: this.flowNodes(flow, map[string]bool{})}
+}
+
+func (this *LocalProject) flowNodes(flow string, embedding map[string]bool) []Node {
+
+	embedding[flow] = true
+	defer delete(embedding, flow)
+
+	var nodes []Node
+
+	for _, job := range this.FlowJobs(flow) {
+
+		node := Node{
+			ID:        job.Name,
+			Type:      job.Type(),
+			In:        job.Dependencies(),
+			JobSource: job.File,
+		}
+
+		// embedded flows, unless they embed themselves
+		if embedded := job.Get("flow.name"); node.Type == JobType.Flow && embedded != "" {
+			node.FlowID = embedded
+			if !embedding[embedded] {
+				node.Nodes = this.flowNodes(embedded, embedding)
+			}
+		}
+
+		nodes = append(nodes, node)
+
+	}
+
+	return nodes
+
+}
+
+// a graph being rendered, with the entry and exit nodes of every node once embedded flows are expanded
+type renderer struct {
+	options  RenderOptions
+	statuses map[string]string
+	ids      map[string]string
+	out      strings.Builder
+}
+
+func newRenderer(options RenderOptions) *renderer {
+
+	r := &renderer{options: options, ids: map[string]string{}}
+
+	if options.Execution != nil {
+		r.statuses = options.Execution.Statuses()
+	}
+
+	return r
+
+}
+
+// short and safe identifier of a node
+func (this *renderer) id(nested string) string {
+	if id, ok := this.ids[nested]; ok {
+		return id
+	}
+	id := fmt.Sprintf("n%d", len(this.ids))
+	this.ids[nested] = id
+	return id
+}
+
+func (this *renderer) label(g *Graph, id string) string {
+
+	node := g.Node(id)
+
+	label := id
+	if flow := node.EmbeddedFlow(); flow != "" {
+		label += "\nflow " + flow
+	} else if node.Type != "" {
+		label += "\n" + node.Type
+	}
+
+	if status := this.statuses[g.NestedID(id)]; status != "" {
+		label += "\n" + status
+	}
+
+	return label
+
+}
+
+func (this *renderer) color(g *Graph, id string) string {
+	return statusColors[this.statuses[g.NestedID(id)]]
+}
+
+func (this *renderer) expand(g *Graph, id string) *Graph {
+	if this.options.Collapse {
+		return nil
+	}
+	if sub := g.SubGraph(id); sub != nil && len(sub.Nodes()) > 0 {
+		return sub
+	}
+	return nil
+}
+
+// entry and exit nodes of a node, expanded embedded flows are entered by their roots and left by their leaves
+func (this *renderer) ends(g *Graph, id string) ([]string, []string) {
+
+	sub := this.expand(g, id)
+	if sub == nil {
+		return []string{this.id(g.NestedID(id))}, []string{this.id(g.NestedID(id))}
+	}
+
+	var entries, exits []string
+
+	for _, root := range sub.Roots() {
+		in, _ := this.ends(sub, root)
+		entries = append(entries, in...)
+	}
+
+	for _, leaf := range sub.Leaves() {
+		_, out := this.ends(sub, leaf)
+		exits = append(exits, out...)
+	}
+
+	return entries, exits
+
+}
+
+// edges of the graph and of its expanded embedded flows
+func (this *renderer) edges(g *Graph) [][2]string {
+
+	var edges [][2]string
+
+	for _, id := range g.Nodes() {
+
+		if sub := this.expand(g, id); sub != nil {
+			edges = append(edges, this.edges(sub)...)
+		}
+
+		entries, _ := this.ends(g, id)
+
+		for _, parent := range g.Parents(id) {
+			_, exits := this.ends(g, parent)
+			for _, from := range exits {
+				for _, to := range entries {
+					edges = append(edges, [2]string{from, to})
+				}
+			}
+		}
+
+	}
+
+	return edges
+
+}
+
+// RenderDOT renders the graph of a flow in the Graphviz DOT language, expanded embedded flows are drawn as clusters.
+func RenderDOT(g *Graph, options RenderOptions) string {
+
+	r := newRenderer(options)
+
+	fmt.Fprintf(&r.out, "digraph %s {\n", quoteDOT(g.Flow))
+	r.out.WriteString("  rankdir=TB;\n")
+	r.out.WriteString("  node [shape=box, style=\"rounded,filled\", fillcolor=\"#ffffff\"];\n")
+
+	r.dotNodes(g, "  ")
+
+	for _, edge := range r.edges(g) {
+		fmt.Fprintf(&r.out, "  %s -> %s;\n", edge[0], edge[1])
+	}
+
+	r.out.WriteString("}\n")
+
+	return r.out.String()
+
+}
+
+func (this *renderer) dotNodes(g *Graph, indent string) {
+
+	for _, id := range g.Nodes() {
+
+		if sub := this.expand(g, id); sub != nil {
+			fmt.Fprintf(&this.out, "%ssubgraph cluster_%s {\n", indent, this.id(g.NestedID(id)))
+			fmt.Fprintf(&this.out, "%s  label=%s;\n", indent, quoteDOT(this.label(g, id)))
+			if color := this.color(g, id); color != "" {
+				fmt.Fprintf(&this.out, "%s  color=%s; penwidth=2;\n", indent, quoteDOT(color))
+			}
+			this.dotNodes(sub, indent+"  ")
+			fmt.Fprintf(&this.out, "%s}\n", indent)
+			continue
+		}
+
+		attributes := "label=" + quoteDOT(this.label(g, id))
+		if color := this.color(g, id); color != "" {
+			attributes += ", fillcolor=" + quoteDOT(color)
+		}
+
+		fmt.Fprintf(&this.out, "%s%s [%s];\n", indent, this.id(g.NestedID(id)), attributes)
+
+	}
+
+}
+
+func quoteDOT(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return `"` + s + `"`
+}
+
+// RenderMermaid renders the graph of a flow as a Mermaid flowchart, expanded embedded flows are drawn as subgraphs.
+func RenderMermaid(g *Graph, options RenderOptions) string {
+
+	r := newRenderer(options)
+
+	r.out.WriteString("flowchart TD\n")
+
+	classes := map[string][]string{}
+	r.mermaidNodes(g, "  ", classes)
+
+	for _, edge := range r.edges(g) {
+		fmt.Fprintf(&r.out, "  %s --> %s\n", edge[0], edge[1])
+	}
+
+	// one class per status
+	var statuses []string
+	for status := range classes {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	for _, status := range statuses {
+		class := strings.ToLower(status)
+		fmt.Fprintf(&r.out, "  classDef %s fill:%s\n", class, statusColors[status])
+		fmt.Fprintf(&r.out, "  class %s %s\n", strings.Join(classes[status], ","), class)
+	}
+
+	return r.out.String()
+
+}
+
+func (this *renderer) mermaidNodes(g *Graph, indent string, classes map[string][]string) {
+
+	for _, id := range g.Nodes() {
+
+		nested := g.NestedID(id)
+
+		if status := this.statuses[nested]; statusColors[status] != "" {
+			classes[status] = append(classes[status], this.id(nested))
+		}
+
+		if sub := this.expand(g, id); sub != nil {
+			fmt.Fprintf(&this.out, "%ssubgraph %s [%s]\n", indent, this.id(nested), quoteMermaid(this.label(g, id)))
+			this.mermaidNodes(sub, indent+"  ", classes)
+			fmt.Fprintf(&this.out, "%send\n", indent)
+			continue
+		}
+
+		fmt.Fprintf(&this.out, "%s%s[%s]\n", indent, this.id(nested), quoteMermaid(this.label(g, id)))
+
+	}
+
+}
+
+func quoteMermaid(s string) string {
+	s = strings.Replace(s, `"`, "#quot;", -1)
+	s = strings.Replace(s, "\n", "<br/>", -1)
+	return `"` + s + `"`
+}
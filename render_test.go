@@ -0,0 +1,57 @@
+package azkaban
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const RENDER_DIR = "./testdata/render"
+
+// a -> b "quoted" -> d, a -> c -> d, c embeds e -> f
+func renderGraph() *Graph {
+	return NewGraph(&Jobs{Flow: `daily\"flow"`, Nodes: []Node{
+		{ID: "a", Type: "command"},
+		{ID: `b "quoted"`, Type: "command", In: []string{"a"}},
+		{ID: "c", Type: "flow", In: []string{"a"}, FlowID: "sub", Nodes: []Node{
+			{ID: "e", Type: "command"},
+			{ID: "f", Type: "noop", In: []string{"e"}},
+		}},
+		{ID: "d", Type: "noop", In: []string{`b "quoted"`, "c"}},
+	}})
+}
+
+func renderExecution() *ExecutionFlow {
+	return &ExecutionFlow{Nodes: []ExecutionNode{
+		{ID: "a", Status: ExecutionStatus.Succeeded},
+		{ID: `b "quoted"`, Status: ExecutionStatus.Failed},
+		{ID: "c", Status: ExecutionStatus.Running, Nodes: []ExecutionNode{
+			{ID: "e", Status: ExecutionStatus.Succeeded},
+			{ID: "f", Status: ExecutionStatus.Running},
+		}},
+		{ID: "d", Status: ExecutionStatus.Ready},
+	}}
+}
+
+func TestRender(t *testing.T) {
+	cases := []struct {
+		golden  string
+		render  func(*Graph, RenderOptions) string
+		options RenderOptions
+	}{
+		{"flow.dot", RenderDOT, RenderOptions{}},
+		{"collapsed.dot", RenderDOT, RenderOptions{Collapse: true}},
+		{"execution.dot", RenderDOT, RenderOptions{Execution: renderExecution()}},
+		{"flow.mmd", RenderMermaid, RenderOptions{}},
+		{"collapsed.mmd", RenderMermaid, RenderOptions{Collapse: true}},
+		{"execution.mmd", RenderMermaid, RenderOptions{Execution: renderExecution()}},
+	}
+
+	for _, c := range cases {
+		golden, err := ioutil.ReadFile(filepath.Join(RENDER_DIR, c.golden))
+		assert.Nil(t, err)
+		assert.Equal(t, string(golden), c.render(renderGraph(), c.options), c.golden)
+	}
+}
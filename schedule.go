@@ -0,0 +1,132 @@
+package azkaban
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+var ScheduleNotFound = errors.New("Schedule not found")
+
+// Schedule is the schedule of a flow, azkaban keeps a single one per flow.
+type Schedule struct {
+	ID           int    `json:"scheduleId,string"`
+	Project      string `json:"project,omitempty"`
+	Flow         string `json:"flow,omitempty"`
+	User         string `json:"submitUser"`
+	Cron         string `json:"cronExpression"`
+	Period       string `json:"period"`
+	FirstSchedAt string `json:"firstSchedTime"`
+	NextExecAt   string `json:"nextExecTime"`
+}
+
+// This API call schedules a flow with a Quartz cron expression, with seconds and an optional
+// year, such as "0 0 1 ? * *". It replaces the schedule the flow already had and returns the
+// id of the new schedule.
+func (this *Client) ScheduleCronFlow(project, flow, cron string) (int, error) {
+
+	// init return
+	var response struct {
+		Detail
+		ID int `json:"scheduleId"`
+	}
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", "scheduleCronFlow")
+	values.Add("session.id", this.Session)
+	values.Add("projectName", project)
+	values.Add("flow", flow)
+	values.Add("cronExpression", cron)
+
+	// request api
+	err := this.action(http.MethodPost, "/schedule", values, &response)
+
+	return response.ID, err
+
+}
+
+// Given a project and a flow, this API call fetches the schedule of the flow,
+// ScheduleNotFound is returned when the flow is not scheduled.
+func (this *Client) FetchSchedule(project, flow string) (*Schedule, error) {
+
+	// schedules are looked up by project id
+	p, err := this.GetProject(project)
+	if err != nil {
+		return nil, err
+	}
+
+	// init return
+	var response struct {
+		Schedule *Schedule `json:"schedule"`
+	}
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", "fetchSchedule")
+	values.Add("session.id", this.Session)
+	values.Add("projectId", strconv.Itoa(p.ID))
+	values.Add("flowId", flow)
+
+	// try to get the schedule
+	if err = this.action(http.MethodGet, "/schedule", values, &response); err != nil {
+		return nil, err
+	}
+
+	// azkaban answers with an empty object
+	if response.Schedule == nil {
+		return nil, ScheduleNotFound
+	}
+
+	response.Schedule.Project = project
+	response.Schedule.Flow = flow
+
+	return response.Schedule, nil
+
+}
+
+// FetchSchedules fetches the schedules of every flow of a project.
+func (this *Client) FetchSchedules(project string) ([]Schedule, error) {
+
+	flows, err := this.FetchFlows(project)
+	if err != nil {
+		return nil, err
+	}
+
+	var schedules []Schedule
+
+	for _, flow := range flows.Flows {
+
+		schedule, err := this.FetchSchedule(project, flow.IdFlow)
+		if err == ScheduleNotFound {
+			continue
+		}
+		if err != nil {
+			return schedules, err
+		}
+
+		schedules = append(schedules, *schedule)
+
+	}
+
+	return schedules, nil
+
+}
+
+// Given a schedule id, this API call removes the schedule.
+func (this *Client) RemoveSchedule(scheduleId int) error {
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("action", "removeSched")
+	values.Add("session.id", this.Session)
+	values.Add("scheduleId", strconv.Itoa(scheduleId))
+
+	// an error or a success status is returned
+	var detail Detail
+
+	// request api
+	return this.action(http.MethodPost, "/schedule", values, &detail)
+
+}
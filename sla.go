@@ -0,0 +1,158 @@
+package azkaban
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// what an SLA setting checks, that the flow or job succeeded or only finished in time
+var SLARule = struct{ Success, Finish string }{"SUCCESS", "FINISH"}
+
+// SLA is the service level agreement of a schedule, azkaban emails the addresses, or kills the
+// execution, when a setting is missed.
+type SLA struct {
+	Emails   []string     `yaml:"emails"`
+	Settings []SLASetting `yaml:"settings"`
+}
+
+// SLASetting is a rule of an SLA, on the flow or on one of its jobs.
+type SLASetting struct {
+	// Job is the job checked, the flow when empty
+	Job      string        `yaml:"job"`
+	Rule     string        `yaml:"rule"`
+	Duration time.Duration `yaml:"duration"`
+	Email    bool          `yaml:"email"`
+	Kill     bool          `yaml:"kill"`
+}
+
+func (this SLASetting) String() string {
+
+	target := this.Job
+	if target == "" {
+		target = "flow"
+	}
+
+	var actions []string
+	if this.Email {
+		actions = append(actions, "email")
+	}
+	if this.Kill {
+		actions = append(actions, "kill")
+	}
+
+	return fmt.Sprintf("%s %s within %s, %s", target, strings.ToLower(this.Rule), this.Duration, strings.Join(actions, "+"))
+
+}
+
+// Given a schedule id, this API call fetches the SLA of the schedule.
+func (this *Client) FetchSLA(scheduleId int) (*SLA, error) {
+
+	// init return
+	var response struct {
+		Emails   []string `json:"slaEmails"`
+		Settings []struct {
+			ID       string   `json:"id"`
+			Rule     string   `json:"rule"`
+			Duration string   `json:"duration"`
+			Actions  []string `json:"actions"`
+		} `json:"settings"`
+	}
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", "slaInfo")
+	values.Add("session.id", this.Session)
+	values.Add("scheduleId", strconv.Itoa(scheduleId))
+
+	// request api
+	if err := this.action(http.MethodGet, "/schedule", values, &response); err != nil {
+		return nil, err
+	}
+
+	sla := &SLA{Emails: response.Emails}
+
+	for _, s := range response.Settings {
+
+		duration, err := parseSLADuration(s.Duration)
+		if err != nil {
+			return nil, err
+		}
+
+		setting := SLASetting{Job: s.ID, Rule: s.Rule, Duration: duration}
+		for _, action := range s.Actions {
+			switch strings.ToUpper(action) {
+			case "EMAIL":
+				setting.Email = true
+			case "KILL":
+				setting.Kill = true
+			}
+		}
+
+		sla.Settings = append(sla.Settings, setting)
+
+	}
+
+	return sla, nil
+
+}
+
+// Given a schedule id, this API call replaces the SLA of the schedule, an SLA without settings removes it.
+func (this *Client) SetSLA(scheduleId int, sla SLA) error {
+
+	// set form parameters
+	values := url.Values{}
+	values.Add("ajax", "setSla")
+	values.Add("session.id", this.Session)
+	values.Add("scheduleId", strconv.Itoa(scheduleId))
+	values.Add("slaEmails", strings.Join(sla.Emails, ","))
+
+	// settings are sent as job,rule,hh:mm,email,kill
+	for i, s := range sla.Settings {
+		minutes := int(s.Duration / time.Minute)
+		values.Add(fmt.Sprintf("settings[%d]", i), fmt.Sprintf("%s,%s,%d:%02d,%t,%t", s.Job, s.Rule, minutes/60, minutes%60, s.Email, s.Kill))
+	}
+
+	// an error or nothing is returned
+	var detail Detail
+
+	// request api
+	return this.action(http.MethodPost, "/schedule", values, &detail)
+
+}
+
+// parse the duration of an SLA setting, hh:mm as sent, or the period string azkaban returns,
+// such as 90m or 2d
+func parseSLADuration(s string) (time.Duration, error) {
+
+	if s == "" {
+		return 0, fmt.Errorf("invalid SLA duration %q", s)
+	}
+
+	if i := strings.Index(s, ":"); i > 0 {
+		hours, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid SLA duration %q", s)
+		}
+		minutes, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return 0, fmt.Errorf("invalid SLA duration %q", s)
+		}
+		return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+	}
+
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if unit, ok := units[s[len(s)-1]]; ok && len(s) > 1 {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid SLA duration %q", s)
+		}
+		return time.Duration(n) * unit, nil
+	}
+
+	return time.ParseDuration(s)
+
+}
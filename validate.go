@@ -0,0 +1,244 @@
+package azkaban
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var FindingType = struct {
+	MissingDependency, Cycle, DuplicateJob, UnknownJobType, UnresolvedParameter, Unreachable string
+}{"missing-dependency", "cycle", "duplicate-job", "unknown-job-type", "unresolved-parameter", "unreachable"}
+
+// KnownJobTypes lists the job types ValidateProject accepts, add the jobtype plugins installed on your server.
+var KnownJobTypes = map[string]bool{
+	JobType.Command:     true,
+	JobType.JavaProcess: true,
+	JobType.HadoopJava:  true,
+	JobType.Hive:        true,
+	JobType.Spark:       true,
+	JobType.Pig:         true,
+	JobType.Noop:        true,
+	JobType.Flow:        true,
+	"java":              true,
+	"hadoopShell":       true,
+	"python":            true,
+}
+
+// RuntimeParameters lists the parameters azkaban sets when it runs a job, which are neither reported
+// nor substituted when they are not defined locally. A name ending with * stands for every parameter
+// starting with what precedes it, add the parameters your server or its plugins set.
+var RuntimeParameters = []string{
+	"working.dir",
+	"azkaban.flow.*",
+	"azkaban.job.*",
+	"azkaban.link.*",
+}
+
+// whether param is set by azkaban at runtime, see RuntimeParameters
+func runtimeParameter(param string) bool {
+
+	for _, name := range RuntimeParameters {
+		if strings.HasSuffix(name, "*") && strings.HasPrefix(param, strings.TrimSuffix(name, "*")) || param == name {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// Finding is a problem found in a local project, at a line of one of its files.
+type Finding struct {
+	File    string
+	Line    int
+	Type    string
+	Message string
+}
+
+func (this Finding) String() string {
+	return fmt.Sprintf("%s:%d: %s", this.File, this.Line, this.Message)
+}
+
+// matches ${param} references
+var parameterReference = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// ValidateProject checks a legacy project directory or zip archive before it is uploaded.
+// It reports missing dependency targets, cycles, duplicate job names, unknown job types,
+// unresolved ${param} references and jobs that no flow reaches, sorted by file and line.
+// The error is only set when the project cannot be read.
+func ValidateProject(name string) ([]Finding, error) {
+
+	project, err := LoadProject(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return project.Validate(), nil
+
+}
+
+// Validate checks the project, see ValidateProject.
+func (this *LocalProject) Validate() []Finding {
+
+	var findings []Finding
+
+	report := func(file string, line int, kind, format string, args ...interface{}) {
+		findings = append(findings, Finding{File: file, Line: line, Type: kind, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// line of a property, or the first line of the file when it is missing
+	lineOf := func(job *LocalJob, key string) int {
+		for i := len(job.Props) - 1; i >= 0; i-- {
+			if job.Props[i].Key == key {
+				return job.Props[i].Line
+			}
+		}
+		return 1
+	}
+
+	var names []string
+	jobs := map[string]*LocalJob{}
+
+	for _, job := range this.Jobs {
+
+		if first, ok := jobs[job.Name]; ok {
+			report(job.File, 1, FindingType.DuplicateJob, "job %s is already defined in %s", job.Name, first.File)
+			continue
+		}
+
+		names = append(names, job.Name)
+		jobs[job.Name] = job
+
+		switch kind := job.Type(); {
+		case kind == "":
+			report(job.File, 1, FindingType.UnknownJobType, "job %s has no type", job.Name)
+		case !KnownJobTypes[kind]:
+			report(job.File, lineOf(job, "type"), FindingType.UnknownJobType, "job %s has unknown type %s", job.Name, kind)
+		}
+
+		for _, dep := range job.Dependencies() {
+			if this.Job(dep) == nil {
+				report(job.File, lineOf(job, "dependencies"), FindingType.MissingDependency, "job %s depends on unknown job %s", job.Name, dep)
+			}
+		}
+
+		if job.Type() == JobType.Flow {
+			if embedded := job.Get("flow.name"); this.Job(embedded) == nil {
+				report(job.File, lineOf(job, "flow.name"), FindingType.MissingDependency, "job %s embeds unknown flow %q", job.Name, embedded)
+			}
+		}
+
+	}
+
+	deps := func(name string) []string {
+		if job, ok := jobs[name]; ok {
+			return job.Dependencies()
+		}
+		return nil
+	}
+
+	for _, component := range stronglyConnected(names, deps) {
+
+		cyclic := len(component) > 1
+		if !cyclic {
+			for _, dep := range deps(component[0]) {
+				cyclic = cyclic || dep == component[0]
+			}
+		}
+
+		if cyclic {
+			job := jobs[component[0]]
+			report(job.File, lineOf(job, "dependencies"), FindingType.Cycle, "dependency cycle between %s", strings.Join(component, ", "))
+		}
+
+	}
+
+	// jobs in a flow, a flow is a job nobody depends on
+	reached := map[string]bool{}
+	for _, flow := range this.Flows() {
+		for _, job := range this.FlowJobs(flow) {
+			reached[job.Name] = true
+		}
+	}
+
+	for _, name := range names {
+		if !reached[name] {
+			report(jobs[name].File, 1, FindingType.Unreachable, "job %s is not part of any flow", name)
+		}
+	}
+
+	// parameters must be defined by the job itself or by a properties file it inherits
+	for _, name := range names {
+
+		job := jobs[name]
+		defined := this.inheritedKeys(job.Dir())
+		for _, prop := range job.Props {
+			defined[prop.Key] = true
+		}
+
+		for _, prop := range job.Props {
+			for _, param := range unresolvedParameters(prop.Value, defined) {
+				report(job.File, prop.Line, FindingType.UnresolvedParameter, "job %s: unresolved parameter ${%s} in %s", job.Name, param, prop.Key)
+			}
+		}
+
+	}
+
+	for _, file := range this.Properties {
+
+		defined := this.inheritedKeys(path.Dir(file.File))
+
+		for _, prop := range file.Props {
+			for _, param := range unresolvedParameters(prop.Value, defined) {
+				report(file.File, prop.Line, FindingType.UnresolvedParameter, "unresolved parameter ${%s} in %s", param, prop.Key)
+			}
+		}
+
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+
+	return findings
+
+}
+
+// keys of the properties files in dir and its parents
+func (this *LocalProject) inheritedKeys(dir string) map[string]bool {
+
+	keys := map[string]bool{}
+
+	for _, file := range this.Properties {
+		parent := path.Dir(file.File)
+		if parent == "." || parent == dir || strings.HasPrefix(dir, parent+"/") {
+			for _, prop := range file.Props {
+				keys[prop.Key] = true
+			}
+		}
+	}
+
+	return keys
+
+}
+
+// parameters referenced by value and not defined, runtime parameters are left out
+func unresolvedParameters(value string, defined map[string]bool) []string {
+
+	var params []string
+
+	for _, match := range parameterReference.FindAllStringSubmatch(value, -1) {
+		if param := match[1]; !defined[param] && !runtimeParameter(param) {
+			params = append(params, param)
+		}
+	}
+
+	return params
+
+}
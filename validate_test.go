@@ -0,0 +1,56 @@
+package azkaban
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateProject(t *testing.T) {
+	findings, err := ValidateProject(FLOW_DIR_PATH)
+	assert.Nil(t, err)
+	assert.Empty(t, findings)
+
+	findings, err = ValidateProject(FLOW_ZIP_PATH)
+	assert.Nil(t, err)
+	assert.Empty(t, findings)
+
+	project, err := ReadProject([]File{
+		{Name: "flow.properties", Body: []byte("p1=1\n")},
+		{Name: "a.job", Body: []byte("type=command\ncommand=echo ${p1} ${p2}\ndependencies=b,missing\n")},
+		{Name: "b.job", Body: []byte("type=command\ndependencies=a\n")},
+		{Name: "sub/a.job", Body: []byte("type=shell\n")},
+		{Name: "c.job", Body: []byte("type=noop\ndependencies=c\n")},
+	})
+	assert.Nil(t, err)
+
+	var got []string
+	for _, finding := range project.Validate() {
+		got = append(got, finding.Type+" "+finding.String())
+	}
+
+	assert.Equal(t, []string{
+		"unreachable a.job:1: job a is not part of any flow",
+		"unresolved-parameter a.job:2: job a: unresolved parameter ${p2} in command",
+		"missing-dependency a.job:3: job a depends on unknown job missing",
+		"cycle a.job:3: dependency cycle between a, b",
+		"unreachable b.job:1: job b is not part of any flow",
+		"unreachable c.job:1: job c is not part of any flow",
+		"cycle c.job:2: dependency cycle between c",
+		"duplicate-job sub/a.job:1: job a is already defined in a.job",
+	}, got)
+	// parameters azkaban sets at runtime are not unresolved, other azkaban.* parameters are
+	project, err = ReadProject([]File{
+		{Name: "run.job", Body: []byte("type=command\ncommand=run ${working.dir} ${azkaban.flow.execid} ${azkaban.job.id} ${azkaban.link.execution.url} ${azkaban.unknown}\n")},
+	})
+	assert.Nil(t, err)
+
+	got = nil
+	for _, finding := range project.Validate() {
+		got = append(got, finding.Type+" "+finding.String())
+	}
+
+	assert.Equal(t, []string{
+		"unresolved-parameter run.job:2: job run: unresolved parameter ${azkaban.unknown} in command",
+	}, got)
+}